@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/bricks-cloud/bricksllm/internal/alert"
 	auth "github.com/bricks-cloud/bricksllm/internal/authenticator"
 	"github.com/bricks-cloud/bricksllm/internal/cache"
 	"github.com/bricks-cloud/bricksllm/internal/config"
@@ -18,11 +19,14 @@ import (
 	"github.com/bricks-cloud/bricksllm/internal/message"
 	"github.com/bricks-cloud/bricksllm/internal/pii"
 	"github.com/bricks-cloud/bricksllm/internal/pii/amazon"
+	"github.com/bricks-cloud/bricksllm/internal/pii/local"
 	custompolicy "github.com/bricks-cloud/bricksllm/internal/policy/custom"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
 	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
 	"github.com/bricks-cloud/bricksllm/internal/provider/azure"
 	"github.com/bricks-cloud/bricksllm/internal/provider/custom"
 	"github.com/bricks-cloud/bricksllm/internal/provider/deepinfra"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
 	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
 	"github.com/bricks-cloud/bricksllm/internal/provider/vllm"
 	"github.com/bricks-cloud/bricksllm/internal/recorder"
@@ -127,6 +131,11 @@ func main() {
 		log.Sugar().Fatalf("error creating policies table: %v", err)
 	}
 
+	err = store.AlterPolicyTable()
+	if err != nil {
+		log.Sugar().Fatalf("error altering policies table: %v", err)
+	}
+
 	err = store.CreateEventsByDayTable()
 	if err != nil {
 		log.Sugar().Fatalf("error creating event aggregated by day table: %v", err)
@@ -152,6 +161,11 @@ func main() {
 		log.Sugar().Fatalf("error creating users table: %v", err)
 	}
 
+	err = store.AlterUsersTable()
+	if err != nil {
+		log.Sugar().Fatalf("error altering users table: %v", err)
+	}
+
 	err = store.CreateCreatedAtIndexForUsers()
 	if err != nil {
 		log.Sugar().Fatalf("error creating created at index for users table: %v", err)
@@ -162,6 +176,26 @@ func main() {
 		log.Sugar().Fatalf("error creating user id for users table: %v", err)
 	}
 
+	err = store.CreatePricesTable()
+	if err != nil {
+		log.Sugar().Fatalf("error creating prices table: %v", err)
+	}
+
+	err = store.CreateBudgetsTable()
+	if err != nil {
+		log.Sugar().Fatalf("error creating budgets table: %v", err)
+	}
+
+	err = store.CreateBatchesTable()
+	if err != nil {
+		log.Sugar().Fatalf("error creating batches table: %v", err)
+	}
+
+	err = store.CreateCreditTransactionsTable()
+	if err != nil {
+		log.Sugar().Fatalf("error creating credit transactions table: %v", err)
+	}
+
 	go store.PrepareEventsIndexes(log)
 
 	cpMemStore, err := memdb.NewCustomProvidersMemDb(store, log, cfg.InMemoryDbUpdateInterval)
@@ -176,6 +210,18 @@ func main() {
 	}
 	rMemStore.Listen()
 
+	prMemStore, err := memdb.NewPricingMemDb(store, log, cfg.InMemoryDbUpdateInterval)
+	if err != nil {
+		log.Sugar().Fatalf("cannot initialize pricing memdb: %v", err)
+	}
+	prMemStore.Listen()
+
+	bMemStore, err := memdb.NewBudgetsMemDb(store, log, cfg.InMemoryDbUpdateInterval)
+	if err != nil {
+		log.Sugar().Fatalf("cannot initialize budgets memdb: %v", err)
+	}
+	bMemStore.Listen()
+
 	defaultRedisOption := func(cfg *config.Config, dbIndex int) *redis.Options {
 
 		options := &redis.Options{
@@ -282,16 +328,28 @@ func main() {
 		log.Sugar().Fatalf("error connecting to requests limit redis storage: %v", err)
 	}
 
+	// the event stream and the recorded event operations live next to the
+	// spend they are recorded into.
+	var idempotencyCache recorder.IdempotencyCache
+	if cfg.MessageBus == "redis" {
+		idempotencyCache = redisStorage.NewIdempotencyCache(costRedisStorage, cfg.EventIdempotencyTtl, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
+	}
+
 	rateLimitCache := redisStorage.NewCache(rateLimitRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	costLimitCache := redisStorage.NewCache(costLimitRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
+	// token limit counters share the databases of the rate limit counters.
+	tokenLimitCache := redisStorage.NewPrefixedCache(rateLimitRedisCache, "tokens:", cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	costStorage := redisStorage.NewStore(costRedisStorage, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	apiCache := redisStorage.NewCache(apiRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	accessCache := redisStorage.NewAccessCache(accessRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 
 	userRateLimitCache := redisStorage.NewCache(userRateLimitRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	userCostLimitCache := redisStorage.NewCache(userCostLimitRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
+	userTokenLimitCache := redisStorage.NewPrefixedCache(userRateLimitRedisCache, "tokens:", cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
+	budgetAlertCache := redisStorage.NewAlertCache(costLimitRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	userCostStorage := redisStorage.NewStore(userCostRedisStorage, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	userAccessCache := redisStorage.NewAccessCache(userAccessRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
+	concurrencyCache := redisStorage.NewConcurrencyCache(rateLimitRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 
 	psCache := redisStorage.NewProviderSettingsCache(providerSettingsRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	keysCache := redisStorage.NewKeysCache(keysRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
@@ -301,17 +359,22 @@ func main() {
 	if cfg.EnableEncrytion && err != nil {
 		log.Sugar().Fatalf("error creating encryption client: %v", err)
 	}
-	v := validator.NewValidator(costLimitCache, rateLimitCache, costStorage, requestsLimitStorage)
+	v := validator.NewValidator(costLimitCache, rateLimitCache, costStorage, requestsLimitStorage, tokenLimitCache, bMemStore)
 
 	m := manager.NewManager(store, costLimitCache, rateLimitCache, accessCache, keysCache, requestsLimitStorage)
 	krm := manager.NewReportingManager(costStorage, store, store, v)
 	psm := manager.NewProviderSettingsManager(store, psCache, encryptor)
+	kum := manager.NewKeyUsageManager(costStorage, requestsLimitStorage, costLimitCache, rateLimitCache, tokenLimitCache, psm)
 	cpm := manager.NewCustomProvidersManager(store, cpMemStore)
 	rm := manager.NewRouteManager(store, store, rMemStore, psm)
+	prm := manager.NewPricingManager(store, prMemStore)
+	rpm := manager.NewRepricingManager(store, prMemStore)
+	bm := manager.NewBudgetManager(store, bMemStore)
+	crm := manager.NewCreditManager(store)
 	pm := manager.NewPolicyManager(store, rMemStore)
 	um := manager.NewUserManager(store, store)
 
-	as, err := admin.NewAdminServer(log, *modePtr, m, krm, psm, cpm, rm, pm, um, cfg.AdminPass, cfg.XCodioSignSecret)
+	as, err := admin.NewAdminServer(log, *modePtr, m, krm, psm, cpm, rm, pm, um, prm, rpm, bm, crm, cfg.AdminPass, cfg.XCodioSignSecret)
 	if err != nil {
 		log.Sugar().Fatalf("error creating admin http server: %v", err)
 	}
@@ -321,7 +384,7 @@ func main() {
 
 	as.Run()
 
-	ce := openai.NewCostEstimator(openai.OpenAiPerThousandTokenCost, tc)
+	ce := openai.NewCostEstimator(openai.OpenAiPerThousandTokenCost, tc, prMemStore)
 
 	atc, err := anthropic.NewTokenCounter()
 	if err != nil {
@@ -333,37 +396,82 @@ func main() {
 		log.Sugar().Fatalf("error creating vllm token counter: %v", err)
 	}
 
-	ace := anthropic.NewCostEstimator(atc)
-	aoe := azure.NewCostEstimator()
+	gtc, err := gemini.NewTokenCounter()
+	if err != nil {
+		log.Sugar().Fatalf("error creating gemini token counter: %v", err)
+	}
+
+	ace := anthropic.NewCostEstimator(atc, prMemStore)
+	aoe := azure.NewCostEstimator(prMemStore)
 	vllme := vllm.NewCostEstimator(vllmtc)
-	die := deepinfra.NewCostEstimator()
+	die := deepinfra.NewCostEstimator(prMemStore)
+	ge := gemini.NewCostEstimator(gtc, prMemStore)
 
-	uv := validator.NewUserValidator(userCostLimitCache, userRateLimitCache, userCostStorage)
+	uv := validator.NewUserValidator(userCostLimitCache, userRateLimitCache, userCostStorage, userTokenLimitCache)
 
-	rec := recorder.NewRecorder(costStorage, userCostStorage, costLimitCache, userCostLimitCache, ce, store, requestsLimitStorage)
-	rlm := manager.NewRateLimitManager(rateLimitCache, userRateLimitCache)
-	a := auth.NewAuthenticator(psm, m, rm, store, encryptor)
+	rec := recorder.NewRecorder(costStorage, userCostStorage, costLimitCache, userCostLimitCache, ce, store, requestsLimitStorage, idempotencyCache, store)
+	rlm := manager.NewRateLimitManager(rateLimitCache, userRateLimitCache, tokenLimitCache, userTokenLimitCache)
+	cm := manager.NewConcurrencyManager(concurrencyCache, cfg.ConcurrencyLeaseTtl)
+	ht := provider.NewHealthTracker(cfg.ProviderSettingEjectionThreshold, cfg.ProviderSettingEjectionDuration)
+
+	a := auth.NewAuthenticator(psm, m, rm, store, encryptor, ht)
 
 	c := cache.NewCache(apiCache)
+	sc := cache.NewSemanticCache(cache.NewOpenAiEmbedder(cfg.SemanticCacheEmbeddingTimeout, cfg.OpenAiApiKey), cfg.SemanticCacheMaxEntries)
+
+	ba := alert.NewBudgetAlerter(costLimitCache, costStorage, userCostLimitCache, userCostStorage, budgetAlertCache, log, cfg.BudgetAlertWebhookSecret, cfg.BudgetAlertWebhookTimeout)
+	ba.Start(4)
+	handler := message.NewHandler(rec, log, ace, ce, vllme, aoe, v, uv, m, um, rlm, accessCache, userAccessCache, ba, bMemStore)
+
+	var messageBus message.Publisher
+	var stopEventConsumers func()
+	switch cfg.MessageBus {
+	case "redis":
+		streamBus := message.NewStreamBus(costRedisStorage, log, message.StreamConfig{
+			Stream:           cfg.EventStreamName,
+			Group:            cfg.EventStreamGroup,
+			DeadLetterStream: cfg.EventStreamDeadLetterName,
+			MaxLen:           cfg.EventStreamMaxLen,
+			MaxDeliveries:    cfg.EventStreamMaxDeliveries,
+			ClaimIdle:        cfg.EventStreamClaimIdle,
+		}, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
+		streamBus.Subscribe("event", handler.HandleEventWithRequestAndResponse)
+
+		if err := streamBus.Start(cfg.NumberOfEventMessageConsumers); err != nil {
+			log.Sugar().Fatalf("error starting event stream consumers: %v", err)
+		}
 
-	messageBus := message.NewMessageBus()
-	eventMessageChan := make(chan message.Message)
-	messageBus.Subscribe("event", eventMessageChan)
+		messageBus = streamBus
+		stopEventConsumers = streamBus.Stop
+	default:
+		memoryBus := message.NewMessageBus()
+		eventMessageChan := make(chan message.Message)
+		memoryBus.Subscribe("event", eventMessageChan)
 
-	handler := message.NewHandler(rec, log, ace, ce, vllme, aoe, v, uv, m, um, rlm, accessCache, userAccessCache)
+		eventConsumer := message.NewConsumer(eventMessageChan, log, 4, handler.HandleEventWithRequestAndResponse)
+		eventConsumer.StartEventMessageConsumers()
 
-	eventConsumer := message.NewConsumer(eventMessageChan, log, 4, handler.HandleEventWithRequestAndResponse)
-	eventConsumer.StartEventMessageConsumers()
+		messageBus = memoryBus
+		stopEventConsumers = eventConsumer.Stop
+	}
 
-	detector, err := amazon.NewClient(cfg.AmazonRequestTimeout, cfg.AmazonConnectionTimeout, log, cfg.AmazonRegion)
-	if err != nil {
-		log.Sugar().Infof("error when connecting to amazon: %v", err)
+	var detector pii.Detector
+	switch cfg.PiiDetector {
+	case "local":
+		detector = local.NewDetector()
+	default:
+		ad, err := amazon.NewClient(cfg.AmazonRequestTimeout, cfg.AmazonConnectionTimeout, log, cfg.AmazonRegion)
+		if err != nil {
+			log.Sugar().Infof("error when connecting to amazon: %v", err)
+		}
+
+		detector = ad
 	}
 
 	scanner := pii.NewScanner(detector)
 	cd := custompolicy.NewOpenAiDetector(cfg.CustomPolicyDetectionTimeout, cfg.OpenAiApiKey)
 
-	ps, err := proxy.NewProxyServer(log, *modePtr, *privacyPtr, c, m, rm, a, psm, cpm, store, ce, ace, aoe, v, rec, messageBus, rlm, cfg.ProxyTimeout, accessCache, userAccessCache, pm, scanner, cd, die, um, cfg.RemoveUserAgent)
+	ps, err := proxy.NewProxyServer(log, *modePtr, *privacyPtr, c, sc, m, rm, a, psm, cpm, store, ce, ace, aoe, v, rec, messageBus, rlm, cfg.ProxyTimeout, accessCache, userAccessCache, pm, scanner, cd, die, ge, um, uv, ht, prMemStore, store, cm, kum, cfg.RemoveUserAgent)
 	if err != nil {
 		log.Sugar().Fatalf("error creating proxy http server: %v", err)
 	}
@@ -374,9 +482,12 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	eventConsumer.Stop()
+	stopEventConsumers()
+	ba.Stop()
 	cpMemStore.Stop()
 	rMemStore.Stop()
+	prMemStore.Stop()
+	bMemStore.Stop()
 
 	log.Sugar().Infof("shutting down server...")
 
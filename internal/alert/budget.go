@@ -0,0 +1,285 @@
+package alert
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/user"
+	"go.uber.org/zap"
+)
+
+const (
+	SignatureHeader          = "X-BricksLLM-Signature"
+	SignatureTimestampHeader = "X-BricksLLM-Signature-Timestamp"
+)
+
+// checkQueueSize bounds the checks waiting for a worker. Checks are dropped
+// when it is full and run again with the next event of the key or user.
+const checkQueueSize = 1000
+
+var ErrCheckQueueFull = errors.New("budget alert check queue is full")
+
+type costLimitCache interface {
+	GetCounter(id string, timeUnit key.TimeUnit) (int64, error)
+}
+
+type costLimitStorage interface {
+	GetCounter(id string) (int64, error)
+}
+
+type alertCache interface {
+	SetIfNotExists(key string, timeUnit key.TimeUnit) (bool, error)
+	Delete(key string) error
+}
+
+type Payload struct {
+	Type       string       `json:"type"`
+	Id         string       `json:"id"`
+	Name       string       `json:"name"`
+	LimitType  string       `json:"limitType"`
+	LimitInUsd float64      `json:"limitInUsd"`
+	SpendInUsd float64      `json:"spendInUsd"`
+	Threshold  int          `json:"threshold"`
+	TimeUnit   key.TimeUnit `json:"timeUnit,omitempty"`
+	CreatedAt  int64        `json:"createdAt"`
+}
+
+type target struct {
+	typ               string
+	id                string
+	name              string
+	costLimit         float64
+	costLimitOverTime float64
+	costLimitUnit     key.TimeUnit
+}
+
+type BudgetAlerter struct {
+	clc    costLimitCache
+	cls    costLimitStorage
+	uclc   costLimitCache
+	ucls   costLimitStorage
+	ac     alertCache
+	client http.Client
+	secret string
+	log    *zap.Logger
+	checks chan func() error
+	done   chan bool
+	wg     sync.WaitGroup
+}
+
+func NewBudgetAlerter(clc costLimitCache, cls costLimitStorage, uclc costLimitCache, ucls costLimitStorage, ac alertCache, log *zap.Logger, secret string, timeout time.Duration) *BudgetAlerter {
+	return &BudgetAlerter{
+		clc:    clc,
+		cls:    cls,
+		uclc:   uclc,
+		ucls:   ucls,
+		ac:     ac,
+		client: http.Client{Timeout: timeout},
+		secret: secret,
+		log:    log,
+		checks: make(chan func() error, checkQueueSize),
+		done:   make(chan bool),
+	}
+}
+
+// Start runs the workers that check budgets and send the webhooks, so that a
+// slow webhook does not hold up the event consumers.
+func (ba *BudgetAlerter) Start(num int) {
+	for i := 0; i < num; i++ {
+		ba.wg.Add(1)
+		go func() {
+			defer ba.wg.Done()
+
+			for {
+				select {
+				case <-ba.done:
+					return
+
+				case check := <-ba.checks:
+					if err := check(); err != nil {
+						telemetry.Incr("bricksllm.alert.budget_alerter.check_error", nil, 1)
+						ba.log.Debug("error when checking budget alerts", zap.Error(err))
+					}
+				}
+			}
+		}()
+	}
+}
+
+func (ba *BudgetAlerter) Stop() {
+	ba.log.Info("shutting down budget alerter...")
+
+	close(ba.done)
+	ba.wg.Wait()
+}
+
+func (ba *BudgetAlerter) enqueue(check func() error) error {
+	select {
+	case ba.checks <- check:
+		return nil
+	default:
+		telemetry.Incr("bricksllm.alert.budget_alerter.check_dropped", nil, 1)
+		return ErrCheckQueueFull
+	}
+}
+
+// CheckKey queues a check of the budget alerts of the key.
+func (ba *BudgetAlerter) CheckKey(k *key.ResponseKey) error {
+	if k == nil || k.BudgetAlertConfig == nil || len(k.BudgetAlertConfig.WebhookUrl) == 0 {
+		return nil
+	}
+
+	t := &target{
+		typ:               "key",
+		id:                k.KeyId,
+		name:              k.Name,
+		costLimit:         k.CostLimitInUsd,
+		costLimitOverTime: k.CostLimitInUsdOverTime,
+		costLimitUnit:     k.CostLimitInUsdUnit,
+	}
+
+	cfg := k.BudgetAlertConfig
+	return ba.enqueue(func() error {
+		return ba.check(cfg, t, ba.clc, ba.cls)
+	})
+}
+
+// CheckUser queues a check of the budget alerts of the user.
+func (ba *BudgetAlerter) CheckUser(u *user.User) error {
+	if u == nil || u.BudgetAlertConfig == nil || len(u.BudgetAlertConfig.WebhookUrl) == 0 {
+		return nil
+	}
+
+	t := &target{
+		typ:               "user",
+		id:                u.Id,
+		name:              u.Name,
+		costLimit:         u.CostLimitInUsd,
+		costLimitOverTime: u.CostLimitInUsdOverTime,
+		costLimitUnit:     u.CostLimitInUsdUnit,
+	}
+
+	cfg := u.BudgetAlertConfig
+	return ba.enqueue(func() error {
+		return ba.check(cfg, t, ba.uclc, ba.ucls)
+	})
+}
+
+func (ba *BudgetAlerter) check(cfg *key.BudgetAlertConfig, t *target, clc costLimitCache, cls costLimitStorage) error {
+	if t.costLimitOverTime != 0 && len(t.costLimitUnit) != 0 {
+		spend, err := clc.GetCounter(t.id, t.costLimitUnit)
+		if err != nil {
+			return err
+		}
+
+		err = ba.alert(cfg, t, "costLimitInUsdOverTime", t.costLimitOverTime, spend, t.costLimitUnit)
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.costLimit != 0 {
+		spend, err := cls.GetCounter(t.id)
+		if err != nil {
+			return err
+		}
+
+		err = ba.alert(cfg, t, "costLimitInUsd", t.costLimit, spend, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ba *BudgetAlerter) alert(cfg *key.BudgetAlertConfig, t *target, limitType string, limit float64, spendInMicros int64, timeUnit key.TimeUnit) error {
+	spend := float64(spendInMicros) / 1000000
+
+	for _, threshold := range cfg.GetThresholds() {
+		if spend < limit*float64(threshold)/100 {
+			continue
+		}
+
+		// the limit is part of the key so that raising a limit re-arms its alerts.
+		dedupKey := fmt.Sprintf("%s:%s:%s:%f:%d", t.typ, t.id, limitType, limit, threshold)
+		ok, err := ba.ac.SetIfNotExists(dedupKey, timeUnit)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		err = ba.send(cfg.WebhookUrl, &Payload{
+			Type:       t.typ,
+			Id:         t.id,
+			Name:       t.name,
+			LimitType:  limitType,
+			LimitInUsd: limit,
+			SpendInUsd: spend,
+			Threshold:  threshold,
+			TimeUnit:   timeUnit,
+			CreatedAt:  time.Now().Unix(),
+		})
+
+		if err != nil {
+			ba.ac.Delete(dedupKey)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ba *BudgetAlerter) send(url string, p *Payload) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(ba.secret) != 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(SignatureTimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign([]byte(timestamp+string(data)), []byte(ba.secret)))
+	}
+
+	res, err := ba.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("budget alert webhook responded with status code %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// Sign computes the base64 encoded HMAC-SHA256 of a message. Receivers verify
+// webhooks by signing the timestamp header followed by the raw request body.
+func Sign(message, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(message)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
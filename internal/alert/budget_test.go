@@ -0,0 +1,129 @@
+package alert
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type memoryAlertCache struct {
+	keys map[string]bool
+}
+
+func (c *memoryAlertCache) SetIfNotExists(key string, timeUnit key.TimeUnit) (bool, error) {
+	if c.keys[key] {
+		return false, nil
+	}
+
+	c.keys[key] = true
+	return true, nil
+}
+
+func (c *memoryAlertCache) Delete(key string) error {
+	delete(c.keys, key)
+	return nil
+}
+
+type fixedCostLimitStorage struct {
+	micros int64
+}
+
+func (s *fixedCostLimitStorage) GetCounter(id string) (int64, error) {
+	return s.micros, nil
+}
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		secret   string
+		expected string
+	}{
+		{
+			name:     "known vector",
+			message:  "The quick brown fox jumps over the lazy dog",
+			secret:   "key",
+			expected: "97yD9DBThCSxMpjmqm+xQ+9NWaFJRhdZl0edvC0aPNg=",
+		},
+		{
+			name:     "empty message",
+			message:  "",
+			secret:   "",
+			expected: "thNnmggU2ex3L5XXeMNfxf8Wl8STcVZTxscSFEKSxa0=",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Sign([]byte(tt.message), []byte(tt.secret)))
+		})
+	}
+}
+
+func TestBudgetAlerter_SignsWebhooks(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{name: "signed", secret: "secret"},
+		{name: "unsigned", secret: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan *http.Request, 1)
+			bodies := make(chan []byte, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received <- r
+				bodies <- body
+			}))
+			defer server.Close()
+
+			ac := &memoryAlertCache{keys: map[string]bool{}}
+			ba := NewBudgetAlerter(nil, &fixedCostLimitStorage{micros: 6000000}, nil, nil, ac, zap.NewNop(), tt.secret, time.Second)
+			ba.Start(1)
+			defer ba.Stop()
+
+			err := ba.CheckKey(&key.ResponseKey{
+				KeyId:             "key-id",
+				CostLimitInUsd:    10,
+				BudgetAlertConfig: &key.BudgetAlertConfig{WebhookUrl: server.URL, Thresholds: []int{50}},
+			})
+			require.NoError(t, err)
+
+			var r *http.Request
+			var body []byte
+			select {
+			case r = <-received:
+				body = <-bodies
+			case <-time.After(time.Second):
+				t.Fatal("webhook was not sent")
+			}
+
+			p := &Payload{}
+			require.NoError(t, json.Unmarshal(body, p))
+			assert.Equal(t, "key-id", p.Id)
+			assert.Equal(t, 50, p.Threshold)
+			assert.InDelta(t, 6, p.SpendInUsd, 1e-9)
+
+			timestamp := r.Header.Get(SignatureTimestampHeader)
+			if len(tt.secret) == 0 {
+				assert.Empty(t, timestamp)
+				assert.Empty(t, r.Header.Get(SignatureHeader))
+				return
+			}
+
+			assert.NotEmpty(t, timestamp)
+			assert.Equal(t, Sign([]byte(timestamp+string(body)), []byte(tt.secret)), r.Header.Get(SignatureHeader))
+		})
+	}
+}
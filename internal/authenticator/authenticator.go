@@ -37,6 +37,10 @@ type keyStorage interface {
 	GetKeyByHash(hash string) (*key.ResponseKey, error)
 }
 
+type healthTracker interface {
+	IsHealthy(settingId string) bool
+}
+
 type Decryptor interface {
 	Decrypt(input string, headers map[string]string) (string, error)
 	Enabled() bool
@@ -48,15 +52,17 @@ type Authenticator struct {
 	rm        routesManager
 	ks        keyStorage
 	decryptor Decryptor
+	ht        healthTracker
 }
 
-func NewAuthenticator(psm providerSettingsManager, kc keysCache, rm routesManager, ks keyStorage, decryptor Decryptor) *Authenticator {
+func NewAuthenticator(psm providerSettingsManager, kc keysCache, rm routesManager, ks keyStorage, decryptor Decryptor, ht healthTracker) *Authenticator {
 	return &Authenticator{
 		psm:       psm,
 		kc:        kc,
 		rm:        rm,
 		ks:        ks,
 		decryptor: decryptor,
+		ht:        ht,
 	}
 }
 
@@ -64,6 +70,11 @@ func getApiKey(req *http.Request) (string, error) {
 	list := []string{
 		req.Header.Get("x-api-key"),
 		req.Header.Get("api-key"),
+		req.Header.Get("x-goog-api-key"),
+	}
+
+	if strings.HasPrefix(req.URL.Path, "/api/providers/gemini") {
+		list = append(list, req.URL.Query().Get("key"))
 	}
 
 	split := strings.Split(req.Header.Get("Authorization"), " ")
@@ -114,6 +125,11 @@ func rewriteHttpAuthHeader(req *http.Request, setting *provider.Setting) error {
 		return nil
 	}
 
+	if strings.HasPrefix(uri, "/api/providers/gemini") {
+		req.Header.Set("x-goog-api-key", apiKey)
+		return nil
+	}
+
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
 
 	return nil
@@ -186,6 +202,10 @@ func canAccessPath(provider string, path string) bool {
 		return false
 	}
 
+	if provider == "gemini" && !strings.HasPrefix(path, "/api/providers/gemini") {
+		return false
+	}
+
 	return true
 }
 
@@ -206,24 +226,90 @@ func anonymize(input string) string {
 	return string(input[0:5]) + "**********************************************"
 }
 
-func (a *Authenticator) AuthenticateHttpRequest(req *http.Request, xCustomProviderId string) (*key.ResponseKey, []*provider.Setting, error) {
-	var raw string
-	var err error
-	var settings []*provider.Setting
-	if xcustom.IsXCustomRequest(req) {
-		providerSetting, er := a.psm.GetSettingViaCache(xCustomProviderId)
-		if er != nil {
-			return nil, nil, er
-		}
-		settings = []*provider.Setting{providerSetting}
-		raw, err = xcustom.ExtractApiKey(req, providerSetting)
-	} else {
-		raw, err = getApiKey(req)
+// RewriteAuthHeader replaces the credentials of the request with the ones of
+// the provider setting, decrypting them first if encryption is enabled.
+func (a *Authenticator) RewriteAuthHeader(req *http.Request, used *provider.Setting) error {
+	return rewriteHttpAuthHeader(req, a.decryptSetting(used))
+}
+
+// decryptSetting returns a copy of the setting with its secret decrypted. The
+// setting itself is shared through the cache and must not be modified.
+func (a *Authenticator) decryptSetting(used *provider.Setting) *provider.Setting {
+	if !a.decryptor.Enabled() {
+		return used
+	}
+
+	param := "apikey"
+	if used.Provider == "amazon" {
+		param = "awsSecretAccessKey"
+	}
+
+	encryptedParam := used.Setting[param]
+	if len(encryptedParam) == 0 {
+		return used
 	}
+
+	decryptedSecret, err := a.decryptor.Decrypt(encryptedParam, map[string]string{"X-UPDATED-AT": strconv.FormatInt(used.UpdatedAt, 10)})
 	if err != nil {
-		return nil, nil, err
+		return used
+	}
+
+	decrypted := *used
+	decrypted.Setting = make(map[string]string, len(used.Setting))
+	for k, v := range used.Setting {
+		decrypted.Setting[k] = v
+	}
+
+	decrypted.Setting[param] = decryptedSecret
+
+	return &decrypted
+}
+
+// selectSetting picks a setting at random in proportion to its weight. Settings
+// ejected by the health tracker are skipped unless all of them are ejected.
+func (a *Authenticator) selectSetting(settings []*provider.Setting) *provider.Setting {
+	healthy := []*provider.Setting{}
+	for _, s := range settings {
+		if a.ht == nil || a.ht.IsHealthy(s.Id) {
+			healthy = append(healthy, s)
+		}
+	}
+
+	if len(healthy) == 0 {
+		telemetry.Incr("bricksllm.authenticator.select_setting.no_healthy_setting", nil, 1)
+		healthy = settings
 	}
 
+	total := 0
+	for _, s := range healthy {
+		total += s.GetWeight()
+	}
+
+	n := rand.Intn(total)
+	for _, s := range healthy {
+		n -= s.GetWeight()
+		if n < 0 {
+			return s
+		}
+	}
+
+	return healthy[len(healthy)-1]
+}
+
+// prioritizeSetting moves the setting used for the request to the front so that
+// downstream handlers read its cost map and parameters.
+func prioritizeSetting(settings []*provider.Setting, used *provider.Setting) []*provider.Setting {
+	prioritized := []*provider.Setting{used}
+	for _, s := range settings {
+		if s != used {
+			prioritized = append(prioritized, s)
+		}
+	}
+
+	return prioritized
+}
+
+func (a *Authenticator) getKey(raw string) (*key.ResponseKey, error) {
 	hash := hasher.Hash(raw)
 
 	key, err := a.kc.GetKeyViaCache(hash)
@@ -238,18 +324,95 @@ func (a *Authenticator) AuthenticateHttpRequest(req *http.Request, xCustomProvid
 	if err != nil {
 		_, ok := err.(notFoundError)
 		if ok {
-			return nil, nil, internal_errors.NewAuthError(fmt.Sprintf("key %s is not found", anonymize(raw)))
+			return nil, internal_errors.NewAuthError(fmt.Sprintf("key %s is not found", anonymize(raw)))
 		}
 
-		return nil, nil, err
+		return nil, err
 	}
 
 	if key == nil {
-		return nil, nil, internal_errors.NewAuthError(fmt.Sprintf("key %s is not found", anonymize(raw)))
+		return nil, internal_errors.NewAuthError(fmt.Sprintf("key %s is not found", anonymize(raw)))
 	}
 
 	if key.Revoked {
-		return nil, nil, internal_errors.NewAuthError(fmt.Sprintf("key %s has been revoked", anonymize(raw)))
+		return nil, internal_errors.NewAuthError(fmt.Sprintf("key %s has been revoked", anonymize(raw)))
+	}
+
+	return key, nil
+}
+
+// AuthenticateKey resolves the key that a request is made with without
+// selecting a provider setting.
+func (a *Authenticator) AuthenticateKey(req *http.Request) (*key.ResponseKey, error) {
+	raw, err := getApiKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.getKey(raw)
+}
+
+// AuthenticateUnifiedRequest authenticates a request sent to the unified
+// endpoints and picks the provider setting of the key that maps the model.
+// Headers are not rewritten since the request is dispatched to a provider
+// route afterwards.
+func (a *Authenticator) AuthenticateUnifiedRequest(req *http.Request, model string) (*key.ResponseKey, *provider.Setting, error) {
+	raw, err := getApiKey(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := a.getKey(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selected := []*provider.Setting{}
+	for _, settingId := range key.GetSettingIds() {
+		setting, _ := a.psm.GetSettingViaCache(settingId)
+		if setting == nil {
+			telemetry.Incr("bricksllm.authenticator.authenticate_unified_request.get_setting_error", nil, 1)
+			continue
+		}
+
+		if _, ok := setting.ModelMap[model]; ok {
+			selected = append(selected, setting)
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, nil, internal_errors.NewAuthError(fmt.Sprintf("provider setting that maps model %s not found for key %s", model, anonymize(raw)))
+	}
+
+	used := selected[0]
+	if key.RotationEnabled {
+		used = a.selectSetting(selected)
+	}
+
+	return key, used, nil
+}
+
+func (a *Authenticator) AuthenticateHttpRequest(req *http.Request, xCustomProviderId string) (*key.ResponseKey, []*provider.Setting, error) {
+	var raw string
+	var err error
+	var settings []*provider.Setting
+	if xcustom.IsXCustomRequest(req) {
+		providerSetting, er := a.psm.GetSettingViaCache(xCustomProviderId)
+		if er != nil {
+			return nil, nil, er
+		}
+		settings = []*provider.Setting{providerSetting}
+		raw, err = xcustom.ExtractApiKey(req, providerSetting)
+	} else {
+		raw, err = getApiKey(req)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := a.getKey(raw)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	if xcustom.IsXCustomRequest(req) {
@@ -309,30 +472,20 @@ func (a *Authenticator) AuthenticateHttpRequest(req *http.Request, xCustomProvid
 	if len(selected) != 0 {
 		used := selected[0]
 		if key.RotationEnabled {
-			used = selected[rand.Intn(len(selected))]
+			used = a.selectSetting(selected)
+			selected = prioritizeSetting(selected, used)
 		}
 
-		if a.decryptor.Enabled() {
-			encryptedParam := ""
-			if used.Provider == "amazon" {
-				encryptedParam = used.Setting["awsSecretAccessKey"]
-			} else if len(used.Setting["apikey"]) != 0 {
-				encryptedParam = used.Setting["apikey"]
-			}
-
-			if len(encryptedParam) != 0 {
-				decryptedSecret, err := a.decryptor.Decrypt(encryptedParam, map[string]string{"X-UPDATED-AT": strconv.FormatInt(used.UpdatedAt, 10)})
-				if err == nil {
-					if used.Provider == "amazon" {
-						used.Setting["awsSecretAccessKey"] = decryptedSecret
-					} else {
-						used.Setting["apikey"] = decryptedSecret
-					}
-				}
+		// decrypted copies are returned so that the proxy can read their secrets.
+		// Custom routes call every provider of their steps with them.
+		selected[0] = a.decryptSetting(used)
+		if strings.HasPrefix(req.URL.Path, "/api/routes") {
+			for i := 1; i < len(selected); i++ {
+				selected[i] = a.decryptSetting(selected[i])
 			}
 		}
 
-		err := rewriteHttpAuthHeader(req, used)
+		err := rewriteHttpAuthHeader(req, selected[0])
 		if err != nil {
 			return nil, nil, err
 		}
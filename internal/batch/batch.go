@@ -0,0 +1,16 @@
+package batch
+
+// Batch tracks an OpenAI batch job created through the proxy so that its
+// cost can be attributed to the key that created it once the batch
+// completes. RecordedAt is set when the spend of the batch has been recorded.
+type Batch struct {
+	Id         string  `json:"id"`
+	KeyId      string  `json:"keyId"`
+	UserId     string  `json:"userId"`
+	CreatedAt  int64   `json:"createdAt"`
+	UpdatedAt  int64   `json:"updatedAt"`
+	Endpoint   string  `json:"endpoint"`
+	Status     string  `json:"status"`
+	CostInUsd  float64 `json:"costInUsd"`
+	RecordedAt int64   `json:"recordedAt"`
+}
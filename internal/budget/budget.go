@@ -0,0 +1,166 @@
+package budget
+
+import (
+	"fmt"
+	"strings"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+)
+
+// Budget caps the collective spend and request rate of every key that
+// belongs to a key ring or carries a tag. Budgets are enforced in addition to
+// the limits configured on the keys and users themselves.
+type Budget struct {
+	Id                     string       `json:"id"`
+	Name                   string       `json:"name"`
+	CreatedAt              int64        `json:"createdAt"`
+	UpdatedAt              int64        `json:"updatedAt"`
+	KeyRing                string       `json:"keyRing"`
+	Tag                    string       `json:"tag"`
+	CostLimitInUsdOverTime float64      `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     key.TimeUnit `json:"costLimitInUsdUnit"`
+	RateLimitOverTime      int          `json:"rateLimitOverTime"`
+	RateLimitUnit          key.TimeUnit `json:"rateLimitUnit"`
+	Archived               bool         `json:"archived"`
+}
+
+type UpdateBudget struct {
+	Name                   string        `json:"name"`
+	UpdatedAt              int64         `json:"updatedAt"`
+	CostLimitInUsdOverTime *float64      `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     *key.TimeUnit `json:"costLimitInUsdUnit"`
+	RateLimitOverTime      *int          `json:"rateLimitOverTime"`
+	RateLimitUnit          *key.TimeUnit `json:"rateLimitUnit"`
+}
+
+// Matches reports whether the key is covered by the budget.
+func (b *Budget) Matches(k *key.ResponseKey) bool {
+	if k == nil {
+		return false
+	}
+
+	if len(b.KeyRing) != 0 {
+		return b.KeyRing == k.KeyRing
+	}
+
+	for _, tag := range k.Tags {
+		if tag == b.Tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *Budget) Validate() error {
+	invalid := []string{}
+
+	if len(b.Name) == 0 {
+		invalid = append(invalid, "name")
+	}
+
+	if b.CostLimitInUsdOverTime < 0 {
+		invalid = append(invalid, "costLimitInUsdOverTime")
+	}
+
+	if b.RateLimitOverTime < 0 {
+		invalid = append(invalid, "rateLimitOverTime")
+	}
+
+	if len(invalid) != 0 {
+		return internal_errors.NewValidationError(fmt.Sprintf("fields [%s] are invalid", strings.Join(invalid, ", ")))
+	}
+
+	if len(b.KeyRing) == 0 && len(b.Tag) == 0 {
+		return internal_errors.NewValidationError("either key ring or tag must be specified")
+	}
+
+	if len(b.KeyRing) != 0 && len(b.Tag) != 0 {
+		return internal_errors.NewValidationError("key ring and tag cannot be specified at the same time")
+	}
+
+	if b.CostLimitInUsdOverTime == 0 && b.RateLimitOverTime == 0 {
+		return internal_errors.NewValidationError("either cost limit over time or rate limit over time must be specified")
+	}
+
+	return validateLimits(b.CostLimitInUsdOverTime, b.CostLimitInUsdUnit, b.RateLimitOverTime, b.RateLimitUnit)
+}
+
+func (ub *UpdateBudget) Validate() error {
+	invalid := []string{}
+
+	if ub.CostLimitInUsdOverTime != nil && *ub.CostLimitInUsdOverTime < 0 {
+		invalid = append(invalid, "costLimitInUsdOverTime")
+	}
+
+	if ub.RateLimitOverTime != nil && *ub.RateLimitOverTime < 0 {
+		invalid = append(invalid, "rateLimitOverTime")
+	}
+
+	if len(invalid) != 0 {
+		return internal_errors.NewValidationError(fmt.Sprintf("fields [%s] are invalid", strings.Join(invalid, ", ")))
+	}
+
+	return nil
+}
+
+// Apply returns a copy of the budget with the update applied so that the
+// resulting limits can be validated before they are stored.
+func (ub *UpdateBudget) Apply(b *Budget) *Budget {
+	updated := *b
+
+	if len(ub.Name) != 0 {
+		updated.Name = ub.Name
+	}
+
+	if ub.CostLimitInUsdOverTime != nil {
+		updated.CostLimitInUsdOverTime = *ub.CostLimitInUsdOverTime
+	}
+
+	if ub.CostLimitInUsdUnit != nil {
+		updated.CostLimitInUsdUnit = *ub.CostLimitInUsdUnit
+	}
+
+	if ub.RateLimitOverTime != nil {
+		updated.RateLimitOverTime = *ub.RateLimitOverTime
+	}
+
+	if ub.RateLimitUnit != nil {
+		updated.RateLimitUnit = *ub.RateLimitUnit
+	}
+
+	return &updated
+}
+
+func validateLimits(costLimitOverTime float64, costLimitUnit key.TimeUnit, rateLimitOverTime int, rateLimitUnit key.TimeUnit) error {
+	if len(rateLimitUnit) != 0 && rateLimitOverTime == 0 {
+		return internal_errors.NewValidationError("rate limit over time can not be empty if rate limit unit is specified")
+	}
+
+	if rateLimitOverTime != 0 {
+		if len(rateLimitUnit) == 0 {
+			return internal_errors.NewValidationError("rate limit unit can not be empty if rate limit over time is specified")
+		}
+
+		if rateLimitUnit != key.HourTimeUnit && rateLimitUnit != key.MinuteTimeUnit && rateLimitUnit != key.SecondTimeUnit && rateLimitUnit != key.DayTimeUnit {
+			return internal_errors.NewValidationError("rate limit unit can not be identified")
+		}
+	}
+
+	if len(costLimitUnit) != 0 && costLimitOverTime == 0 {
+		return internal_errors.NewValidationError("cost limit over time can not be empty if cost limit unit is specified")
+	}
+
+	if costLimitOverTime != 0 {
+		if len(costLimitUnit) == 0 {
+			return internal_errors.NewValidationError("cost limit unit can not be empty if cost limit over time is specified")
+		}
+
+		if costLimitUnit != key.DayTimeUnit && costLimitUnit != key.HourTimeUnit && costLimitUnit != key.MonthTimeUnit && costLimitUnit != key.MinuteTimeUnit {
+			return internal_errors.NewValidationError("cost limit unit can not be identified")
+		}
+	}
+
+	return nil
+}
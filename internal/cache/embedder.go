@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	goopenai "github.com/sashabaranov/go-openai"
+)
+
+type OpenAiEmbedder struct {
+	client *goopenai.Client
+	rt     time.Duration
+}
+
+func NewOpenAiEmbedder(rt time.Duration, key string) *OpenAiEmbedder {
+	e := &OpenAiEmbedder{
+		rt: rt,
+	}
+
+	if len(key) != 0 {
+		e.client = goopenai.NewClient(key)
+	}
+
+	return e
+}
+
+// Embed returns the embedding of the input along with the number of tokens
+// it was billed for.
+func (e *OpenAiEmbedder) Embed(model, input string) ([]float32, int, error) {
+	if e.client == nil {
+		return nil, 0, errors.New("openai api key is not configured for embeddings")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.rt)
+	defer cancel()
+
+	resp, err := e.client.CreateEmbeddings(ctx, goopenai.EmbeddingRequest{
+		Input: []string{input},
+		Model: goopenai.EmbeddingModel(model),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(resp.Data) == 0 {
+		return nil, resp.Usage.PromptTokens, errors.New("there are no embeddings from OpenAI")
+	}
+
+	return resp.Data[0].Embedding, resp.Usage.PromptTokens, nil
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+type embedder interface {
+	Embed(model, input string) ([]float32, int, error)
+}
+
+type semanticEntry struct {
+	key       string
+	model     string
+	vector    []float32
+	expiresAt time.Time
+}
+
+// SemanticCache is an in-process vector index that maps prompt embeddings to
+// the keys of previously cached responses. Entries are kept per namespace
+// and compared with a brute force cosine similarity search.
+type SemanticCache struct {
+	e          embedder
+	mu         sync.RWMutex
+	entries    map[string][]*semanticEntry
+	maxEntries int
+}
+
+func NewSemanticCache(e embedder, maxEntries int) *SemanticCache {
+	return &SemanticCache{
+		e:          e,
+		entries:    map[string][]*semanticEntry{},
+		maxEntries: maxEntries,
+	}
+}
+
+// Search embeds the input and returns the key of the most similar entry in the
+// namespace if its similarity reaches the threshold. The normalized embedding
+// is returned so that it can be reused when adding a new entry, along with the
+// number of tokens used to create it.
+func (sc *SemanticCache) Search(namespace, model, input string, threshold float64) (string, []float32, int, error) {
+	if len(input) == 0 {
+		return "", nil, 0, errors.New("semantic cache input is empty")
+	}
+
+	vector, tks, err := sc.e.Embed(model, input)
+	if err != nil {
+		return "", nil, tks, err
+	}
+
+	normalized := normalize(vector)
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	now := time.Now()
+	matched := ""
+	best := threshold
+	for _, entry := range sc.entries[namespace] {
+		if entry.model != model || now.After(entry.expiresAt) {
+			continue
+		}
+
+		similarity := dot(normalized, entry.vector)
+		if similarity >= best {
+			best = similarity
+			matched = entry.key
+		}
+	}
+
+	return matched, normalized, tks, nil
+}
+
+// Add stores a normalized embedding returned by Search. Expired entries are
+// evicted first and the oldest entries are dropped once the namespace is full.
+func (sc *SemanticCache) Add(namespace, model, key string, vector []float32, ttl time.Duration) {
+	if len(vector) == 0 {
+		return
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	now := time.Now()
+	kept := []*semanticEntry{}
+	for _, entry := range sc.entries[namespace] {
+		if now.Before(entry.expiresAt) && entry.key != key {
+			kept = append(kept, entry)
+		}
+	}
+
+	kept = append(kept, &semanticEntry{
+		key:       key,
+		model:     model,
+		vector:    vector,
+		expiresAt: now.Add(ttl),
+	})
+
+	if sc.maxEntries > 0 && len(kept) > sc.maxEntries {
+		kept = kept[len(kept)-sc.maxEntries:]
+	}
+
+	sc.entries[namespace] = kept
+}
+
+func normalize(vector []float32) []float32 {
+	var sum float64 = 0
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+
+	norm := math.Sqrt(sum)
+	if norm == 0 {
+		return vector
+	}
+
+	normalized := make([]float32, len(vector))
+	for i, v := range vector {
+		normalized[i] = float32(float64(v) / norm)
+	}
+
+	return normalized
+}
+
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var sum float64 = 0
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+
+	return sum
+}
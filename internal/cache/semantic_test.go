@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticEmbedder returns fixed embeddings so that similarities are known.
+type staticEmbedder struct {
+	vectors map[string][]float32
+}
+
+func (e *staticEmbedder) Embed(model, input string) ([]float32, int, error) {
+	return e.vectors[input], len(input), nil
+}
+
+func TestSemanticCache_Search(t *testing.T) {
+	e := &staticEmbedder{
+		vectors: map[string][]float32{
+			"cached":     {3, 4},
+			"same":       {6, 8},
+			"similar":    {4, 4},
+			"orthogonal": {-4, 3},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		model     string
+		input     string
+		threshold float64
+		expected  string
+	}{
+		{name: "same direction", namespace: "route:key:user", model: "m", input: "same", threshold: 0.99, expected: "entry"},
+		{name: "similar above threshold", namespace: "route:key:user", model: "m", input: "similar", threshold: 0.95, expected: "entry"},
+		{name: "similar below threshold", namespace: "route:key:user", model: "m", input: "similar", threshold: 0.999, expected: ""},
+		{name: "orthogonal", namespace: "route:key:user", model: "m", input: "orthogonal", threshold: 0.5, expected: ""},
+		{name: "other namespace", namespace: "route:other-key:user", model: "m", input: "same", threshold: 0.99, expected: ""},
+		{name: "other model", namespace: "route:key:user", model: "other", input: "same", threshold: 0.99, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := NewSemanticCache(e, 10)
+
+			_, vector, tks, err := sc.Search("route:key:user", "m", "cached", 0.9)
+			require.NoError(t, err)
+			assert.Equal(t, len("cached"), tks)
+			sc.Add("route:key:user", "m", "entry", vector, time.Minute)
+
+			matched, _, _, err := sc.Search(tt.namespace, tt.model, tt.input, tt.threshold)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, matched)
+		})
+	}
+}
+
+func TestSemanticCache_Add(t *testing.T) {
+	e := &staticEmbedder{
+		vectors: map[string][]float32{
+			"a": {1, 0},
+			"b": {0, 1},
+			"c": {1, 1},
+		},
+	}
+
+	sc := NewSemanticCache(e, 2)
+	for _, input := range []string{"a", "b", "c"} {
+		_, vector, _, err := sc.Search("ns", "m", input, 1)
+		require.NoError(t, err)
+		sc.Add("ns", "m", input, vector, time.Minute)
+	}
+
+	matched, _, _, err := sc.Search("ns", "m", "a", 0.99)
+	require.NoError(t, err)
+	assert.Empty(t, matched, "the oldest entry is dropped once the namespace is full")
+
+	matched, _, _, err = sc.Search("ns", "m", "c", 0.99)
+	require.NoError(t, err)
+	assert.Equal(t, "c", matched)
+
+	sc = NewSemanticCache(e, 10)
+	_, vector, _, err := sc.Search("ns", "m", "a", 1)
+	require.NoError(t, err)
+	sc.Add("ns", "m", "a", vector, -time.Minute)
+
+	matched, _, _, err = sc.Search("ns", "m", "a", 0.99)
+	require.NoError(t, err)
+	assert.Empty(t, matched, "expired entries are never matched")
+}
+
+func TestSemanticCache_SearchEmptyInput(t *testing.T) {
+	_, _, _, err := NewSemanticCache(&staticEmbedder{}, 10).Search("ns", "m", "", 0.9)
+	assert.Error(t, err)
+}
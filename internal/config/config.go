@@ -16,42 +16,58 @@ import (
 )
 
 type Config struct {
-	PostgresqlHosts               string        `koanf:"postgresql_hosts" env:"POSTGRESQL_HOSTS" envSeparator:":" envDefault:"localhost"`
-	PostgresqlDbName              string        `koanf:"postgresql_db_name" env:"POSTGRESQL_DB_NAME"`
-	PostgresqlUsername            string        `koanf:"postgresql_username" env:"POSTGRESQL_USERNAME"`
-	PostgresqlPassword            string        `koanf:"postgresql_password" env:"POSTGRESQL_PASSWORD"`
-	PostgresqlSslMode             string        `koanf:"postgresql_ssl_mode" env:"POSTGRESQL_SSL_MODE" envDefault:"disable"`
-	PostgresqlPort                string        `koanf:"postgresql_port" env:"POSTGRESQL_PORT" envDefault:"5432"`
-	RedisHosts                    string        `koanf:"redis_hosts" env:"REDIS_HOSTS" envSeparator:":" envDefault:"localhost"`
-	RedisPort                     string        `koanf:"redis_port" env:"REDIS_PORT" envDefault:"6379"`
-	RedisUsername                 string        `koanf:"redis_username" env:"REDIS_USERNAME"`
-	RedisPassword                 string        `koanf:"redis_password" env:"REDIS_PASSWORD"`
-	RedisDBStartIndex             int           `koanf:"redis_db_start_index" env:"REDIS_DB_START_INDEX" envDefault:"0"`
-	RedisReadTimeout              time.Duration `koanf:"redis_read_time_out" env:"REDIS_READ_TIME_OUT" envDefault:"1s"`
-	RedisWriteTimeout             time.Duration `koanf:"redis_write_time_out" env:"REDIS_WRITE_TIME_OUT" envDefault:"500ms"`
-	PostgresqlReadTimeout         time.Duration `koanf:"postgresql_read_time_out" env:"POSTGRESQL_READ_TIME_OUT" envDefault:"10m"`
-	PostgresqlWriteTimeout        time.Duration `koanf:"postgresql_write_time_out" env:"POSTGRESQL_WRITE_TIME_OUT" envDefault:"5s"`
-	InMemoryDbUpdateInterval      time.Duration `koanf:"in_memory_db_update_interval" env:"IN_MEMORY_DB_UPDATE_INTERVAL" envDefault:"5s"`
-	TelemetryProvider             string        `koanf:"telemetry_provider" env:"TELEMETRY_PROVIDER" envDefault:"statsd"`
-	StatsEnabled                  bool          `koanf:"stats_enabled" env:"STATS_ENABLED" envDefault:"true"`
-	StatsAddress                  string        `koanf:"stats_address" env:"STATS_ADDRESS" envDefault:"127.0.0.1:8125"`
-	PrometheusEnabled             bool          `koanf:"prometheus_enabled" env:"PROMETHEUS_ENABLED" envDefault:"true"`
-	PrometheusPort                string        `koanf:"prometheus_port" env:"PROMETHEUS_PORT" envDefault:"2112"`
-	AdminPass                     string        `koanf:"admin_pass" env:"ADMIN_PASS"`
-	ProxyTimeout                  time.Duration `koanf:"proxy_timeout" env:"PROXY_TIMEOUT" envDefault:"600s"`
-	NumberOfEventMessageConsumers int           `koanf:"number_of_event_message_consumers" env:"NUMBER_OF_EVENT_MESSAGE_CONSUMERS" envDefault:"3"`
-	OpenAiApiKey                  string        `koanf:"openai_api_key" env:"OPENAI_API_KEY"`
-	CustomPolicyDetectionTimeout  time.Duration `koanf:"custom_policy_detection_timeout" env:"CUSTOM_POLICY_DETECTION_TIMEOUT" envDefault:"10m"`
-	AmazonRegion                  string        `koanf:"amazon_region" env:"AMAZON_REGION" envDefault:"us-west-2"`
-	AmazonRequestTimeout          time.Duration `koanf:"amazon_request_timeout" env:"AMAZON_REQUEST_TIMEOUT" envDefault:"5s"`
-	AmazonConnectionTimeout       time.Duration `koanf:"amazon_connection_timeout" env:"AMAZON_CONNECTION_TIMEOUT" envDefault:"10s"`
-	RemoveUserAgent               bool          `koanf:"remove_user_agent" env:"REMOVE_USER_AGENT" envDefault:"false"`
-	EnableEncrytion               bool          `koanf:"enable_encryption" env:"ENABLE_ENCRYPTION" envDefault:"false"`
-	EncryptionEndpoint            string        `koanf:"encryption_endpoint" env:"ENCRYPTION_ENDPOINT"`
-	DecryptionEndpoint            string        `koanf:"decryption_endpoint" env:"DECRYPTION_ENDPOINT"`
-	EncryptionTimeout             time.Duration `koanf:"encryption_timeout" env:"ENCRYPTION_TIMEOUT" envDefault:"5s"`
-	Audience                      string        `koanf:"audience" env:"AUDIENCE"`
-	XCodioSignSecret              string        `koanf:"x_codio_sign_secret" env:"X_CODIO_SIGN_SECRET"`
+	PostgresqlHosts                  string        `koanf:"postgresql_hosts" env:"POSTGRESQL_HOSTS" envSeparator:":" envDefault:"localhost"`
+	PostgresqlDbName                 string        `koanf:"postgresql_db_name" env:"POSTGRESQL_DB_NAME"`
+	PostgresqlUsername               string        `koanf:"postgresql_username" env:"POSTGRESQL_USERNAME"`
+	PostgresqlPassword               string        `koanf:"postgresql_password" env:"POSTGRESQL_PASSWORD"`
+	PostgresqlSslMode                string        `koanf:"postgresql_ssl_mode" env:"POSTGRESQL_SSL_MODE" envDefault:"disable"`
+	PostgresqlPort                   string        `koanf:"postgresql_port" env:"POSTGRESQL_PORT" envDefault:"5432"`
+	RedisHosts                       string        `koanf:"redis_hosts" env:"REDIS_HOSTS" envSeparator:":" envDefault:"localhost"`
+	RedisPort                        string        `koanf:"redis_port" env:"REDIS_PORT" envDefault:"6379"`
+	RedisUsername                    string        `koanf:"redis_username" env:"REDIS_USERNAME"`
+	RedisPassword                    string        `koanf:"redis_password" env:"REDIS_PASSWORD"`
+	RedisDBStartIndex                int           `koanf:"redis_db_start_index" env:"REDIS_DB_START_INDEX" envDefault:"0"`
+	RedisReadTimeout                 time.Duration `koanf:"redis_read_time_out" env:"REDIS_READ_TIME_OUT" envDefault:"1s"`
+	RedisWriteTimeout                time.Duration `koanf:"redis_write_time_out" env:"REDIS_WRITE_TIME_OUT" envDefault:"500ms"`
+	PostgresqlReadTimeout            time.Duration `koanf:"postgresql_read_time_out" env:"POSTGRESQL_READ_TIME_OUT" envDefault:"10m"`
+	PostgresqlWriteTimeout           time.Duration `koanf:"postgresql_write_time_out" env:"POSTGRESQL_WRITE_TIME_OUT" envDefault:"5s"`
+	InMemoryDbUpdateInterval         time.Duration `koanf:"in_memory_db_update_interval" env:"IN_MEMORY_DB_UPDATE_INTERVAL" envDefault:"5s"`
+	TelemetryProvider                string        `koanf:"telemetry_provider" env:"TELEMETRY_PROVIDER" envDefault:"statsd"`
+	StatsEnabled                     bool          `koanf:"stats_enabled" env:"STATS_ENABLED" envDefault:"true"`
+	StatsAddress                     string        `koanf:"stats_address" env:"STATS_ADDRESS" envDefault:"127.0.0.1:8125"`
+	PrometheusEnabled                bool          `koanf:"prometheus_enabled" env:"PROMETHEUS_ENABLED" envDefault:"true"`
+	PrometheusPort                   string        `koanf:"prometheus_port" env:"PROMETHEUS_PORT" envDefault:"2112"`
+	AdminPass                        string        `koanf:"admin_pass" env:"ADMIN_PASS"`
+	ProxyTimeout                     time.Duration `koanf:"proxy_timeout" env:"PROXY_TIMEOUT" envDefault:"600s"`
+	NumberOfEventMessageConsumers    int           `koanf:"number_of_event_message_consumers" env:"NUMBER_OF_EVENT_MESSAGE_CONSUMERS" envDefault:"3"`
+	OpenAiApiKey                     string        `koanf:"openai_api_key" env:"OPENAI_API_KEY"`
+	CustomPolicyDetectionTimeout     time.Duration `koanf:"custom_policy_detection_timeout" env:"CUSTOM_POLICY_DETECTION_TIMEOUT" envDefault:"10m"`
+	SemanticCacheEmbeddingTimeout    time.Duration `koanf:"semantic_cache_embedding_timeout" env:"SEMANTIC_CACHE_EMBEDDING_TIMEOUT" envDefault:"5s"`
+	SemanticCacheMaxEntries          int           `koanf:"semantic_cache_max_entries" env:"SEMANTIC_CACHE_MAX_ENTRIES" envDefault:"10000"`
+	ProviderSettingEjectionThreshold int           `koanf:"provider_setting_ejection_threshold" env:"PROVIDER_SETTING_EJECTION_THRESHOLD" envDefault:"3"`
+	ProviderSettingEjectionDuration  time.Duration `koanf:"provider_setting_ejection_duration" env:"PROVIDER_SETTING_EJECTION_DURATION" envDefault:"30s"`
+	PiiDetector                      string        `koanf:"pii_detector" env:"PII_DETECTOR" envDefault:"amazon"`
+	AmazonRegion                     string        `koanf:"amazon_region" env:"AMAZON_REGION" envDefault:"us-west-2"`
+	AmazonRequestTimeout             time.Duration `koanf:"amazon_request_timeout" env:"AMAZON_REQUEST_TIMEOUT" envDefault:"5s"`
+	AmazonConnectionTimeout          time.Duration `koanf:"amazon_connection_timeout" env:"AMAZON_CONNECTION_TIMEOUT" envDefault:"10s"`
+	RemoveUserAgent                  bool          `koanf:"remove_user_agent" env:"REMOVE_USER_AGENT" envDefault:"false"`
+	EnableEncrytion                  bool          `koanf:"enable_encryption" env:"ENABLE_ENCRYPTION" envDefault:"false"`
+	EncryptionEndpoint               string        `koanf:"encryption_endpoint" env:"ENCRYPTION_ENDPOINT"`
+	DecryptionEndpoint               string        `koanf:"decryption_endpoint" env:"DECRYPTION_ENDPOINT"`
+	EncryptionTimeout                time.Duration `koanf:"encryption_timeout" env:"ENCRYPTION_TIMEOUT" envDefault:"5s"`
+	Audience                         string        `koanf:"audience" env:"AUDIENCE"`
+	XCodioSignSecret                 string        `koanf:"x_codio_sign_secret" env:"X_CODIO_SIGN_SECRET"`
+	BudgetAlertWebhookSecret         string        `koanf:"budget_alert_webhook_secret" env:"BUDGET_ALERT_WEBHOOK_SECRET"`
+	BudgetAlertWebhookTimeout        time.Duration `koanf:"budget_alert_webhook_timeout" env:"BUDGET_ALERT_WEBHOOK_TIMEOUT" envDefault:"5s"`
+	MessageBus                       string        `koanf:"message_bus" env:"MESSAGE_BUS" envDefault:"memory"`
+	EventStreamName                  string        `koanf:"event_stream_name" env:"EVENT_STREAM_NAME" envDefault:"bricksllm:events"`
+	EventStreamGroup                 string        `koanf:"event_stream_group" env:"EVENT_STREAM_GROUP" envDefault:"bricksllm"`
+	EventStreamDeadLetterName        string        `koanf:"event_stream_dead_letter_name" env:"EVENT_STREAM_DEAD_LETTER_NAME" envDefault:"bricksllm:events:dead"`
+	EventStreamMaxLen                int64         `koanf:"event_stream_max_len" env:"EVENT_STREAM_MAX_LEN" envDefault:"1000000"`
+	EventStreamMaxDeliveries         int64         `koanf:"event_stream_max_deliveries" env:"EVENT_STREAM_MAX_DELIVERIES" envDefault:"5"`
+	EventStreamClaimIdle             time.Duration `koanf:"event_stream_claim_idle" env:"EVENT_STREAM_CLAIM_IDLE" envDefault:"1m"`
+	EventIdempotencyTtl              time.Duration `koanf:"event_idempotency_ttl" env:"EVENT_IDEMPOTENCY_TTL" envDefault:"24h"`
+	ConcurrencyLeaseTtl              time.Duration `koanf:"concurrency_lease_ttl" env:"CONCURRENCY_LEASE_TTL" envDefault:"30s"`
 }
 
 func prepareDotEnv(envFilePath string) error {
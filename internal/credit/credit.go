@@ -0,0 +1,58 @@
+package credit
+
+import (
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+)
+
+type TransactionType string
+
+const (
+	TopUpTransactionType      TransactionType = "top_up"
+	DebitTransactionType      TransactionType = "debit"
+	AdjustmentTransactionType TransactionType = "adjustment"
+)
+
+// Transaction is an entry of the prepaid credit ledger of a user. Top-ups add
+// to the balance, debits subtract the cost of an event and adjustments correct
+// the balance in either direction. BalanceInUsd is the balance of the user
+// right after the transaction.
+type Transaction struct {
+	Id           string          `json:"id"`
+	UserId       string          `json:"userId"`
+	Type         TransactionType `json:"type"`
+	AmountInUsd  float64         `json:"amountInUsd"`
+	BalanceInUsd float64         `json:"balanceInUsd"`
+	EventId      string          `json:"eventId"`
+	Description  string          `json:"description"`
+	CreatedAt    int64           `json:"createdAt"`
+}
+
+// CreateTransaction is a top-up or an adjustment made through the admin API.
+// Debits are only recorded for events.
+type CreateTransaction struct {
+	Type        TransactionType `json:"type"`
+	AmountInUsd float64         `json:"amountInUsd"`
+	Description string          `json:"description"`
+}
+
+func (ct *CreateTransaction) Validate() error {
+	if ct.Type != TopUpTransactionType && ct.Type != AdjustmentTransactionType {
+		return internal_errors.NewValidationError("type has to be either top_up or adjustment")
+	}
+
+	if ct.Type == TopUpTransactionType && ct.AmountInUsd <= 0 {
+		return internal_errors.NewValidationError("amount of a top up has to be positive")
+	}
+
+	if ct.AmountInUsd == 0 {
+		return internal_errors.NewValidationError("amount can not be 0")
+	}
+
+	return nil
+}
+
+type Credits struct {
+	UserId       string         `json:"userId"`
+	BalanceInUsd float64        `json:"balanceInUsd"`
+	Transactions []*Transaction `json:"transactions"`
+}
@@ -0,0 +1,17 @@
+package errors
+
+type BudgetLimitError struct {
+	message string
+}
+
+func NewBudgetLimitError(msg string) *BudgetLimitError {
+	return &BudgetLimitError{
+		message: msg,
+	}
+}
+
+func (ble *BudgetLimitError) Error() string {
+	return ble.message
+}
+
+func (ble *BudgetLimitError) BudgetLimit() {}
@@ -0,0 +1,17 @@
+package errors
+
+type ConcurrencyLimitError struct {
+	message string
+}
+
+func NewConcurrencyLimitError(msg string) *ConcurrencyLimitError {
+	return &ConcurrencyLimitError{
+		message: msg,
+	}
+}
+
+func (cle *ConcurrencyLimitError) Error() string {
+	return cle.message
+}
+
+func (cle *ConcurrencyLimitError) ConcurrencyLimit() {}
@@ -0,0 +1,17 @@
+package errors
+
+type ModelLimitError struct {
+	message string
+}
+
+func NewModelLimitError(msg string) *ModelLimitError {
+	return &ModelLimitError{
+		message: msg,
+	}
+}
+
+func (mle *ModelLimitError) Error() string {
+	return mle.message
+}
+
+func (mle *ModelLimitError) ModelLimit() {}
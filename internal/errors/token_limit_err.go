@@ -0,0 +1,17 @@
+package errors
+
+type TokenLimitError struct {
+	message string
+}
+
+func NewTokenLimitError(msg string) *TokenLimitError {
+	return &TokenLimitError{
+		message: msg,
+	}
+}
+
+func (tle *TokenLimitError) Error() string {
+	return tle.message
+}
+
+func (tle *TokenLimitError) TokenLimit() {}
@@ -8,28 +8,33 @@ import (
 )
 
 type Event struct {
-	Id                   string   `json:"id"`
-	CreatedAt            int64    `json:"created_at"`
-	Tags                 []string `json:"tags"`
-	KeyId                string   `json:"key_id"`
-	CostInUsd            float64  `json:"cost_in_usd"`
-	Provider             string   `json:"provider"`
-	Model                string   `json:"model"`
-	Status               int      `json:"status"`
-	PromptTokenCount     int      `json:"prompt_token_count"`
-	CompletionTokenCount int      `json:"completion_token_count"`
-	LatencyInMs          int      `json:"latency_in_ms"`
-	Path                 string   `json:"path"`
-	Method               string   `json:"method"`
-	CustomId             string   `json:"custom_id"`
-	Request              []byte   `json:"request"`
-	Response             []byte   `json:"response"`
-	UserId               string   `json:"userId"`
-	Action               string   `json:"action"`
-	PolicyId             string   `json:"policyId"`
-	RouteId              string   `json:"routeId"`
-	CorrelationId        string   `json:"correlationId"`
-	Metadata             []byte   `json:"metadata"`
+	Id                      string   `json:"id"`
+	CreatedAt               int64    `json:"created_at"`
+	Tags                    []string `json:"tags"`
+	KeyId                   string   `json:"key_id"`
+	CostInUsd               float64  `json:"cost_in_usd"`
+	Provider                string   `json:"provider"`
+	Model                   string   `json:"model"`
+	Status                  int      `json:"status"`
+	PromptTokenCount        int      `json:"prompt_token_count"`
+	CompletionTokenCount    int      `json:"completion_token_count"`
+	CachedPromptTokenCount  int      `json:"cached_prompt_token_count"`
+	CacheCreationTokenCount int      `json:"cache_creation_token_count"`
+	LatencyInMs             int      `json:"latency_in_ms"`
+	Path                    string   `json:"path"`
+	Method                  string   `json:"method"`
+	CustomId                string   `json:"custom_id"`
+	Request                 []byte   `json:"request"`
+	Response                []byte   `json:"response"`
+	UserId                  string   `json:"userId"`
+	Action                  string   `json:"action"`
+	ResponseAction          string   `json:"responseAction"`
+	PolicyId                string   `json:"policyId"`
+	RouteId                 string   `json:"routeId"`
+	CorrelationId           string   `json:"correlationId"`
+	Metadata                []byte   `json:"metadata"`
+	CacheHit                bool     `json:"cacheHit"`
+	PricedWithCostMap       bool     `json:"pricedWithCostMap"`
 }
 
 type EventResponse struct {
@@ -129,3 +134,13 @@ func (r *EventRequest) Validate() error {
 
 	return nil
 }
+
+// CostUpdate changes the cost of a stored event. The previous cost is used to
+// adjust the aggregated cost of the day the event was created in.
+type CostUpdate struct {
+	EventId           string
+	KeyId             string
+	CreatedAt         int64
+	PreviousCostInUsd float64
+	CostInUsd         float64
+}
@@ -17,4 +17,14 @@ type EventWithRequestAndContent struct {
 	Key                   *key.ResponseKey
 	CostMap               *provider.CostMap
 	ImageResponseMetadata *openai.ImageResponseMetadata
+	TokenReservation      *TokenReservation
+}
+
+// TokenReservation is the estimated prompt tokens added to the token limit
+// counters of a key and a user when their request was admitted. It is
+// reconciled with the actual token usage when the event is handled.
+type TokenReservation struct {
+	KeyTokens  int   `json:"keyTokens"`
+	UserTokens int   `json:"userTokens"`
+	ReservedAt int64 `json:"reservedAt"`
 }
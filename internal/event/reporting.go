@@ -1,17 +1,18 @@
 package event
 
 type DataPoint struct {
-	TimeStamp            int64   `json:"timeStamp"`
-	NumberOfRequests     int64   `json:"numberOfRequests"`
-	CostInUsd            float64 `json:"costInUsd"`
-	LatencyInMs          int     `json:"latencyInMs"`
-	PromptTokenCount     int     `json:"promptTokenCount"`
-	CompletionTokenCount int     `json:"completionTokenCount"`
-	SuccessCount         int     `json:"successCount"`
-	Model                string  `json:"model"`
-	KeyId                string  `json:"keyId"`
-	CustomId             string  `json:"customId"`
-	UserId               string  `json:"userId"`
+	TimeStamp              int64   `json:"timeStamp"`
+	NumberOfRequests       int64   `json:"numberOfRequests"`
+	CostInUsd              float64 `json:"costInUsd"`
+	LatencyInMs            int     `json:"latencyInMs"`
+	PromptTokenCount       int     `json:"promptTokenCount"`
+	CompletionTokenCount   int     `json:"completionTokenCount"`
+	CachedPromptTokenCount int     `json:"cachedPromptTokenCount"`
+	SuccessCount           int     `json:"successCount"`
+	Model                  string  `json:"model"`
+	KeyId                  string  `json:"keyId"`
+	CustomId               string  `json:"customId"`
+	UserId                 string  `json:"userId"`
 }
 
 type DataPointV2 struct {
@@ -3,6 +3,8 @@ package key
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
@@ -12,26 +14,34 @@ import (
 const RevokedReasonExpired string = "expired"
 
 type UpdateKey struct {
-	Name                   string        `json:"name"`
-	UpdatedAt              int64         `json:"updatedAt"`
-	Tags                   []string      `json:"tags"`
-	Revoked                *bool         `json:"revoked"`
-	RevokedReason          string        `json:"revokedReason"`
-	Key                    string        `json:"key"`
-	SettingId              string        `json:"settingId"`
-	SettingIds             []string      `json:"settingIds"`
-	CostLimitInUsd         *float64      `json:"costLimitInUsd"`
-	CostLimitInUsdOverTime *float64      `json:"costLimitInUsdOverTime"`
-	CostLimitInUsdUnit     *TimeUnit     `json:"costLimitInUsdUnit"`
-	RateLimitOverTime      *int          `json:"rateLimitOverTime"`
-	RateLimitUnit          *TimeUnit     `json:"rateLimitUnit"`
-	RequestsLimit          *int          `json:"requestsLimit"`
-	AllowedPaths           *[]PathConfig `json:"allowedPaths,omitempty"`
-	ShouldLogRequest       *bool         `json:"shouldLogRequest"`
-	ShouldLogResponse      *bool         `json:"shouldLogResponse"`
-	RotationEnabled        *bool         `json:"rotationEnabled"`
-	PolicyId               *string       `json:"policyId"`
-	IsKeyNotHashed         *bool         `json:"isKeyNotHashed"`
+	Name                   string                 `json:"name"`
+	UpdatedAt              int64                  `json:"updatedAt"`
+	Tags                   []string               `json:"tags"`
+	Revoked                *bool                  `json:"revoked"`
+	RevokedReason          string                 `json:"revokedReason"`
+	Key                    string                 `json:"key"`
+	SettingId              string                 `json:"settingId"`
+	SettingIds             []string               `json:"settingIds"`
+	CostLimitInUsd         *float64               `json:"costLimitInUsd"`
+	CostLimitInUsdOverTime *float64               `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     *TimeUnit              `json:"costLimitInUsdUnit"`
+	RateLimitOverTime      *int                   `json:"rateLimitOverTime"`
+	RateLimitUnit          *TimeUnit              `json:"rateLimitUnit"`
+	RequestsLimit          *int                   `json:"requestsLimit"`
+	AllowedPaths           *[]PathConfig          `json:"allowedPaths,omitempty"`
+	ShouldLogRequest       *bool                  `json:"shouldLogRequest"`
+	ShouldLogResponse      *bool                  `json:"shouldLogResponse"`
+	RotationEnabled        *bool                  `json:"rotationEnabled"`
+	PolicyId               *string                `json:"policyId"`
+	IsKeyNotHashed         *bool                  `json:"isKeyNotHashed"`
+	CacheConfig            *CacheConfig           `json:"cacheConfig"`
+	RetryConfig            *RetryConfig           `json:"retryConfig"`
+	BudgetAlertConfig      *BudgetAlertConfig     `json:"budgetAlertConfig"`
+	TokenLimitOverTime     *int                   `json:"tokenLimitOverTime"`
+	TokenLimitUnit         *TimeUnit              `json:"tokenLimitUnit"`
+	RateLimitAlgorithm     *RateLimitAlgorithm    `json:"rateLimitAlgorithm"`
+	MaxConcurrentRequests  *int                   `json:"maxConcurrentRequests"`
+	ModelLimits            map[string]*ModelLimit `json:"modelLimits"`
 }
 
 func (uk *UpdateKey) Validate() error {
@@ -88,6 +98,25 @@ func (uk *UpdateKey) Validate() error {
 		}
 	}
 
+	if uk.CacheConfig != nil && !uk.CacheConfig.IsValid() {
+		invalid = append(invalid, "cacheConfig.ttl")
+	}
+
+	if uk.BudgetAlertConfig != nil && !uk.BudgetAlertConfig.IsValid() {
+		invalid = append(invalid, "budgetAlertConfig")
+	}
+
+	for model, ml := range uk.ModelLimits {
+		if len(model) == 0 || ml == nil || !ml.IsValid() {
+			invalid = append(invalid, fmt.Sprintf("modelLimits.%s", model))
+			break
+		}
+	}
+
+	if uk.RetryConfig != nil && uk.RetryConfig.MaxAttempts < 0 {
+		invalid = append(invalid, "retryConfig.maxAttempts")
+	}
+
 	if len(invalid) > 0 {
 		return internal_errors.NewValidationError(fmt.Sprintf("fields [%s] are invalid", strings.Join(invalid, ", ")))
 	}
@@ -130,6 +159,42 @@ func (uk *UpdateKey) Validate() error {
 		}
 	}
 
+	if uk.RateLimitAlgorithm != nil && !uk.RateLimitAlgorithm.IsValid() {
+		return internal_errors.NewValidationError("rate limit algorithm can not be identified")
+	}
+
+	if uk.MaxConcurrentRequests != nil && *uk.MaxConcurrentRequests < 0 {
+		return internal_errors.NewValidationError("max concurrent requests can not be negative")
+	}
+
+	if uk.TokenLimitUnit != nil {
+		if uk.TokenLimitOverTime == nil {
+			return internal_errors.NewValidationError("token limit over time can not be empty if token limit unit is specified")
+		}
+
+		if len(*uk.TokenLimitUnit) == 0 && *uk.TokenLimitOverTime != 0 {
+			return internal_errors.NewValidationError("token limit over time must be 0 if token limit unit is empty")
+		}
+	}
+
+	if uk.TokenLimitOverTime != nil {
+		if *uk.TokenLimitOverTime < 0 {
+			return internal_errors.NewValidationError("token limit over time can not be negative")
+		}
+
+		if uk.TokenLimitUnit == nil {
+			return internal_errors.NewValidationError("token limit unit can not be empty if token limit over time is specified")
+		}
+
+		if *uk.TokenLimitOverTime == 0 && len(*uk.TokenLimitUnit) != 0 {
+			return internal_errors.NewValidationError("token limit unit has to be empty if token limit over time is 0")
+		}
+
+		if *uk.TokenLimitOverTime != 0 && *uk.TokenLimitUnit != HourTimeUnit && *uk.TokenLimitUnit != MinuteTimeUnit && *uk.TokenLimitUnit != SecondTimeUnit && *uk.TokenLimitUnit != DayTimeUnit {
+			return internal_errors.NewValidationError("token limit unit can not be identified")
+		}
+	}
+
 	if uk.CostLimitInUsdOverTime != nil {
 		if uk.CostLimitInUsdUnit == nil {
 			return internal_errors.NewValidationError("cost limit unit can not be empty if cost limit over time is specified")
@@ -151,34 +216,159 @@ func (uk *UpdateKey) Validate() error {
 	return nil
 }
 
+// CacheConfig enables response caching for requests made with a key directly
+// against provider endpoints. Ttl is a duration string such as "1h".
+type CacheConfig struct {
+	Enabled bool   `json:"enabled"`
+	Ttl     string `json:"ttl"`
+}
+
+func (cc *CacheConfig) IsValid() bool {
+	if !cc.Enabled && len(cc.Ttl) == 0 {
+		return true
+	}
+
+	parsed, err := time.ParseDuration(cc.Ttl)
+	if err != nil {
+		return false
+	}
+
+	return parsed > 0
+}
+
+func (cc *CacheConfig) GetTtl() time.Duration {
+	parsed, err := time.ParseDuration(cc.Ttl)
+	if err != nil {
+		return 0
+	}
+
+	return parsed
+}
+
+// RetryConfig replays requests made directly against provider endpoints on
+// another setting of the key when the upstream responds with 429 or 5xx.
+// MaxAttempts includes the first attempt and 0 means every setting is tried.
+type RetryConfig struct {
+	Enabled     bool `json:"enabled"`
+	MaxAttempts int  `json:"maxAttempts"`
+}
+
+// BudgetAlertConfig sends a signed webhook to WebhookUrl when the spend of a
+// key or user crosses one of the Thresholds, given as percentages of its cost
+// limits. Each threshold fires once per cost limit period.
+type BudgetAlertConfig struct {
+	WebhookUrl string `json:"webhookUrl"`
+	Thresholds []int  `json:"thresholds"`
+}
+
+var DefaultBudgetAlertThresholds = []int{50, 80, 100}
+
+func (bac *BudgetAlertConfig) IsValid() bool {
+	if len(bac.WebhookUrl) == 0 {
+		return len(bac.Thresholds) == 0
+	}
+
+	parsed, err := url.ParseRequestURI(bac.WebhookUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return false
+	}
+
+	for _, t := range bac.Thresholds {
+		if t <= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (bac *BudgetAlertConfig) GetThresholds() []int {
+	if len(bac.Thresholds) == 0 {
+		return DefaultBudgetAlertThresholds
+	}
+
+	return bac.Thresholds
+}
+
+// ModelLimit caps the spend and the request rate of a key for a single
+// model, on top of the limits of the key that apply across all models.
+type ModelLimit struct {
+	CostLimitInUsdOverTime float64  `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     TimeUnit `json:"costLimitInUsdUnit"`
+	RateLimitOverTime      int      `json:"rateLimitOverTime"`
+	RateLimitUnit          TimeUnit `json:"rateLimitUnit"`
+}
+
+func (ml *ModelLimit) IsValid() bool {
+	if ml.CostLimitInUsdOverTime < 0 || ml.RateLimitOverTime < 0 {
+		return false
+	}
+
+	if (ml.CostLimitInUsdOverTime == 0) != (len(ml.CostLimitInUsdUnit) == 0) {
+		return false
+	}
+
+	if (ml.RateLimitOverTime == 0) != (len(ml.RateLimitUnit) == 0) {
+		return false
+	}
+
+	if ml.CostLimitInUsdOverTime != 0 && ml.CostLimitInUsdUnit != DayTimeUnit && ml.CostLimitInUsdUnit != HourTimeUnit && ml.CostLimitInUsdUnit != MonthTimeUnit && ml.CostLimitInUsdUnit != MinuteTimeUnit {
+		return false
+	}
+
+	if ml.RateLimitOverTime != 0 && ml.RateLimitUnit != HourTimeUnit && ml.RateLimitUnit != MinuteTimeUnit && ml.RateLimitUnit != SecondTimeUnit && ml.RateLimitUnit != DayTimeUnit {
+		return false
+	}
+
+	return true
+}
+
+// modelSnapshotSuffix matches the suffix of dated model snapshots such as
+// gpt-4o-2024-08-06 and claude-3-5-sonnet-20240620.
+var modelSnapshotSuffix = regexp.MustCompile(`-(\d{4}-\d{2}-\d{2}|\d{8}|latest)$`)
+
+// GetModelLimitCounterId returns the id under which the per model counters of
+// a key are kept in the rate limit and cost limit caches.
+func GetModelLimitCounterId(keyId, model string) string {
+	return fmt.Sprintf("%s:model:%s", keyId, model)
+}
+
 type PathConfig struct {
 	Method string `json:"method"`
 	Path   string `json:"path"`
 }
 
 type RequestKey struct {
-	Name                   string       `json:"name"`
-	CreatedAt              int64        `json:"createdAt"`
-	UpdatedAt              int64        `json:"updatedAt"`
-	Tags                   []string     `json:"tags"`
-	KeyId                  string       `json:"keyId"`
-	Key                    string       `json:"key"`
-	CostLimitInUsd         float64      `json:"costLimitInUsd"`
-	CostLimitInUsdOverTime float64      `json:"costLimitInUsdOverTime"`
-	CostLimitInUsdUnit     TimeUnit     `json:"costLimitInUsdUnit"`
-	RateLimitOverTime      int          `json:"rateLimitOverTime"`
-	RateLimitUnit          TimeUnit     `json:"rateLimitUnit"`
-	Ttl                    string       `json:"ttl"`
-	KeyRing                string       `json:"keyRing"`
-	SettingId              string       `json:"settingId"`
-	AllowedPaths           []PathConfig `json:"allowedPaths"`
-	SettingIds             []string     `json:"settingIds"`
-	ShouldLogRequest       bool         `json:"shouldLogRequest"`
-	ShouldLogResponse      bool         `json:"shouldLogResponse"`
-	RotationEnabled        bool         `json:"rotationEnabled"`
-	PolicyId               string       `json:"policyId"`
-	IsKeyNotHashed         bool         `json:"isKeyNotHashed"`
-	RequestsLimit          int          `json:"requestsLimit"`
+	Name                   string                 `json:"name"`
+	CreatedAt              int64                  `json:"createdAt"`
+	UpdatedAt              int64                  `json:"updatedAt"`
+	Tags                   []string               `json:"tags"`
+	KeyId                  string                 `json:"keyId"`
+	Key                    string                 `json:"key"`
+	CostLimitInUsd         float64                `json:"costLimitInUsd"`
+	CostLimitInUsdOverTime float64                `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     TimeUnit               `json:"costLimitInUsdUnit"`
+	RateLimitOverTime      int                    `json:"rateLimitOverTime"`
+	RateLimitUnit          TimeUnit               `json:"rateLimitUnit"`
+	Ttl                    string                 `json:"ttl"`
+	KeyRing                string                 `json:"keyRing"`
+	SettingId              string                 `json:"settingId"`
+	AllowedPaths           []PathConfig           `json:"allowedPaths"`
+	SettingIds             []string               `json:"settingIds"`
+	ShouldLogRequest       bool                   `json:"shouldLogRequest"`
+	ShouldLogResponse      bool                   `json:"shouldLogResponse"`
+	RotationEnabled        bool                   `json:"rotationEnabled"`
+	PolicyId               string                 `json:"policyId"`
+	IsKeyNotHashed         bool                   `json:"isKeyNotHashed"`
+	RequestsLimit          int                    `json:"requestsLimit"`
+	CacheConfig            *CacheConfig           `json:"cacheConfig"`
+	RetryConfig            *RetryConfig           `json:"retryConfig"`
+	BudgetAlertConfig      *BudgetAlertConfig     `json:"budgetAlertConfig"`
+	TokenLimitOverTime     int                    `json:"tokenLimitOverTime"`
+	TokenLimitUnit         TimeUnit               `json:"tokenLimitUnit"`
+	RateLimitAlgorithm     RateLimitAlgorithm     `json:"rateLimitAlgorithm"`
+	MaxConcurrentRequests  int                    `json:"maxConcurrentRequests"`
+	ModelLimits            map[string]*ModelLimit `json:"modelLimits"`
 }
 
 func (rk *RequestKey) Validate() error {
@@ -247,6 +437,10 @@ func (rk *RequestKey) Validate() error {
 		invalid = append(invalid, "requestsLimit")
 	}
 
+	if rk.TokenLimitOverTime < 0 {
+		invalid = append(invalid, "tokenLimitOverTime")
+	}
+
 	if len(rk.Ttl) != 0 {
 		_, err := time.ParseDuration(rk.Ttl)
 		if err != nil {
@@ -254,6 +448,25 @@ func (rk *RequestKey) Validate() error {
 		}
 	}
 
+	if rk.CacheConfig != nil && !rk.CacheConfig.IsValid() {
+		invalid = append(invalid, "cacheConfig.ttl")
+	}
+
+	if rk.RetryConfig != nil && rk.RetryConfig.MaxAttempts < 0 {
+		invalid = append(invalid, "retryConfig.maxAttempts")
+	}
+
+	if rk.BudgetAlertConfig != nil && !rk.BudgetAlertConfig.IsValid() {
+		invalid = append(invalid, "budgetAlertConfig")
+	}
+
+	for model, ml := range rk.ModelLimits {
+		if len(model) == 0 || ml == nil || !ml.IsValid() {
+			invalid = append(invalid, fmt.Sprintf("modelLimits.%s", model))
+			break
+		}
+	}
+
 	if len(rk.AllowedPaths) != 0 {
 		for index, p := range rk.AllowedPaths {
 			if len(p.Path) == 0 {
@@ -290,6 +503,28 @@ func (rk *RequestKey) Validate() error {
 		}
 	}
 
+	if !rk.RateLimitAlgorithm.IsValid() {
+		return internal_errors.NewValidationError("rate limit algorithm can not be identified")
+	}
+
+	if rk.MaxConcurrentRequests < 0 {
+		return internal_errors.NewValidationError("max concurrent requests can not be negative")
+	}
+
+	if len(rk.TokenLimitUnit) != 0 && rk.TokenLimitOverTime == 0 {
+		return internal_errors.NewValidationError("token limit over time can not be empty if token limit unit is specified")
+	}
+
+	if rk.TokenLimitOverTime != 0 {
+		if len(rk.TokenLimitUnit) == 0 {
+			return internal_errors.NewValidationError("token limit unit can not be empty if token limit over time is specified")
+		}
+
+		if rk.TokenLimitUnit != HourTimeUnit && rk.TokenLimitUnit != MinuteTimeUnit && rk.TokenLimitUnit != SecondTimeUnit && rk.TokenLimitUnit != DayTimeUnit {
+			return internal_errors.NewValidationError("token limit unit can not be identified")
+		}
+	}
+
 	if rk.CostLimitInUsdOverTime != 0 {
 		if len(rk.CostLimitInUsdUnit) == 0 {
 			return internal_errors.NewValidationError("cost limit unit can not be empty if cost limit over time is specified")
@@ -313,31 +548,72 @@ const (
 	MonthTimeUnit  TimeUnit = "mo"
 )
 
+// GetWindowStart returns the start of the fixed window of the time unit that
+// contains t. Windows are aligned in UTC like the limit counters.
+func GetWindowStart(unit TimeUnit, t time.Time) time.Time {
+	t = t.UTC()
+	switch unit {
+	case SecondTimeUnit:
+		return t.Truncate(time.Second)
+	case MinuteTimeUnit:
+		return t.Truncate(time.Minute)
+	case HourTimeUnit:
+		return t.Truncate(time.Hour)
+	case DayTimeUnit:
+		return t.Truncate(24 * time.Hour)
+	case MonthTimeUnit:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return t
+}
+
+type RateLimitAlgorithm string
+
+const (
+	FixedWindowRateLimitAlgorithm   RateLimitAlgorithm = "fixed_window"
+	SlidingWindowRateLimitAlgorithm RateLimitAlgorithm = "sliding_window"
+)
+
+// IsValid reports whether the algorithm is known. An empty algorithm falls
+// back to fixed window.
+func (a RateLimitAlgorithm) IsValid() bool {
+	return len(a) == 0 || a == FixedWindowRateLimitAlgorithm || a == SlidingWindowRateLimitAlgorithm
+}
+
 type ResponseKey struct {
-	Name                   string       `json:"name"`
-	CreatedAt              int64        `json:"createdAt"`
-	UpdatedAt              int64        `json:"updatedAt"`
-	Tags                   []string     `json:"tags"`
-	KeyId                  string       `json:"keyId"`
-	Revoked                bool         `json:"revoked"`
-	Key                    string       `json:"key"`
-	RevokedReason          string       `json:"revokedReason"`
-	CostLimitInUsd         float64      `json:"costLimitInUsd"`
-	CostLimitInUsdOverTime float64      `json:"costLimitInUsdOverTime"`
-	CostLimitInUsdUnit     TimeUnit     `json:"costLimitInUsdUnit"`
-	RateLimitOverTime      int          `json:"rateLimitOverTime"`
-	RateLimitUnit          TimeUnit     `json:"rateLimitUnit"`
-	RequestsLimit          int          `json:"requestsLimit"`
-	Ttl                    string       `json:"ttl"`
-	KeyRing                string       `json:"keyRing"`
-	SettingId              string       `json:"settingId"`
-	AllowedPaths           []PathConfig `json:"allowedPaths"`
-	SettingIds             []string     `json:"settingIds"`
-	ShouldLogRequest       bool         `json:"shouldLogRequest"`
-	ShouldLogResponse      bool         `json:"shouldLogResponse"`
-	RotationEnabled        bool         `json:"rotationEnabled"`
-	PolicyId               string       `json:"policyId"`
-	IsKeyNotHashed         bool         `json:"isKeyNotHashed"`
+	Name                   string                 `json:"name"`
+	CreatedAt              int64                  `json:"createdAt"`
+	UpdatedAt              int64                  `json:"updatedAt"`
+	Tags                   []string               `json:"tags"`
+	KeyId                  string                 `json:"keyId"`
+	Revoked                bool                   `json:"revoked"`
+	Key                    string                 `json:"key"`
+	RevokedReason          string                 `json:"revokedReason"`
+	CostLimitInUsd         float64                `json:"costLimitInUsd"`
+	CostLimitInUsdOverTime float64                `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     TimeUnit               `json:"costLimitInUsdUnit"`
+	RateLimitOverTime      int                    `json:"rateLimitOverTime"`
+	RateLimitUnit          TimeUnit               `json:"rateLimitUnit"`
+	RequestsLimit          int                    `json:"requestsLimit"`
+	Ttl                    string                 `json:"ttl"`
+	KeyRing                string                 `json:"keyRing"`
+	SettingId              string                 `json:"settingId"`
+	AllowedPaths           []PathConfig           `json:"allowedPaths"`
+	SettingIds             []string               `json:"settingIds"`
+	ShouldLogRequest       bool                   `json:"shouldLogRequest"`
+	ShouldLogResponse      bool                   `json:"shouldLogResponse"`
+	RotationEnabled        bool                   `json:"rotationEnabled"`
+	PolicyId               string                 `json:"policyId"`
+	IsKeyNotHashed         bool                   `json:"isKeyNotHashed"`
+	CacheConfig            *CacheConfig           `json:"cacheConfig"`
+	RetryConfig            *RetryConfig           `json:"retryConfig"`
+	BudgetAlertConfig      *BudgetAlertConfig     `json:"budgetAlertConfig"`
+	TokenLimitOverTime     int                    `json:"tokenLimitOverTime"`
+	TokenLimitUnit         TimeUnit               `json:"tokenLimitUnit"`
+	RateLimitAlgorithm     RateLimitAlgorithm     `json:"rateLimitAlgorithm"`
+	MaxConcurrentRequests  int                    `json:"maxConcurrentRequests"`
+	ModelLimits            map[string]*ModelLimit `json:"modelLimits"`
 }
 
 func (rk *ResponseKey) GetSettingIds() []string {
@@ -354,3 +630,25 @@ func (rk *ResponseKey) GetSettingIds() []string {
 
 	return settingIds
 }
+
+// GetModelLimit returns the limit of the key for a model along with the model
+// name it is configured under. Snapshots of a model fall under the limit of
+// their alias unless they have a limit of their own.
+func (rk *ResponseKey) GetModelLimit(model string) (string, *ModelLimit) {
+	if len(model) == 0 || len(rk.ModelLimits) == 0 {
+		return "", nil
+	}
+
+	candidates := []string{model}
+	if alias := modelSnapshotSuffix.ReplaceAllString(model, ""); alias != model {
+		candidates = append(candidates, alias, alias+"-latest")
+	}
+
+	for _, candidate := range candidates {
+		if ml, ok := rk.ModelLimits[candidate]; ok && ml != nil {
+			return candidate, ml
+		}
+	}
+
+	return "", nil
+}
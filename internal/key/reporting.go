@@ -20,3 +20,50 @@ type GetKeysResponse struct {
 	Keys  []*ResponseKey `json:"keys"`
 	Count int            `json:"count"`
 }
+
+// KeyUsage is the usage of a key against its limits as reported to the key
+// holder. Window usages are only set for the limits that the key has.
+type KeyUsage struct {
+	KeyId              string                 `json:"keyId"`
+	Name               string                 `json:"name"`
+	CostInUsd          float64                `json:"costInUsd"`
+	CostLimitInUsd     float64                `json:"costLimitInUsd"`
+	RemainingCostInUsd *float64               `json:"remainingCostInUsd,omitempty"`
+	Requests           int64                  `json:"requests"`
+	RequestsLimit      int                    `json:"requestsLimit"`
+	RemainingRequests  *int64                 `json:"remainingRequests,omitempty"`
+	CostLimitOverTime  *WindowUsage           `json:"costLimitOverTime,omitempty"`
+	RateLimitOverTime  *WindowUsage           `json:"rateLimitOverTime,omitempty"`
+	TokenLimitOverTime *WindowUsage           `json:"tokenLimitOverTime,omitempty"`
+	ModelLimits        map[string]*ModelUsage `json:"modelLimits,omitempty"`
+	ExpiresAt          int64                  `json:"expiresAt,omitempty"`
+	AllowedPaths       []PathConfig           `json:"allowedPaths"`
+	AllowedModels      map[string][]string    `json:"allowedModels"`
+}
+
+// WindowUsage is the usage of a limit within the current window of its unit.
+type WindowUsage struct {
+	Used      float64  `json:"used"`
+	Limit     float64  `json:"limit"`
+	Remaining float64  `json:"remaining"`
+	Unit      TimeUnit `json:"unit"`
+}
+
+func NewWindowUsage(used, limit float64, unit TimeUnit) *WindowUsage {
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &WindowUsage{
+		Used:      used,
+		Limit:     limit,
+		Remaining: remaining,
+		Unit:      unit,
+	}
+}
+
+type ModelUsage struct {
+	CostLimitOverTime *WindowUsage `json:"costLimitOverTime,omitempty"`
+	RateLimitOverTime *WindowUsage `json:"rateLimitOverTime,omitempty"`
+}
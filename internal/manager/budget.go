@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/budget"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+type BudgetsStorage interface {
+	CreateBudget(b *budget.Budget) (*budget.Budget, error)
+	UpdateBudget(id string, ub *budget.UpdateBudget) (*budget.Budget, error)
+	ArchiveBudget(id string, updatedAt int64) error
+	GetBudget(id string) (*budget.Budget, error)
+	GetBudgets(keyRing, tag string) ([]*budget.Budget, error)
+}
+
+type BudgetsMemStorage interface {
+	SetBudget(b *budget.Budget)
+}
+
+type BudgetManager struct {
+	s  BudgetsStorage
+	ms BudgetsMemStorage
+}
+
+func NewBudgetManager(s BudgetsStorage, ms BudgetsMemStorage) *BudgetManager {
+	return &BudgetManager{
+		s:  s,
+		ms: ms,
+	}
+}
+
+func (m *BudgetManager) CreateBudget(b *budget.Budget) (*budget.Budget, error) {
+	b.Id = util.NewUuid()
+	b.CreatedAt = time.Now().Unix()
+	b.UpdatedAt = time.Now().Unix()
+	b.Archived = false
+
+	err := b.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := m.s.CreateBudget(b)
+	if err != nil {
+		return nil, err
+	}
+
+	m.ms.SetBudget(created)
+
+	return created, nil
+}
+
+func (m *BudgetManager) UpdateBudget(id string, ub *budget.UpdateBudget) (*budget.Budget, error) {
+	err := ub.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := m.s.GetBudget(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ub.Apply(existing).Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	ub.UpdatedAt = time.Now().Unix()
+
+	updated, err := m.s.UpdateBudget(id, ub)
+	if err != nil {
+		return nil, err
+	}
+
+	m.ms.SetBudget(updated)
+
+	return updated, nil
+}
+
+func (m *BudgetManager) DeleteBudget(id string) error {
+	existing, err := m.s.GetBudget(id)
+	if err != nil {
+		return err
+	}
+
+	existing.UpdatedAt = time.Now().Unix()
+	err = m.s.ArchiveBudget(id, existing.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	existing.Archived = true
+	m.ms.SetBudget(existing)
+
+	return nil
+}
+
+func (m *BudgetManager) GetBudget(id string) (*budget.Budget, error) {
+	return m.s.GetBudget(id)
+}
+
+func (m *BudgetManager) GetBudgets(keyRing, tag string) ([]*budget.Budget, error) {
+	return m.s.GetBudgets(keyRing, tag)
+}
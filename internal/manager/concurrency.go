@@ -0,0 +1,88 @@
+package manager
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+type concurrencyCache interface {
+	AcquireLease(id, leaseId string, limit int, ttl time.Duration) (bool, error)
+	ExtendLease(id, leaseId string, ttl time.Duration) error
+	ReleaseLease(id, leaseId string) error
+}
+
+// ConcurrencyManager bounds the number of in-flight requests of keys and
+// users. Held leases are extended in the background until they are released
+// so that long running streams keep their slot while leases of a crashed
+// instance expire after ttl.
+type ConcurrencyManager struct {
+	c      concurrencyCache
+	ttl    time.Duration
+	lock   sync.Mutex
+	leases map[string]chan bool
+}
+
+func NewConcurrencyManager(c concurrencyCache, ttl time.Duration) *ConcurrencyManager {
+	return &ConcurrencyManager{
+		c:      c,
+		ttl:    ttl,
+		leases: map[string]chan bool{},
+	}
+}
+
+// Acquire takes one of the limit slots of the id and returns the id of the
+// lease to release once the request finishes.
+func (m *ConcurrencyManager) Acquire(id string, limit int) (string, error) {
+	leaseId := util.NewUuid()
+	acquired, err := m.c.AcquireLease(id, leaseId, limit, m.ttl)
+	if err != nil {
+		return "", err
+	}
+
+	if !acquired {
+		return "", internal_errors.NewConcurrencyLimitError(fmt.Sprintf("max concurrent requests of %d reached", limit))
+	}
+
+	done := make(chan bool)
+	m.lock.Lock()
+	m.leases[leaseId] = done
+	m.lock.Unlock()
+
+	go m.extend(id, leaseId, done)
+
+	return leaseId, nil
+}
+
+func (m *ConcurrencyManager) extend(id, leaseId string, done chan bool) {
+	ticker := time.NewTicker(m.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := m.c.ExtendLease(id, leaseId, m.ttl); err != nil {
+				telemetry.Incr("bricksllm.manager.concurrency_manager.extend.extend_lease_error", nil, 1)
+			}
+		}
+	}
+}
+
+func (m *ConcurrencyManager) Release(id, leaseId string) error {
+	m.lock.Lock()
+	done, ok := m.leases[leaseId]
+	delete(m.leases, leaseId)
+	m.lock.Unlock()
+
+	if ok {
+		close(done)
+	}
+
+	return m.c.ReleaseLease(id, leaseId)
+}
@@ -0,0 +1,58 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/credit"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+type CreditStorage interface {
+	CreateCreditTransaction(t *credit.Transaction) (*credit.Transaction, error)
+	GetCreditBalance(userId string) (float64, error)
+	GetCreditTransactions(userId string, offset, limit int) ([]*credit.Transaction, error)
+}
+
+type CreditManager struct {
+	s CreditStorage
+}
+
+func NewCreditManager(s CreditStorage) *CreditManager {
+	return &CreditManager{
+		s: s,
+	}
+}
+
+func (m *CreditManager) CreateTransaction(userId string, ct *credit.CreateTransaction) (*credit.Transaction, error) {
+	err := ct.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.s.CreateCreditTransaction(&credit.Transaction{
+		Id:          util.NewUuid(),
+		UserId:      userId,
+		Type:        ct.Type,
+		AmountInUsd: ct.AmountInUsd,
+		Description: ct.Description,
+		CreatedAt:   time.Now().Unix(),
+	})
+}
+
+func (m *CreditManager) GetCredits(userId string, offset, limit int) (*credit.Credits, error) {
+	balance, err := m.s.GetCreditBalance(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := m.s.GetCreditTransactions(userId, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &credit.Credits{
+		UserId:       userId,
+		BalanceInUsd: balance,
+		Transactions: transactions,
+	}, nil
+}
@@ -0,0 +1,184 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+)
+
+type counterCache interface {
+	GetCounter(keyId string, timeUnit key.TimeUnit) (int64, error)
+}
+
+type slidingWindowCounterCache interface {
+	counterCache
+	GetSlidingWindowCounter(keyId string, timeUnit key.TimeUnit) (int64, error)
+}
+
+type settingsCache interface {
+	GetSettingViaCache(id string) (*provider.Setting, error)
+}
+
+// KeyUsageManager reads the counters that the limits of a key are enforced
+// with, so that key holders can see how much of their limits is left.
+type KeyUsageManager struct {
+	cs  costStorage
+	rqs costStorage
+	clc counterCache
+	rlc slidingWindowCounterCache
+	tlc counterCache
+	psm settingsCache
+}
+
+func NewKeyUsageManager(cs, rqs costStorage, clc counterCache, rlc slidingWindowCounterCache, tlc counterCache, psm settingsCache) *KeyUsageManager {
+	return &KeyUsageManager{
+		cs:  cs,
+		rqs: rqs,
+		clc: clc,
+		rlc: rlc,
+		tlc: tlc,
+		psm: psm,
+	}
+}
+
+func (m *KeyUsageManager) GetKeyUsage(k *key.ResponseKey) (*key.KeyUsage, error) {
+	micros, err := m.cs.GetCounter(k.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := m.rqs.GetCounter(k.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &key.KeyUsage{
+		KeyId:          k.KeyId,
+		Name:           k.Name,
+		CostInUsd:      convertMicroDollarsToDollars(micros),
+		CostLimitInUsd: k.CostLimitInUsd,
+		Requests:       requests,
+		RequestsLimit:  k.RequestsLimit,
+		AllowedPaths:   k.AllowedPaths,
+		AllowedModels:  m.getAllowedModels(k),
+	}
+
+	if k.CostLimitInUsd != 0 {
+		remaining := k.CostLimitInUsd - usage.CostInUsd
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		usage.RemainingCostInUsd = &remaining
+	}
+
+	if k.RequestsLimit != 0 {
+		remaining := int64(k.RequestsLimit) - requests
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		usage.RemainingRequests = &remaining
+	}
+
+	if k.CostLimitInUsdOverTime != 0 {
+		c, err := m.clc.GetCounter(k.KeyId, k.CostLimitInUsdUnit)
+		if err != nil {
+			return nil, err
+		}
+
+		usage.CostLimitOverTime = key.NewWindowUsage(convertMicroDollarsToDollars(c), k.CostLimitInUsdOverTime, k.CostLimitInUsdUnit)
+	}
+
+	if k.RateLimitOverTime != 0 {
+		getCounter := m.rlc.GetCounter
+		if k.RateLimitAlgorithm == key.SlidingWindowRateLimitAlgorithm {
+			getCounter = m.rlc.GetSlidingWindowCounter
+		}
+
+		c, err := getCounter(k.KeyId, k.RateLimitUnit)
+		if err != nil {
+			return nil, err
+		}
+
+		usage.RateLimitOverTime = key.NewWindowUsage(float64(c), float64(k.RateLimitOverTime), k.RateLimitUnit)
+	}
+
+	if k.TokenLimitOverTime != 0 {
+		c, err := m.tlc.GetCounter(k.KeyId, k.TokenLimitUnit)
+		if err != nil {
+			return nil, err
+		}
+
+		usage.TokenLimitOverTime = key.NewWindowUsage(float64(c), float64(k.TokenLimitOverTime), k.TokenLimitUnit)
+	}
+
+	if len(k.ModelLimits) != 0 {
+		usage.ModelLimits = map[string]*key.ModelUsage{}
+	}
+
+	for model, ml := range k.ModelLimits {
+		id := key.GetModelLimitCounterId(k.KeyId, model)
+		mu := &key.ModelUsage{}
+
+		if ml.CostLimitInUsdOverTime != 0 {
+			c, err := m.clc.GetCounter(id, ml.CostLimitInUsdUnit)
+			if err != nil {
+				return nil, err
+			}
+
+			mu.CostLimitOverTime = key.NewWindowUsage(convertMicroDollarsToDollars(c), ml.CostLimitInUsdOverTime, ml.CostLimitInUsdUnit)
+		}
+
+		if ml.RateLimitOverTime != 0 {
+			c, err := m.rlc.GetCounter(id, ml.RateLimitUnit)
+			if err != nil {
+				return nil, err
+			}
+
+			mu.RateLimitOverTime = key.NewWindowUsage(float64(c), float64(ml.RateLimitOverTime), ml.RateLimitUnit)
+		}
+
+		usage.ModelLimits[model] = mu
+	}
+
+	ttl, _ := time.ParseDuration(k.Ttl)
+	if ttl > 0 {
+		usage.ExpiresAt = k.CreatedAt + int64(ttl.Seconds())
+	}
+
+	return usage, nil
+}
+
+// getAllowedModels returns the models that the key can use per provider. An
+// empty list means that every model of the provider is allowed.
+func (m *KeyUsageManager) getAllowedModels(k *key.ResponseKey) map[string][]string {
+	allowed := map[string][]string{}
+	for _, id := range k.GetSettingIds() {
+		setting, err := m.psm.GetSettingViaCache(id)
+		if err != nil || setting == nil {
+			telemetry.Incr("bricksllm.manager.get_allowed_models.get_setting_error", nil, 1)
+			continue
+		}
+
+		models, ok := allowed[setting.Provider]
+		if ok && len(models) == 0 {
+			continue
+		}
+
+		if len(setting.AllowedModels) == 0 {
+			allowed[setting.Provider] = []string{}
+			continue
+		}
+
+		allowed[setting.Provider] = append(models, setting.AllowedModels...)
+	}
+
+	return allowed
+}
+
+func convertMicroDollarsToDollars(micros int64) float64 {
+	return float64(micros) / 1000000
+}
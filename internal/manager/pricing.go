@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/pricing"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+type PricesStorage interface {
+	CreatePrice(p *pricing.Price) (*pricing.Price, error)
+	UpdatePrice(id string, p *pricing.UpdatePrice) (*pricing.Price, error)
+	ArchivePrice(id string, updatedAt int64) error
+	GetPrice(id string) (*pricing.Price, error)
+	GetPrices(provider, model string) ([]*pricing.Price, error)
+}
+
+type PricesMemStorage interface {
+	SetPrice(p *pricing.Price)
+}
+
+type PricingManager struct {
+	s  PricesStorage
+	ms PricesMemStorage
+}
+
+func NewPricingManager(s PricesStorage, ms PricesMemStorage) *PricingManager {
+	return &PricingManager{
+		s:  s,
+		ms: ms,
+	}
+}
+
+func (m *PricingManager) CreatePrice(p *pricing.Price) (*pricing.Price, error) {
+	err := p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	p.Id = util.NewUuid()
+	p.CreatedAt = time.Now().Unix()
+	p.UpdatedAt = time.Now().Unix()
+	p.Archived = false
+
+	// prices without an effective date only apply to requests made from now
+	// on so that events recorded earlier keep being priced consistently.
+	if p.EffectiveFrom == 0 {
+		p.EffectiveFrom = p.CreatedAt
+	}
+
+	created, err := m.s.CreatePrice(p)
+	if err != nil {
+		return nil, err
+	}
+
+	m.ms.SetPrice(created)
+
+	return created, nil
+}
+
+func (m *PricingManager) UpdatePrice(id string, p *pricing.UpdatePrice) (*pricing.Price, error) {
+	err := p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	p.UpdatedAt = time.Now().Unix()
+
+	updated, err := m.s.UpdatePrice(id, p)
+	if err != nil {
+		return nil, err
+	}
+
+	m.ms.SetPrice(updated)
+
+	return updated, nil
+}
+
+func (m *PricingManager) DeletePrice(id string) error {
+	existing, err := m.s.GetPrice(id)
+	if err != nil {
+		return err
+	}
+
+	existing.UpdatedAt = time.Now().Unix()
+	err = m.s.ArchivePrice(id, existing.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	existing.Archived = true
+	m.ms.SetPrice(existing)
+
+	return nil
+}
+
+func (m *PricingManager) GetPrice(id string) (*pricing.Price, error) {
+	return m.s.GetPrice(id)
+}
+
+func (m *PricingManager) GetPrices(provider, model string) ([]*pricing.Price, error) {
+	return m.s.GetPrices(provider, model)
+}
@@ -41,7 +41,7 @@ type ProviderSettingsManager struct {
 	Encryptor Encryptor
 }
 
-var nativelySupportedProviders = []string{"openai", "anthropic", "azure", "vllm", "deepinfra", "bedrock", "xCustom"}
+var nativelySupportedProviders = []string{"openai", "anthropic", "azure", "vllm", "deepinfra", "bedrock", "gemini", "xCustom"}
 
 func NewProviderSettingsManager(s ProviderSettingsStorage, cache ProviderSettingsCache, encryptor Encryptor) *ProviderSettingsManager {
 	return &ProviderSettingsManager{
@@ -58,7 +58,7 @@ func isProviderNativelySupported(provider string) bool {
 func findMissingAuthParams(providerName string, params map[string]string) string {
 	missingFields := []string{}
 
-	if providerName == "openai" || providerName == "anthropic" || providerName == "deepinfra" {
+	if providerName == "openai" || providerName == "anthropic" || providerName == "deepinfra" || providerName == "gemini" {
 		val := params["apikey"]
 		if len(val) == 0 {
 			missingFields = append(missingFields, "apikey")
@@ -158,7 +158,7 @@ func (m *ProviderSettingsManager) EncryptParams(updatedAt int64, provider string
 
 		params["awsSecretAccessKey"] = encryted
 
-	} else if provider == "openai" || provider == "anthropic" || provider == "deepinfra" || provider == "azure" {
+	} else if provider == "openai" || provider == "anthropic" || provider == "deepinfra" || provider == "azure" || provider == "gemini" {
 		encryted, err := m.Encryptor.Encrypt(params["apikey"], map[string]string{"X-UPDATED-AT": strconv.FormatInt(updatedAt, 10)})
 		if err != nil {
 			return nil, err
@@ -170,6 +170,33 @@ func (m *ProviderSettingsManager) EncryptParams(updatedAt int64, provider string
 	return params, nil
 }
 
+var unifiedProviders = map[string]bool{
+	"openai":    true,
+	"azure":     true,
+	"anthropic": true,
+	"bedrock":   true,
+	"vllm":      true,
+	"deepinfra": true,
+}
+
+func validateModelMap(providerName string, modelMap map[string]string) error {
+	if len(modelMap) == 0 {
+		return nil
+	}
+
+	if !unifiedProviders[providerName] {
+		return internal_errors.NewValidationError(fmt.Sprintf("model map is not supported by provider %s", providerName))
+	}
+
+	for model, mapped := range modelMap {
+		if len(model) == 0 || len(mapped) == 0 {
+			return internal_errors.NewValidationError("model map cannot contain empty model names")
+		}
+	}
+
+	return nil
+}
+
 func (m *ProviderSettingsManager) CreateSetting(setting *provider.Setting) (*provider.Setting, error) {
 	if len(setting.Provider) == 0 {
 		return nil, internal_errors.NewValidationError("provider field cannot be empty")
@@ -179,6 +206,14 @@ func (m *ProviderSettingsManager) CreateSetting(setting *provider.Setting) (*pro
 		return nil, err
 	}
 
+	if setting.Weight < 0 {
+		return nil, internal_errors.NewValidationError("weight cannot be negative")
+	}
+
+	if err := validateModelMap(setting.Provider, setting.ModelMap); err != nil {
+		return nil, err
+	}
+
 	setting.Id = util.NewUuid()
 	setting.CreatedAt = time.Now().Unix()
 	setting.UpdatedAt = time.Now().Unix()
@@ -212,11 +247,19 @@ func (m *ProviderSettingsManager) UpdateSetting(id string, setting *provider.Upd
 		return nil, internal_errors.NewValidationError("id cannot be empty")
 	}
 
+	if setting.Weight != nil && *setting.Weight < 0 {
+		return nil, internal_errors.NewValidationError("weight cannot be negative")
+	}
+
 	existing, _ := m.Storage.GetProviderSetting(id, true)
 	if existing == nil {
 		return nil, internal_errors.NewNotFoundError("provider setting is not found")
 	}
 
+	if err := validateModelMap(existing.Provider, setting.ModelMap); err != nil {
+		return nil, err
+	}
+
 	if len(setting.Setting) != 0 {
 		merged, err := m.getMergedSettings(existing, setting.Setting)
 		if err != nil {
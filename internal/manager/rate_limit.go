@@ -6,19 +6,32 @@ type Cache interface {
 	IncrementCounter(keyId string, rateLimitUnit key.TimeUnit, incr int64) error
 }
 
+type slidingWindowCache interface {
+	Cache
+	IncrementSlidingWindowCounter(keyId string, rateLimitUnit key.TimeUnit) error
+}
+
 type RateLimitManager struct {
-	c  Cache
-	uc Cache
+	c   slidingWindowCache
+	uc  Cache
+	tc  Cache
+	utc Cache
 }
 
-func NewRateLimitManager(c Cache, uc Cache) *RateLimitManager {
+func NewRateLimitManager(c slidingWindowCache, uc Cache, tc Cache, utc Cache) *RateLimitManager {
 	return &RateLimitManager{
-		c:  c,
-		uc: uc,
+		c:   c,
+		uc:  uc,
+		tc:  tc,
+		utc: utc,
 	}
 }
 
-func (rlm *RateLimitManager) Increment(keyId string, timeUnit key.TimeUnit) error {
+func (rlm *RateLimitManager) Increment(keyId string, timeUnit key.TimeUnit, algorithm key.RateLimitAlgorithm) error {
+	if algorithm == key.SlidingWindowRateLimitAlgorithm {
+		return rlm.c.IncrementSlidingWindowCounter(keyId, timeUnit)
+	}
+
 	err := rlm.c.IncrementCounter(keyId, timeUnit, 1)
 
 	if err != nil {
@@ -37,3 +50,23 @@ func (rlm *RateLimitManager) IncrementUser(id string, timeUnit key.TimeUnit) err
 
 	return nil
 }
+
+func (rlm *RateLimitManager) IncrementTokens(keyId string, timeUnit key.TimeUnit, tks int64) error {
+	err := rlm.tc.IncrementCounter(keyId, timeUnit, tks)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (rlm *RateLimitManager) IncrementUserTokens(id string, timeUnit key.TimeUnit, tks int64) error {
+	err := rlm.utc.IncrementCounter(id, timeUnit, tks)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,367 @@
+package manager
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/event"
+	"github.com/bricks-cloud/bricksllm/internal/pricing"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/provider/azure"
+	"github.com/bricks-cloud/bricksllm/internal/provider/deepinfra"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
+	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+const repricingBatchSize = 500
+
+type RepricingStorage interface {
+	CountEventsForRepricing(start, end int64, provider, model string) (int, error)
+	GetEventsForRepricing(start, end int64, provider, model string, afterCreatedAt int64, afterId string, limit int) ([]*event.Event, error)
+	UpdateEventCosts(updates []*event.CostUpdate) error
+}
+
+type PricesCatalog interface {
+	GetPrices(provider string) []*pricing.Price
+}
+
+// RepricingManager runs repricing jobs in the background. Jobs are kept in
+// memory and only one job runs at a time so that the aggregated costs of
+// overlapping time ranges are not adjusted concurrently.
+type RepricingManager struct {
+	s    RepricingStorage
+	pc   PricesCatalog
+	jobs map[string]*pricing.RepricingJob
+	lock sync.RWMutex
+}
+
+func NewRepricingManager(s RepricingStorage, pc PricesCatalog) *RepricingManager {
+	return &RepricingManager{
+		s:    s,
+		pc:   pc,
+		jobs: map[string]*pricing.RepricingJob{},
+	}
+}
+
+func (m *RepricingManager) CreateRepricingJob(r *pricing.RepricingRequest) (*pricing.RepricingJob, error) {
+	err := r.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, job := range m.jobs {
+		if job.Status == pricing.RepricingStatusPending || job.Status == pricing.RepricingStatusRunning {
+			return nil, internal_errors.NewValidationError("repricing job " + job.Id + " is still running")
+		}
+	}
+
+	job := &pricing.RepricingJob{
+		Id:        util.NewUuid(),
+		CreatedAt: time.Now().Unix(),
+		UpdatedAt: time.Now().Unix(),
+		Status:    pricing.RepricingStatusPending,
+		Request:   r,
+		Summary: &pricing.RepricingSummary{
+			Models: map[string]*pricing.ModelRepricingSummary{},
+		},
+	}
+
+	m.jobs[job.Id] = job
+
+	go m.run(job.Id)
+
+	return copyRepricingJob(job), nil
+}
+
+func (m *RepricingManager) GetRepricingJob(id string) (*pricing.RepricingJob, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, internal_errors.NewNotFoundError("repricing job is not found for id: " + id)
+	}
+
+	return copyRepricingJob(job), nil
+}
+
+func (m *RepricingManager) GetRepricingJobs() []*pricing.RepricingJob {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	jobs := []*pricing.RepricingJob{}
+	for _, job := range m.jobs {
+		jobs = append(jobs, copyRepricingJob(job))
+	}
+
+	return jobs
+}
+
+func copyRepricingJob(job *pricing.RepricingJob) *pricing.RepricingJob {
+	copied := *job
+
+	summary := *job.Summary
+	summary.Models = map[string]*pricing.ModelRepricingSummary{}
+	for k, ms := range job.Summary.Models {
+		copiedMs := *ms
+		summary.Models[k] = &copiedMs
+	}
+
+	copied.Summary = &summary
+
+	return &copied
+}
+
+func (m *RepricingManager) update(id string, fn func(job *pricing.RepricingJob)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	job := m.jobs[id]
+	fn(job)
+	job.UpdatedAt = time.Now().Unix()
+}
+
+func (m *RepricingManager) fail(id string, err error) {
+	telemetry.Incr("bricksllm.manager.repricing_manager.run.error", nil, 1)
+
+	m.update(id, func(job *pricing.RepricingJob) {
+		job.Status = pricing.RepricingStatusFailed
+		job.Error = err.Error()
+	})
+}
+
+func (m *RepricingManager) run(id string) {
+	m.lock.RLock()
+	r := m.jobs[id].Request
+	m.lock.RUnlock()
+
+	total, err := m.s.CountEventsForRepricing(r.Start, r.End, r.Provider, r.Model)
+	if err != nil {
+		m.fail(id, err)
+		return
+	}
+
+	m.update(id, func(job *pricing.RepricingJob) {
+		job.Status = pricing.RepricingStatusRunning
+		job.Total = total
+	})
+
+	ep := newEventPricer(r.Prices, m.pc)
+	afterCreatedAt := r.Start - 1
+	afterId := ""
+
+	for {
+		events, err := m.s.GetEventsForRepricing(r.Start, r.End, r.Provider, r.Model, afterCreatedAt, afterId, repricingBatchSize)
+		if err != nil {
+			m.fail(id, err)
+			return
+		}
+
+		if len(events) == 0 {
+			break
+		}
+
+		type repriced struct {
+			e    *event.Event
+			cost float64
+		}
+
+		priced := []repriced{}
+		updates := []*event.CostUpdate{}
+		for _, e := range events {
+			cost, ok := ep.price(e)
+			if !ok {
+				continue
+			}
+
+			priced = append(priced, repriced{e: e, cost: cost})
+			if math.Abs(cost-e.CostInUsd) > 1e-12 {
+				updates = append(updates, &event.CostUpdate{
+					EventId:           e.Id,
+					KeyId:             e.KeyId,
+					CreatedAt:         e.CreatedAt,
+					PreviousCostInUsd: e.CostInUsd,
+					CostInUsd:         cost,
+				})
+			}
+		}
+
+		if !r.DryRun {
+			err = m.s.UpdateEventCosts(updates)
+			if err != nil {
+				m.fail(id, err)
+				return
+			}
+		}
+
+		m.update(id, func(job *pricing.RepricingJob) {
+			job.Processed += len(events)
+			job.Repriced += len(priced)
+			job.Skipped += len(events) - len(priced)
+
+			for _, p := range priced {
+				job.Summary.Add(p.e.Provider, p.e.Model, p.e.CostInUsd, p.cost)
+			}
+		})
+
+		last := events[len(events)-1]
+		afterCreatedAt = last.CreatedAt
+		afterId = last.Id
+	}
+
+	telemetry.Incr("bricksllm.manager.repricing_manager.run.success", nil, 1)
+
+	m.update(id, func(job *pricing.RepricingJob) {
+		job.Status = pricing.RepricingStatusCompleted
+	})
+}
+
+type repricingCostMap struct {
+	costMap    map[string]map[string]float64
+	validFrom  int64
+	validUntil int64
+}
+
+// eventPricer prices stored events from their token counts using the
+// provider cost estimators.
+type eventPricer struct {
+	supplied []*pricing.Price
+	pc       PricesCatalog
+	catalog  map[string][]*pricing.Price
+	costMaps map[string]*repricingCostMap
+}
+
+func newEventPricer(prices map[string]map[string]float64, pc PricesCatalog) *eventPricer {
+	supplied := []*pricing.Price{}
+	for category, models := range prices {
+		for model, cost := range models {
+			supplied = append(supplied, &pricing.Price{
+				Category: category,
+				Model:    model,
+				Cost:     cost,
+			})
+		}
+	}
+
+	return &eventPricer{
+		supplied: supplied,
+		pc:       pc,
+		catalog:  map[string][]*pricing.Price{},
+		costMaps: map[string]*repricingCostMap{},
+	}
+}
+
+func (ep *eventPricer) getCostMap(provider string, base map[string]map[string]float64, at int64) map[string]map[string]float64 {
+	if len(ep.supplied) != 0 {
+		cached, ok := ep.costMaps[provider]
+		if !ok {
+			cached = &repricingCostMap{costMap: pricing.Apply(base, ep.supplied, 0)}
+			ep.costMaps[provider] = cached
+		}
+
+		return cached.costMap
+	}
+
+	prices, ok := ep.catalog[provider]
+	if !ok {
+		prices = ep.pc.GetPrices(provider)
+		ep.catalog[provider] = prices
+	}
+
+	cached, ok := ep.costMaps[provider]
+	if ok && at >= cached.validFrom && at < cached.validUntil {
+		return cached.costMap
+	}
+
+	var validFrom int64 = math.MinInt64
+	for _, p := range prices {
+		if !p.Archived && p.EffectiveFrom <= at && p.EffectiveFrom > validFrom {
+			validFrom = p.EffectiveFrom
+		}
+	}
+
+	cached = &repricingCostMap{
+		costMap:    pricing.Apply(base, prices, at),
+		validFrom:  validFrom,
+		validUntil: pricing.NextChange(prices, at),
+	}
+	ep.costMaps[provider] = cached
+
+	return cached.costMap
+}
+
+// pricedAt is a price catalog that returns the prices in effect at a fixed time.
+type pricedAt struct {
+	ep *eventPricer
+	at int64
+}
+
+func (p *pricedAt) GetCostMap(provider string, base map[string]map[string]float64) map[string]map[string]float64 {
+	return p.ep.getCostMap(provider, base, p.at)
+}
+
+func (ep *eventPricer) price(e *event.Event) (float64, bool) {
+	if e.Status != http.StatusOK || e.CacheHit || e.PromptTokenCount+e.CompletionTokenCount == 0 {
+		return 0, false
+	}
+
+	// the custom prices of provider settings take precedence over the catalog.
+	if e.PricedWithCostMap {
+		return 0, false
+	}
+
+	pc := &pricedAt{ep: ep, at: e.CreatedAt}
+	isEmbeddings := strings.HasSuffix(e.Path, "/embeddings")
+	isCompletions := strings.HasSuffix(e.Path, "/completions") || strings.HasSuffix(e.Path, "/messages") || strings.HasSuffix(e.Path, "/complete")
+
+	var cost float64
+	var err error
+
+	switch {
+	case e.Provider == "openai" && isEmbeddings:
+		cost, err = openai.NewCostEstimator(openai.OpenAiPerThousandTokenCost, nil, pc).EstimateEmbeddingsInputCost(e.Model, e.PromptTokenCount)
+	case e.Provider == "openai" && isCompletions:
+		cost, err = openai.NewCostEstimator(openai.OpenAiPerThousandTokenCost, nil, pc).EstimateTotalCostWithCachedTokens(e.Model, e.PromptTokenCount, e.CachedPromptTokenCount, e.CompletionTokenCount)
+	case e.Provider == "azure" && isEmbeddings:
+		cost, err = azure.NewCostEstimator(pc).EstimateEmbeddingsInputCost(e.Model, e.PromptTokenCount)
+	case e.Provider == "azure" && isCompletions:
+		cost, err = azure.NewCostEstimator(pc).EstimateTotalCost(e.Model, e.PromptTokenCount, e.CompletionTokenCount)
+	case (e.Provider == "anthropic" || e.Provider == "bedrock") && isCompletions:
+		ce := anthropic.NewCostEstimator(nil, pc)
+
+		// prompt token counts of anthropic events include the tokens written
+		// to and read from the prompt cache.
+		uncached := e.PromptTokenCount - e.CachedPromptTokenCount - e.CacheCreationTokenCount
+		if uncached < 0 {
+			uncached = 0
+		}
+
+		cost, err = ce.EstimateTotalCost(e.Model, uncached, e.CompletionTokenCount)
+		if err == nil {
+			var cachedCost float64
+			cachedCost, err = ce.EstimateCachedPromptCost(e.Model, e.CacheCreationTokenCount, e.CachedPromptTokenCount)
+			cost += cachedCost
+		}
+	case e.Provider == "deepinfra" && isEmbeddings:
+		cost, err = deepinfra.NewCostEstimator(pc).EstimateEmbeddingsInputCost(e.Model, e.PromptTokenCount)
+	case e.Provider == "gemini":
+		cost, err = gemini.NewCostEstimator(nil, pc).EstimateTotalCost(e.Model, e.PromptTokenCount, e.CompletionTokenCount)
+	default:
+		return 0, false
+	}
+
+	if err != nil {
+		return 0, false
+	}
+
+	return cost, true
+}
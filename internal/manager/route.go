@@ -79,6 +79,20 @@ func addDefaultValues(r *route.Route) {
 		r.CacheConfig.Ttl = "168h"
 	}
 
+	if r.CacheConfig != nil && len(r.CacheConfig.Mode) == 0 {
+		r.CacheConfig.Mode = route.CacheModeExact
+	}
+
+	if r.CacheConfig.IsSemantic() {
+		if r.CacheConfig.SimilarityThreshold == 0 {
+			r.CacheConfig.SimilarityThreshold = 0.95
+		}
+
+		if len(r.CacheConfig.EmbeddingModel) == 0 {
+			r.CacheConfig.EmbeddingModel = "text-embedding-3-small"
+		}
+	}
+
 	for _, step := range r.Steps {
 		if len(step.Timeout) == 0 {
 			step.Timeout = "5m"
@@ -443,6 +457,20 @@ func (m *RouteManager) validateRoute(r *route.Route) error {
 		}
 	}
 
+	if r.CacheConfig != nil && len(r.CacheConfig.Mode) != 0 && r.CacheConfig.Mode != route.CacheModeExact && r.CacheConfig.Mode != route.CacheModeSemantic {
+		fields = append(fields, "cacheConfig.mode")
+	}
+
+	if r.CacheConfig.IsSemantic() {
+		if r.ShouldRunEmbeddings() {
+			return internal_errors.NewValidationError("cacheConfig.mode semantic is only supported for chat completion routes")
+		}
+
+		if r.CacheConfig.SimilarityThreshold < 0 || r.CacheConfig.SimilarityThreshold > 1 {
+			fields = append(fields, "cacheConfig.similarityThreshold")
+		}
+	}
+
 	found, err := m.ks.GetKeys(nil, r.KeyIds, "")
 	if err != nil {
 		return err
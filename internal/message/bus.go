@@ -1,5 +1,9 @@
 package message
 
+type Publisher interface {
+	Publish(ms Message)
+}
+
 type MessageBus struct {
 	Subscribers map[string][]chan<- Message
 }
@@ -0,0 +1,203 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/bricks-cloud/bricksllm/internal/event"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/provider/custom"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
+	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
+	"github.com/bricks-cloud/bricksllm/internal/provider/vllm"
+	goopenai "github.com/sashabaranov/go-openai"
+)
+
+const bytesPayloadType = "bytes"
+
+// payloadTypes maps the names used on the wire to constructors of the request
+// and response types carried by EventWithRequestAndContent.
+var payloadTypes = map[string]func() interface{}{
+	"goopenai.ChatCompletionRequest": func() interface{} { return &goopenai.ChatCompletionRequest{} },
+	"goopenai.CompletionRequest":     func() interface{} { return &goopenai.CompletionRequest{} },
+	"goopenai.EmbeddingRequest":      func() interface{} { return &goopenai.EmbeddingRequest{} },
+	"goopenai.ImageRequest":          func() interface{} { return &goopenai.ImageRequest{} },
+	"goopenai.ImageEditRequest":      func() interface{} { return &goopenai.ImageEditRequest{} },
+	"goopenai.ImageVariRequest":      func() interface{} { return &goopenai.ImageVariRequest{} },
+	"goopenai.CreateSpeechRequest":   func() interface{} { return &goopenai.CreateSpeechRequest{} },
+	"openai.ResponseRequest":         func() interface{} { return &openai.ResponseRequest{} },
+	"openai.VideoRequest":            func() interface{} { return &openai.VideoRequest{} },
+	"anthropic.CompletionRequest":    func() interface{} { return &anthropic.CompletionRequest{} },
+	"vllm.ChatRequest":               func() interface{} { return &vllm.ChatRequest{} },
+	"vllm.CompletionRequest":         func() interface{} { return &vllm.CompletionRequest{} },
+	"gemini.GenerateContentRequest":  func() interface{} { return &gemini.GenerateContentRequest{} },
+}
+
+func payloadTypeOf(v interface{}) (string, error) {
+	switch v.(type) {
+	case []byte:
+		return bytesPayloadType, nil
+	case *goopenai.ChatCompletionRequest:
+		return "goopenai.ChatCompletionRequest", nil
+	case *goopenai.CompletionRequest:
+		return "goopenai.CompletionRequest", nil
+	case *goopenai.EmbeddingRequest:
+		return "goopenai.EmbeddingRequest", nil
+	case *goopenai.ImageRequest:
+		return "goopenai.ImageRequest", nil
+	case *goopenai.ImageEditRequest:
+		return "goopenai.ImageEditRequest", nil
+	case *goopenai.ImageVariRequest:
+		return "goopenai.ImageVariRequest", nil
+	case *goopenai.CreateSpeechRequest:
+		return "goopenai.CreateSpeechRequest", nil
+	case *openai.ResponseRequest:
+		return "openai.ResponseRequest", nil
+	case *openai.VideoRequest:
+		return "openai.VideoRequest", nil
+	case *anthropic.CompletionRequest:
+		return "anthropic.CompletionRequest", nil
+	case *vllm.ChatRequest:
+		return "vllm.ChatRequest", nil
+	case *vllm.CompletionRequest:
+		return "vllm.CompletionRequest", nil
+	case *gemini.GenerateContentRequest:
+		return "gemini.GenerateContentRequest", nil
+	}
+
+	return "", fmt.Errorf("unsupported payload type %T", v)
+}
+
+type streamEvent struct {
+	Event                 *event.Event                  `json:"event"`
+	IsEmbeddingsRequest   bool                          `json:"isEmbeddingsRequest"`
+	RouteConfig           *custom.RouteConfig           `json:"routeConfig,omitempty"`
+	RequestType           string                        `json:"requestType,omitempty"`
+	Request               json.RawMessage               `json:"request,omitempty"`
+	Content               string                        `json:"content,omitempty"`
+	ResponseType          string                        `json:"responseType,omitempty"`
+	Response              json.RawMessage               `json:"response,omitempty"`
+	Key                   *key.ResponseKey              `json:"key,omitempty"`
+	CostMap               *provider.CostMap             `json:"costMap,omitempty"`
+	ImageResponseMetadata *openai.ImageResponseMetadata `json:"imageResponseMetadata,omitempty"`
+	TokenReservation      *event.TokenReservation       `json:"tokenReservation,omitempty"`
+}
+
+func encodePayload(v interface{}) (string, json.RawMessage, error) {
+	if v == nil {
+		return "", nil, nil
+	}
+
+	t, err := payloadTypeOf(v)
+	if err != nil {
+		return "", nil, err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return t, data, nil
+}
+
+func decodePayload(t string, data json.RawMessage) (interface{}, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+
+	if t == bytesPayloadType {
+		bs := []byte{}
+		if err := json.Unmarshal(data, &bs); err != nil {
+			return nil, err
+		}
+
+		return bs, nil
+	}
+
+	constructor, ok := payloadTypes[t]
+	if !ok {
+		return nil, fmt.Errorf("unsupported payload type %s", t)
+	}
+
+	v := constructor()
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// encodeMessage serializes a message so that it can be stored outside of the
+// process. Only event messages are supported.
+func encodeMessage(m Message) ([]byte, error) {
+	switch data := m.Data.(type) {
+	case *event.EventWithRequestAndContent:
+		se := &streamEvent{
+			Event:                 data.Event,
+			IsEmbeddingsRequest:   data.IsEmbeddingsRequest,
+			RouteConfig:           data.RouteConfig,
+			Content:               data.Content,
+			Key:                   data.Key,
+			CostMap:               data.CostMap,
+			ImageResponseMetadata: data.ImageResponseMetadata,
+			TokenReservation:      data.TokenReservation,
+		}
+
+		t, payload, err := encodePayload(data.Request)
+		if err != nil {
+			return nil, err
+		}
+
+		se.RequestType = t
+		se.Request = payload
+
+		t, payload, err = encodePayload(data.Response)
+		if err != nil {
+			return nil, err
+		}
+
+		se.ResponseType = t
+		se.Response = payload
+
+		return json.Marshal(se)
+	}
+
+	return nil, errors.New("message data cannot be serialized")
+}
+
+func decodeMessage(messageType string, data []byte) (Message, error) {
+	se := &streamEvent{}
+	if err := json.Unmarshal(data, se); err != nil {
+		return Message{}, err
+	}
+
+	req, err := decodePayload(se.RequestType, se.Request)
+	if err != nil {
+		return Message{}, err
+	}
+
+	resp, err := decodePayload(se.ResponseType, se.Response)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Type: messageType,
+		Data: &event.EventWithRequestAndContent{
+			Event:                 se.Event,
+			IsEmbeddingsRequest:   se.IsEmbeddingsRequest,
+			RouteConfig:           se.RouteConfig,
+			Request:               req,
+			Content:               se.Content,
+			Response:              resp,
+			Key:                   se.Key,
+			CostMap:               se.CostMap,
+			ImageResponseMetadata: se.ImageResponseMetadata,
+			TokenReservation:      se.TokenReservation,
+		},
+	}, nil
+}
@@ -0,0 +1,69 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/event"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
+	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
+	"github.com/bricks-cloud/bricksllm/internal/provider/vllm"
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec_RoundTripRequests(t *testing.T) {
+	// every type the proxy middleware assigns to the request of an event.
+	requests := []interface{}{
+		[]byte(`{"input":"hi"}`),
+		&goopenai.ChatCompletionRequest{Model: "gpt-4o"},
+		&goopenai.CompletionRequest{Model: "gpt-3.5-turbo-instruct"},
+		&goopenai.EmbeddingRequest{Model: "text-embedding-3-small"},
+		&goopenai.ImageRequest{Model: "dall-e-3"},
+		&goopenai.ImageEditRequest{Model: "dall-e-2"},
+		&goopenai.ImageVariRequest{Model: "dall-e-2"},
+		&goopenai.CreateSpeechRequest{Model: "tts-1"},
+		&openai.ResponseRequest{},
+		&openai.VideoRequest{Model: "sora-2", Prompt: "a cat", Size: "1280x720"},
+		&anthropic.CompletionRequest{Model: "claude-2"},
+		&vllm.ChatRequest{},
+		&vllm.CompletionRequest{},
+		&gemini.GenerateContentRequest{},
+	}
+
+	for _, req := range requests {
+		name, err := payloadTypeOf(req)
+		require.NoError(t, err)
+
+		t.Run(name, func(t *testing.T) {
+			data, err := encodeMessage(Message{
+				Type: "event",
+				Data: &event.EventWithRequestAndContent{
+					Event:   &event.Event{Id: "id"},
+					Request: req,
+				},
+			})
+			require.NoError(t, err)
+
+			m, err := decodeMessage("event", data)
+			require.NoError(t, err)
+
+			decoded, ok := m.Data.(*event.EventWithRequestAndContent)
+			require.True(t, ok)
+			assert.Equal(t, "id", decoded.Event.Id)
+			assert.Equal(t, req, decoded.Request)
+		})
+	}
+}
+
+func TestCodec_UnsupportedRequest(t *testing.T) {
+	_, err := encodeMessage(Message{
+		Type: "event",
+		Data: &event.EventWithRequestAndContent{
+			Event:   &event.Event{},
+			Request: "not a request",
+		},
+	})
+	assert.Error(t, err)
+}
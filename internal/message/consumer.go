@@ -15,10 +15,13 @@ type Consumer struct {
 }
 
 type recorder interface {
-	RecordKeySpend(keyId string, micros int64, costLimitUnit key.TimeUnit) error
-	RecordUserSpend(userId string, micros int64, costLimitUnit key.TimeUnit) error
+	RecordKeySpend(eventId, keyId, model string, micros int64, costLimitUnit, modelCostLimitUnit key.TimeUnit) error
+	RecordUserSpend(eventId, userId string, micros int64, costLimitUnit key.TimeUnit) error
+	RecordBudgetSpend(eventId, budgetId string, micros int64, costLimitUnit key.TimeUnit) error
+	RecordUserDebit(eventId, userId string, costInUsd float64) error
 	RecordEvent(e *event.Event) error
-	RecordKeyRequestSpent(keyId string) error
+	RecordKeyRequestSpent(eventId, keyId string) error
+	Once(eventId, operation string, record func() error) error
 }
 
 func NewConsumer(mc <-chan Message, log *zap.Logger, num int, handle func(Message) error) *Consumer {
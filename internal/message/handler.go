@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bricks-cloud/bricksllm/internal/budget"
 	"github.com/bricks-cloud/bricksllm/internal/event"
 	"github.com/bricks-cloud/bricksllm/internal/key"
 	"github.com/bricks-cloud/bricksllm/internal/provider"
@@ -77,8 +78,19 @@ type userManager interface {
 }
 
 type rateLimitManager interface {
-	Increment(keyId string, timeUnit key.TimeUnit) error
+	Increment(keyId string, timeUnit key.TimeUnit, algorithm key.RateLimitAlgorithm) error
 	IncrementUser(id string, timeUnit key.TimeUnit) error
+	IncrementTokens(keyId string, timeUnit key.TimeUnit, tks int64) error
+	IncrementUserTokens(id string, timeUnit key.TimeUnit, tks int64) error
+}
+
+type budgetAlerter interface {
+	CheckKey(k *key.ResponseKey) error
+	CheckUser(u *user.User) error
+}
+
+type budgetsStorage interface {
+	GetKeyBudgets(k *key.ResponseKey) []*budget.Budget
 }
 
 type accessCache interface {
@@ -103,9 +115,11 @@ type Handler struct {
 	rlm      rateLimitManager
 	ac       accessCache
 	uac      userAccessCache
+	ba       budgetAlerter
+	bs       budgetsStorage
 }
 
-func NewHandler(r recorder, log *zap.Logger, ae anthropicEstimator, e estimator, vllme vllmEstimator, aze azureEstimator, v validator, uv userValidator, km keyManager, um userManager, rlm rateLimitManager, ac accessCache, uac accessCache) *Handler {
+func NewHandler(r recorder, log *zap.Logger, ae anthropicEstimator, e estimator, vllme vllmEstimator, aze azureEstimator, v validator, uv userValidator, km keyManager, um userManager, rlm rateLimitManager, ac accessCache, uac accessCache, ba budgetAlerter, bs budgetsStorage) *Handler {
 	return &Handler{
 		recorder: r,
 		log:      log,
@@ -120,6 +134,8 @@ func NewHandler(r recorder, log *zap.Logger, ae anthropicEstimator, e estimator,
 		rlm:      rlm,
 		ac:       ac,
 		uac:      uac,
+		ba:       ba,
+		bs:       bs,
 	}
 }
 
@@ -177,6 +193,21 @@ func getContentFromJson(bytes []byte, contentLoc string) string {
 	return content
 }
 
+// getUnreservedTokens returns the tokens left to count after the reservation
+// made when the request was admitted. A reservation made in an earlier window
+// has already expired with its counter, so the full usage is counted instead.
+func getUnreservedTokens(tks int64, reserved int, reservedAt int64, unit key.TimeUnit) int64 {
+	if reserved == 0 || len(unit) == 0 {
+		return tks
+	}
+
+	if !key.GetWindowStart(unit, time.Unix(reservedAt, 0)).Equal(key.GetWindowStart(unit, time.Now())) {
+		return tks
+	}
+
+	return tks - int64(reserved)
+}
+
 type costLimitError interface {
 	Error() string
 	CostLimit()
@@ -187,12 +218,27 @@ type rateLimitError interface {
 	RateLimit()
 }
 
+type tokenLimitError interface {
+	Error() string
+	TokenLimit()
+}
+
+type budgetLimitError interface {
+	Error() string
+	BudgetLimit()
+}
+
 type expirationError interface {
 	Error() string
 	Reason() string
 }
 
 func (h *Handler) handleValidationResult(kc *key.ResponseKey, cost float64) error {
+	if err := h.ba.CheckKey(kc); err != nil {
+		telemetry.Incr("bricksllm.message.handler.handle_validation_result.check_budget_alert_error", nil, 1)
+		h.log.Debug("error when checking key budget alerts", zap.Error(err))
+	}
+
 	err := h.v.Validate(kc, cost)
 
 	if err != nil {
@@ -232,6 +278,13 @@ func (h *Handler) handleValidationResult(kc *key.ResponseKey, cost float64) erro
 			}
 		}
 
+		// budgets are shared by several keys, so they are checked by the proxy on
+		// every request instead of blocking access to a single key.
+		if _, ok := err.(budgetLimitError); ok {
+			telemetry.Incr("bricksllm.message.handler.handle_validation_result.budget_limit_error", nil, 1)
+			return nil
+		}
+
 		if _, ok := err.(rateLimitError); ok {
 			telemetry.Incr("bricksllm.message.handler.handle_validation_result.rate_limit_error", nil, 1)
 
@@ -244,6 +297,18 @@ func (h *Handler) handleValidationResult(kc *key.ResponseKey, cost float64) erro
 			return nil
 		}
 
+		if _, ok := err.(tokenLimitError); ok {
+			telemetry.Incr("bricksllm.message.handler.handle_validation_result.token_limit_error", nil, 1)
+
+			err = h.ac.Set(kc.KeyId, kc.TokenLimitUnit)
+			if err != nil {
+				telemetry.Incr("bricksllm.message.handler.handle_validation_result.set_token_limit_error", nil, 1)
+				return err
+			}
+
+			return nil
+		}
+
 		if _, ok := err.(costLimitError); ok {
 			telemetry.Incr("bricksllm.message.handler.handle_validation_result.cost_limit_error", nil, 1)
 
@@ -263,6 +328,11 @@ func (h *Handler) handleValidationResult(kc *key.ResponseKey, cost float64) erro
 }
 
 func (h *Handler) handleUserValidationResult(u *user.User, cost float64) error {
+	if err := h.ba.CheckUser(u); err != nil {
+		telemetry.Incr("bricksllm.message.handler.handle_user_validation_result.check_budget_alert_error", nil, 1)
+		h.log.Debug("error when checking user budget alerts", zap.Error(err))
+	}
+
 	err := h.uv.Validate(u, cost)
 
 	if err != nil {
@@ -297,6 +367,18 @@ func (h *Handler) handleUserValidationResult(u *user.User, cost float64) error {
 			return nil
 		}
 
+		if _, ok := err.(tokenLimitError); ok {
+			telemetry.Incr("bricksllm.message.handler.handle_user_validation_result.token_limit_error", nil, 1)
+
+			err = h.uac.Set(u.Id, u.TokenLimitUnit)
+			if err != nil {
+				telemetry.Incr("bricksllm.message.handler.handle_user_validation_result.set_token_limit_error", nil, 1)
+				return err
+			}
+
+			return nil
+		}
+
 		if _, ok := err.(costLimitError); ok {
 			telemetry.Incr("bricksllm.message.handler.handle_user_validation_result.cost_limit_error", nil, 1)
 
@@ -323,6 +405,10 @@ func (h *Handler) HandleEventWithRequestAndResponse(m Message) error {
 		return errors.New("message data cannot be parsed as event with request and response")
 	}
 
+	// spend errors are returned after the event is recorded so that the
+	// message is redelivered. Every counter write goes through the recorder's
+	// Once, so it is applied a single time per event id.
+	var spendErr error
 	if e.Key != nil && !e.Key.Revoked && e.Event != nil {
 		err := h.decorateEvent(m)
 		if err != nil {
@@ -331,51 +417,146 @@ func (h *Handler) HandleEventWithRequestAndResponse(m Message) error {
 		}
 
 		var u *user.User
+		budgets := h.bs.GetKeyBudgets(e.Key)
+		limitedModel, modelLimit := e.Key.GetModelLimit(e.Event.Model)
 
-		err = h.recorder.RecordKeyRequestSpent(e.Event.KeyId)
+		err = h.recorder.RecordKeyRequestSpent(e.Event.Id, e.Event.KeyId)
 		if err != nil {
 			telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.record_key_request_spend_error", nil, 1)
 			h.log.Debug("error when recording key request spend", zap.Error(err))
 		}
 
+		if len(e.Event.UserId) != 0 {
+			us, err := h.um.GetUsers(e.Key.Tags, nil, []string{e.Event.UserId}, 0, 0)
+			if err != nil {
+				telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.get_users_error", nil, 1)
+				h.log.Debug("error when getting users", zap.Error(err))
+			}
+
+			if len(us) == 1 {
+				u = us[0]
+			}
+		}
+
 		if e.Event.CostInUsd != 0 {
 			micros := int64(e.Event.CostInUsd * 1000000)
-			err = h.recorder.RecordKeySpend(e.Event.KeyId, micros, e.Key.CostLimitInUsdUnit)
+			var modelCostLimitUnit key.TimeUnit
+			if modelLimit != nil {
+				modelCostLimitUnit = modelLimit.CostLimitInUsdUnit
+			}
+
+			err = h.recorder.RecordKeySpend(e.Event.Id, e.Event.KeyId, limitedModel, micros, e.Key.CostLimitInUsdUnit, modelCostLimitUnit)
 			if err != nil {
 				telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.record_key_spend_error", nil, 1)
 				h.log.Debug("error when recording key spend", zap.Error(err))
+				spendErr = err
 			}
 
-			if len(e.Event.UserId) != 0 {
-				us, err := h.um.GetUsers(e.Key.Tags, nil, []string{e.Event.UserId}, 0, 0)
+			if u != nil {
+				err = h.recorder.RecordUserSpend(e.Event.Id, u.Id, micros, u.CostLimitInUsdUnit)
 				if err != nil {
-					telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.get_users_error", nil, 1)
-					h.log.Debug("error when getting users", zap.Error(err))
+					telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.record_user_spend_error", nil, 1)
+					h.log.Debug("error when recording user spend", zap.Error(err))
+					spendErr = err
 				}
 
-				if len(us) == 1 {
-					u = us[0]
-
-					err = h.recorder.RecordUserSpend(u.Id, micros, u.CostLimitInUsdUnit)
+				if u.CreditBalanceInUsd != nil {
+					err = h.recorder.RecordUserDebit(e.Event.Id, u.Id, e.Event.CostInUsd)
 					if err != nil {
-						telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.record_user_spend_error", nil, 1)
-						h.log.Debug("error when recording user spend", zap.Error(err))
+						telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.record_user_debit_error", nil, 1)
+						h.log.Debug("error when recording user debit", zap.Error(err))
+						spendErr = err
 					}
 				}
 			}
+
+			for _, b := range budgets {
+				if len(b.CostLimitInUsdUnit) == 0 {
+					continue
+				}
+
+				err = h.recorder.RecordBudgetSpend(e.Event.Id, b.Id, micros, b.CostLimitInUsdUnit)
+				if err != nil {
+					telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.record_budget_spend_error", nil, 1)
+					h.log.Debug("error when recording budget spend", zap.Error(err))
+					spendErr = err
+				}
+			}
+		}
+
+		tks := int64(e.Event.PromptTokenCount + e.Event.CompletionTokenCount)
+		reservation := e.TokenReservation
+		if reservation == nil {
+			reservation = &event.TokenReservation{}
+		}
+
+		if keyTks := getUnreservedTokens(tks, reservation.KeyTokens, reservation.ReservedAt, e.Key.TokenLimitUnit); keyTks != 0 && len(e.Key.TokenLimitUnit) != 0 {
+			err := h.recorder.Once(e.Event.Id, "key_tokens", func() error {
+				return h.rlm.IncrementTokens(e.Key.KeyId, e.Key.TokenLimitUnit, keyTks)
+			})
+			if err != nil {
+				telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.token_limit_increment_error", nil, 1)
+
+				h.log.Debug("error when incrementing token limit", zap.Error(err))
+			}
+		}
+
+		if u != nil && len(u.TokenLimitUnit) != 0 {
+			if userTks := getUnreservedTokens(tks, reservation.UserTokens, reservation.ReservedAt, u.TokenLimitUnit); userTks != 0 {
+				err := h.recorder.Once(e.Event.Id, "user_tokens", func() error {
+					return h.rlm.IncrementUserTokens(u.Id, u.TokenLimitUnit, userTks)
+				})
+				if err != nil {
+					telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.token_limit_increment_user_error", nil, 1)
+
+					h.log.Debug("error when incrementing user token limit", zap.Error(err))
+				}
+			}
 		}
 
 		if len(e.Key.RateLimitUnit) != 0 {
-			if err := h.rlm.Increment(e.Key.KeyId, e.Key.RateLimitUnit); err != nil {
+			err := h.recorder.Once(e.Event.Id, "key_rate_limit", func() error {
+				return h.rlm.Increment(e.Key.KeyId, e.Key.RateLimitUnit, e.Key.RateLimitAlgorithm)
+			})
+			if err != nil {
 				telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.rate_limit_increment_error", nil, 1)
 
 				h.log.Debug("error when incrementing rate limit", zap.Error(err))
 			}
 		}
 
+		if modelLimit != nil && len(modelLimit.RateLimitUnit) != 0 {
+			err := h.recorder.Once(e.Event.Id, "key_model_rate_limit", func() error {
+				return h.rlm.Increment(key.GetModelLimitCounterId(e.Key.KeyId, limitedModel), modelLimit.RateLimitUnit, key.FixedWindowRateLimitAlgorithm)
+			})
+			if err != nil {
+				telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.rate_limit_increment_model_error", nil, 1)
+
+				h.log.Debug("error when incrementing model rate limit", zap.Error(err))
+			}
+		}
+
+		for _, b := range budgets {
+			if len(b.RateLimitUnit) == 0 {
+				continue
+			}
+
+			err := h.recorder.Once(e.Event.Id, "budget_rate_limit:"+b.Id, func() error {
+				return h.rlm.Increment(b.Id, b.RateLimitUnit, key.FixedWindowRateLimitAlgorithm)
+			})
+			if err != nil {
+				telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.rate_limit_increment_budget_error", nil, 1)
+
+				h.log.Debug("error when incrementing budget rate limit", zap.Error(err))
+			}
+		}
+
 		if u != nil {
 			if len(u.RateLimitUnit) != 0 {
-				if err := h.rlm.IncrementUser(u.Id, u.RateLimitUnit); err != nil {
+				err := h.recorder.Once(e.Event.Id, "user_rate_limit", func() error {
+					return h.rlm.IncrementUser(u.Id, u.RateLimitUnit)
+				})
+				if err != nil {
 					telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.rate_limit_increment_user_error", nil, 1)
 
 					h.log.Debug("error when incrementing rate limit", zap.Error(err))
@@ -397,6 +578,8 @@ func (h *Handler) HandleEventWithRequestAndResponse(m Message) error {
 
 	}
 
+	e.Event.PricedWithCostMap = e.CostMap.Prices(e.Event.Model)
+
 	start := time.Now()
 	err := h.recorder.RecordEvent(e.Event)
 	if err != nil {
@@ -405,6 +588,10 @@ func (h *Handler) HandleEventWithRequestAndResponse(m Message) error {
 		return err
 	}
 
+	if spendErr != nil {
+		return spendErr
+	}
+
 	telemetry.Timing("bricksllm.message.handler.handle_event_with_request_and_response.latency", time.Since(start), nil, 1)
 	telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.success", nil, 1)
 
@@ -421,6 +608,10 @@ func (h *Handler) decorateEvent(m Message) error {
 		return errors.New("message data cannot be parsed as event with request and response")
 	}
 
+	if e.Event.CacheHit {
+		return nil
+	}
+
 	if e.Event.Path == "/api/providers/openai/v1/images/generations" {
 		gir, ok := e.Request.(*goopenai.ImageRequest)
 		if !ok {
@@ -674,7 +865,8 @@ func (h *Handler) decorateEvent(m Message) error {
 			return errors.New("event request data cannot be parsed as openai completion request")
 		}
 
-		if ccr.Stream {
+		// streams that include usage are already priced by the proxy.
+		if ccr.Stream && e.Event.PromptTokenCount == 0 {
 			tks, cost, err := h.e.EstimateChatCompletionPromptCostWithTokenCounts(ccr)
 			if err != nil {
 				telemetry.Incr("bricksllm.message.handler.decorate_event.estimate_chat_completion_prompt_cost_with_token_counts", nil, 1)
@@ -818,6 +1010,20 @@ func (h *Handler) decorateEvent(m Message) error {
 		}
 	}
 
+	if strings.HasPrefix(e.Event.Path, "/api/providers/xCustom/") {
+		if e.Event.Status == http.StatusOK && e.CostMap != nil {
+			cost, err := provider.EstimateTotalCostWithCostMaps(e.Event.Model, e.Event.PromptTokenCount, e.Event.CompletionTokenCount, 1000, e.CostMap.PromptCostPerModel, e.CostMap.CompletionCostPerModel)
+			if err != nil {
+				telemetry.Incr("bricksllm.message.handler.decorate_event.estimate_x_custom_cost_error", nil, 1)
+				h.log.Debug("error when estimating x custom cost with cost maps", zap.Error(err))
+			}
+
+			if cost != 0 {
+				e.Event.CostInUsd = cost
+			}
+		}
+	}
+
 	if strings.HasPrefix(e.Event.Path, "/api/custom/providers") && e.RouteConfig != nil {
 		body, ok := e.Request.([]byte)
 		if !ok {
@@ -0,0 +1,345 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	streamReadCount    = 10
+	streamBlockTimeout = 2 * time.Second
+	streamClaimCount   = 100
+)
+
+type StreamConfig struct {
+	Stream           string
+	Group            string
+	DeadLetterStream string
+	MaxLen           int64
+	MaxDeliveries    int64
+	ClaimIdle        time.Duration
+}
+
+// StreamBus is a message bus backed by Redis Streams. Published messages are
+// appended to a stream and read through a consumer group, so they outlive the
+// process that published them. A message is acknowledged only after it has
+// been handled. Messages that stay pending for longer than ClaimIdle are
+// redelivered and moved to the dead letter stream after MaxDeliveries.
+type StreamBus struct {
+	client   *redis.Client
+	log      *zap.Logger
+	cfg      StreamConfig
+	consumer string
+	handlers map[string]func(Message) error
+	done     chan bool
+	wg       sync.WaitGroup
+	wt       time.Duration
+	rt       time.Duration
+}
+
+func NewStreamBus(c *redis.Client, log *zap.Logger, cfg StreamConfig, wt time.Duration, rt time.Duration) *StreamBus {
+	hostname, _ := os.Hostname()
+
+	return &StreamBus{
+		client:   c,
+		log:      log,
+		cfg:      cfg,
+		consumer: fmt.Sprintf("%s-%s", hostname, util.NewUuid()),
+		handlers: map[string]func(Message) error{},
+		done:     make(chan bool),
+		wt:       wt,
+		rt:       rt,
+	}
+}
+
+func (sb *StreamBus) Subscribe(messageType string, handle func(Message) error) {
+	sb.handlers[messageType] = handle
+}
+
+func (sb *StreamBus) Publish(m Message) {
+	telemetry.Incr("bricksllm.message.stream_bus.publish.requests", nil, 1)
+
+	data, err := encodeMessage(m)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), sb.wt)
+		defer cancel()
+
+		args := &redis.XAddArgs{
+			Stream: sb.cfg.Stream,
+			Values: map[string]interface{}{
+				"type": m.Type,
+				"data": data,
+			},
+		}
+
+		if sb.cfg.MaxLen > 0 {
+			args.MaxLen = sb.cfg.MaxLen
+			args.Approx = true
+		}
+
+		err = sb.client.XAdd(ctx, args).Err()
+		if err == nil {
+			telemetry.Incr("bricksllm.message.stream_bus.publish.success", nil, 1)
+			return
+		}
+	}
+
+	telemetry.Incr("bricksllm.message.stream_bus.publish.error", nil, 1)
+	sb.log.Error("error when publishing message to stream, handling it in process", zap.Error(err))
+
+	// the message is handled in process so that it is not lost when the
+	// stream is unavailable.
+	handle, ok := sb.handlers[m.Type]
+	if ok {
+		go handle(m)
+	}
+}
+
+func (sb *StreamBus) createGroup() error {
+	ctx, cancel := context.WithTimeout(context.Background(), sb.wt)
+	defer cancel()
+
+	err := sb.client.XGroupCreateMkStream(ctx, sb.cfg.Stream, sb.cfg.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	return nil
+}
+
+func (sb *StreamBus) Start(num int) error {
+	err := sb.createGroup()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < num; i++ {
+		sb.wg.Add(1)
+		go func() {
+			defer sb.wg.Done()
+			sb.read()
+		}()
+	}
+
+	sb.wg.Add(1)
+	go func() {
+		defer sb.wg.Done()
+		sb.reclaim()
+	}()
+
+	return nil
+}
+
+func (sb *StreamBus) isDone() bool {
+	select {
+	case <-sb.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (sb *StreamBus) read() {
+	for {
+		if sb.isDone() {
+			sb.log.Info("stream message consumer stoped...")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), streamBlockTimeout+sb.rt)
+		streams, err := sb.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    sb.cfg.Group,
+			Consumer: sb.consumer,
+			Streams:  []string{sb.cfg.Stream, ">"},
+			Count:    streamReadCount,
+			Block:    streamBlockTimeout,
+		}).Result()
+		cancel()
+
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+
+			telemetry.Incr("bricksllm.message.stream_bus.read.read_group_error", nil, 1)
+			sb.log.Debug("error when reading from stream", zap.Error(err))
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for _, s := range streams {
+			for _, xm := range s.Messages {
+				sb.process(xm)
+			}
+		}
+	}
+}
+
+// reclaim periodically claims messages that have not been acknowledged in
+// time, either because handling them failed or because the consumer that read
+// them is gone.
+func (sb *StreamBus) reclaim() {
+	interval := sb.cfg.ClaimIdle / 2
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sb.done:
+			sb.log.Info("stream message reclaimer stoped...")
+			return
+
+		case <-ticker.C:
+			err := sb.reclaimPending()
+			if err != nil {
+				telemetry.Incr("bricksllm.message.stream_bus.reclaim.error", nil, 1)
+				sb.log.Debug("error when reclaiming pending messages", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (sb *StreamBus) reclaimPending() error {
+	ctx, cancel := context.WithTimeout(context.Background(), sb.rt)
+	defer cancel()
+
+	pending, err := sb.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: sb.cfg.Stream,
+		Group:  sb.cfg.Group,
+		Idle:   sb.cfg.ClaimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  streamClaimCount,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pending {
+		claimCtx, claimCancel := context.WithTimeout(context.Background(), sb.wt)
+		claimed, err := sb.client.XClaim(claimCtx, &redis.XClaimArgs{
+			Stream:   sb.cfg.Stream,
+			Group:    sb.cfg.Group,
+			Consumer: sb.consumer,
+			MinIdle:  sb.cfg.ClaimIdle,
+			Messages: []string{p.ID},
+		}).Result()
+		claimCancel()
+
+		if err != nil {
+			return err
+		}
+
+		for _, xm := range claimed {
+			telemetry.Incr("bricksllm.message.stream_bus.reclaim.redelivered", nil, 1)
+
+			if sb.cfg.MaxDeliveries > 0 && p.RetryCount >= sb.cfg.MaxDeliveries {
+				sb.deadLetter(xm, p.RetryCount, "exceeded max deliveries")
+				continue
+			}
+
+			sb.process(xm)
+		}
+	}
+
+	return nil
+}
+
+func (sb *StreamBus) process(xm redis.XMessage) {
+	messageType, _ := xm.Values["type"].(string)
+	data, _ := xm.Values["data"].(string)
+
+	m, err := decodeMessage(messageType, []byte(data))
+	if err != nil {
+		telemetry.Incr("bricksllm.message.stream_bus.process.decode_error", nil, 1)
+		sb.deadLetter(xm, 0, err.Error())
+		return
+	}
+
+	handle, ok := sb.handlers[messageType]
+	if !ok {
+		telemetry.Incr("bricksllm.message.stream_bus.process.handler_not_found", nil, 1)
+		sb.deadLetter(xm, 0, "no handler for message type")
+		return
+	}
+
+	err = handle(m)
+	if err != nil {
+		// the message stays pending and is redelivered by the reclaimer.
+		telemetry.Incr("bricksllm.message.stream_bus.process.handle_error", nil, 1)
+		sb.log.Debug("error when handling stream message", zap.String("id", xm.ID), zap.Error(err))
+		return
+	}
+
+	err = sb.ack(xm.ID)
+	if err != nil {
+		telemetry.Incr("bricksllm.message.stream_bus.process.ack_error", nil, 1)
+		sb.log.Debug("error when acknowledging stream message", zap.String("id", xm.ID), zap.Error(err))
+		return
+	}
+
+	telemetry.Incr("bricksllm.message.stream_bus.process.success", nil, 1)
+}
+
+func (sb *StreamBus) ack(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sb.wt)
+	defer cancel()
+
+	return sb.client.XAck(ctx, sb.cfg.Stream, sb.cfg.Group, id).Err()
+}
+
+func (sb *StreamBus) deadLetter(xm redis.XMessage, deliveries int64, reason string) {
+	telemetry.Incr("bricksllm.message.stream_bus.dead_letter.requests", nil, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), sb.wt)
+	defer cancel()
+
+	values := map[string]interface{}{
+		"id":         xm.ID,
+		"deliveries": deliveries,
+		"reason":     reason,
+	}
+
+	for k, v := range xm.Values {
+		values[k] = v
+	}
+
+	err := sb.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: sb.cfg.DeadLetterStream,
+		Values: values,
+	}).Err()
+	if err != nil {
+		telemetry.Incr("bricksllm.message.stream_bus.dead_letter.error", nil, 1)
+		sb.log.Error("error when moving message to dead letter stream", zap.String("id", xm.ID), zap.Error(err))
+		return
+	}
+
+	sb.log.Info("message moved to dead letter stream", zap.String("id", xm.ID), zap.String("reason", reason))
+
+	err = sb.ack(xm.ID)
+	if err != nil {
+		telemetry.Incr("bricksllm.message.stream_bus.dead_letter.ack_error", nil, 1)
+		sb.log.Debug("error when acknowledging dead lettered message", zap.String("id", xm.ID), zap.Error(err))
+	}
+}
+
+func (sb *StreamBus) Stop() {
+	sb.log.Info("shutting down stream bus...")
+
+	close(sb.done)
+	sb.wg.Wait()
+}
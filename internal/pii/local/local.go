@@ -0,0 +1,230 @@
+package local
+
+import (
+	"math/big"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/pii"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+)
+
+type matcher struct {
+	entityType string
+	regex      *regexp.Regexp
+	validate   func(match string) bool
+	trim       func(match string) string
+}
+
+// matchers are evaluated in order. Matches overlapping an entity found by an
+// earlier matcher are dropped, so more specific patterns come first.
+var matchers = []*matcher{
+	{
+		entityType: "AWS_ACCESS_KEY",
+		regex:      regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|AIPA|ANPA|ANVA|ABIA|ACCA)[A-Z0-9]{16}\b`),
+	},
+	{
+		entityType: "URL",
+		regex:      regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`),
+		trim:       trimTrailingPunctuation,
+	},
+	{
+		entityType: "EMAIL",
+		regex:      regexp.MustCompile(`\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}\b`),
+	},
+	{
+		entityType: "INTERNATIONAL_BANK_ACCOUNT_NUMBER",
+		regex:      regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+		validate:   isValidIban,
+	},
+	{
+		entityType: "CREDIT_DEBIT_NUMBER",
+		regex:      regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+		validate:   isValidCardNumber,
+	},
+	{
+		entityType: "SSN",
+		regex:      regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		validate:   isValidSsn,
+	},
+	{
+		entityType: "MAC_ADDRESS",
+		regex:      regexp.MustCompile(`\b[0-9A-Fa-f]{2}(?:[:\-][0-9A-Fa-f]{2}){5}\b`),
+	},
+	{
+		entityType: "IP_ADDRESS",
+		regex:      regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|\b(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}\b`),
+		validate:   isValidIp,
+	},
+	{
+		entityType: "PHONE",
+		regex:      regexp.MustCompile(`\+\d{1,3}[\s.\-]?\(?\d{1,4}\)?(?:[\s.\-]?\d{2,4}){2,3}\b|(?:\(\d{3}\)|\b\d{3})[\s.\-]?\d{3}[\s.\-]?\d{4}\b`),
+		validate:   isValidPhone,
+	},
+}
+
+// Detector finds regex detectable PII entities without calling an external
+// service. Entity types and byte offsets follow the format of the Amazon
+// Comprehend detector so that both can back the same policies.
+type Detector struct{}
+
+func NewDetector() *Detector {
+	return &Detector{}
+}
+
+func (d *Detector) Detect(input []string) (*pii.Result, error) {
+	start := time.Now()
+
+	result := &pii.Result{
+		Detections: make([]*pii.Detection, len(input)),
+	}
+
+	for i, text := range input {
+		result.Detections[i] = &pii.Detection{
+			Input:    text,
+			Entities: detect(text),
+		}
+	}
+
+	telemetry.Timing("bricksllm.local.detect.latency_in_ms", time.Since(start), nil, 1)
+
+	return result, nil
+}
+
+func detect(text string) []*pii.Entity {
+	entities := []*pii.Entity{}
+
+	for _, m := range matchers {
+		for _, loc := range m.regex.FindAllStringIndex(text, -1) {
+			begin, end := loc[0], loc[1]
+			match := text[begin:end]
+
+			if m.trim != nil {
+				match = m.trim(match)
+				end = begin + len(match)
+			}
+
+			if m.validate != nil && !m.validate(match) {
+				continue
+			}
+
+			if overlaps(entities, begin, end) {
+				continue
+			}
+
+			entities = append(entities, &pii.Entity{
+				BeginOffset: begin,
+				EndOffset:   end,
+				Type:        m.entityType,
+			})
+		}
+	}
+
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].BeginOffset < entities[j].BeginOffset
+	})
+
+	return entities
+}
+
+func overlaps(entities []*pii.Entity, begin, end int) bool {
+	for _, e := range entities {
+		if begin < e.EndOffset && e.BeginOffset < end {
+			return true
+		}
+	}
+
+	return false
+}
+
+func trimTrailingPunctuation(match string) string {
+	return strings.TrimRight(match, ".,;:!?)]}")
+}
+
+func digitsOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+
+		return -1
+	}, s)
+}
+
+func isValidCardNumber(match string) bool {
+	digits := digitsOnly(match)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		n := int(digits[i] - '0')
+		if double {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+
+		sum += n
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+func isValidSsn(match string) bool {
+	parts := strings.Split(match, "-")
+	if len(parts) != 3 {
+		return false
+	}
+
+	area, group, serial := parts[0], parts[1], parts[2]
+	if area == "000" || area == "666" || area[0] == '9' {
+		return false
+	}
+
+	return group != "00" && serial != "0000"
+}
+
+func isValidIban(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	rearranged := iban[4:] + iban[:4]
+
+	var sb strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			sb.WriteString(big.NewInt(int64(r - 'A' + 10)).String())
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(sb.String(), 10)
+	if !ok {
+		return false
+	}
+
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+func isValidIp(match string) bool {
+	return net.ParseIP(match) != nil
+}
+
+func isValidPhone(match string) bool {
+	digits := digitsOnly(match)
+	return len(digits) >= 10 && len(digits) <= 15
+}
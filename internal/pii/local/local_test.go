@@ -0,0 +1,130 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/pii"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidCardNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		match    string
+		expected bool
+	}{
+		{name: "visa", match: "4111111111111111", expected: true},
+		{name: "visa with spaces", match: "4111 1111 1111 1111", expected: true},
+		{name: "mastercard with dashes", match: "5500-0000-0000-0004", expected: true},
+		{name: "amex", match: "378282246310005", expected: true},
+		{name: "failed checksum", match: "4111111111111112", expected: false},
+		{name: "too short", match: "411111111111", expected: false},
+		{name: "too long", match: "41111111111111111111", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isValidCardNumber(tt.match))
+		})
+	}
+}
+
+func TestIsValidSsn(t *testing.T) {
+	tests := []struct {
+		name     string
+		match    string
+		expected bool
+	}{
+		{name: "valid", match: "123-45-6789", expected: true},
+		{name: "area 000", match: "000-45-6789", expected: false},
+		{name: "area 666", match: "666-45-6789", expected: false},
+		{name: "area 9xx", match: "900-45-6789", expected: false},
+		{name: "group 00", match: "123-00-6789", expected: false},
+		{name: "serial 0000", match: "123-45-0000", expected: false},
+		{name: "not dashed", match: "123456789", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isValidSsn(tt.match))
+		})
+	}
+}
+
+func TestIsValidIban(t *testing.T) {
+	tests := []struct {
+		name     string
+		match    string
+		expected bool
+	}{
+		{name: "germany", match: "DE89370400440532013000", expected: true},
+		{name: "united kingdom with spaces", match: "GB29 NWBK 6016 1331 9268 19", expected: true},
+		{name: "norway", match: "NO9386011117947", expected: true},
+		{name: "failed checksum", match: "DE89370400440532013001", expected: false},
+		{name: "too short", match: "DE8937040044", expected: false},
+		{name: "lowercase", match: "de89370400440532013000", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isValidIban(tt.match))
+		})
+	}
+}
+
+func TestDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []*pii.Entity
+	}{
+		{
+			name:     "nothing",
+			input:    "the weather is nice today",
+			expected: []*pii.Entity{},
+		},
+		{
+			name:  "email and card",
+			input: "mail a@b.com card 4111 1111 1111 1111",
+			expected: []*pii.Entity{
+				{BeginOffset: 5, EndOffset: 12, Type: "EMAIL"},
+				{BeginOffset: 18, EndOffset: 37, Type: "CREDIT_DEBIT_NUMBER"},
+			},
+		},
+		{
+			name:     "card that fails the checksum",
+			input:    "order 4111 1111 1111 1112",
+			expected: []*pii.Entity{},
+		},
+		{
+			name:  "ssn",
+			input: "ssn 123-45-6789.",
+			expected: []*pii.Entity{
+				{BeginOffset: 4, EndOffset: 15, Type: "SSN"},
+			},
+		},
+		{
+			name:  "iban",
+			input: "iban DE89370400440532013000",
+			expected: []*pii.Entity{
+				{BeginOffset: 5, EndOffset: 27, Type: "INTERNATIONAL_BANK_ACCOUNT_NUMBER"},
+			},
+		},
+		{
+			name:  "url trailing punctuation is trimmed",
+			input: "see https://example.com/a.",
+			expected: []*pii.Entity{
+				{BeginOffset: 4, EndOffset: 25, Type: "URL"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewDetector().Detect([]string{tt.input})
+			assert.NoError(t, err)
+			assert.Len(t, result.Detections, 1)
+			assert.Equal(t, tt.expected, result.Detections[0].Entities)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package policy
+
+import (
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+)
+
+// textField is a piece of text inside a request along with a function that
+// replaces it.
+type textField struct {
+	text   string
+	update func(string)
+}
+
+// collectStringFields collects the string values nested in a decoded JSON
+// value such as tool inputs.
+func collectStringFields(value interface{}, update func(string)) []textField {
+	fields := []textField{}
+
+	switch converted := value.(type) {
+	case string:
+		fields = append(fields, textField{text: converted, update: update})
+	case map[string]interface{}:
+		for k, v := range converted {
+			k := k
+			fields = append(fields, collectStringFields(v, func(s string) {
+				converted[k] = s
+			})...)
+		}
+	case []interface{}:
+		for i, v := range converted {
+			i := i
+			fields = append(fields, collectStringFields(v, func(s string) {
+				converted[i] = s
+			})...)
+		}
+	}
+
+	return fields
+}
+
+// collectContentFields collects the text of an Anthropic content, which is
+// either a string or a list of blocks. Text blocks, tool_use inputs and
+// tool_result contents are included. Images and other blocks are skipped.
+func collectContentFields(content interface{}, update func(string)) []textField {
+	fields := []textField{}
+
+	if text, ok := content.(string); ok {
+		return append(fields, textField{text: text, update: update})
+	}
+
+	blocks, ok := content.([]interface{})
+	if !ok {
+		return fields
+	}
+
+	for _, b := range blocks {
+		block, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch block["type"] {
+		case "text":
+			if text, ok := block["text"].(string); ok {
+				fields = append(fields, textField{text: text, update: func(s string) {
+					block["text"] = s
+				}})
+			}
+		case "tool_use":
+			fields = append(fields, collectStringFields(block["input"], func(s string) {
+				block["input"] = s
+			})...)
+		case "tool_result":
+			fields = append(fields, collectContentFields(block["content"], func(s string) {
+				block["content"] = s
+			})...)
+		}
+	}
+
+	return fields
+}
+
+func collectMessagesRequestFields(mr *anthropic.MessagesRequest) []textField {
+	fields := collectContentFields(mr.System, func(s string) {
+		mr.System = s
+	})
+
+	for i := range mr.Messages {
+		message := &mr.Messages[i]
+		fields = append(fields, collectContentFields(message.Content, func(s string) {
+			message.Content = s
+		})...)
+	}
+
+	return fields
+}
@@ -0,0 +1,78 @@
+package policy
+
+import (
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// JsonRequest is a request body without a known schema. The text contents to
+// inspect are located with gjson paths. It marshals to the possibly redacted
+// body.
+type JsonRequest struct {
+	Body      []byte
+	Locations []string
+}
+
+func (r *JsonRequest) MarshalJSON() ([]byte, error) {
+	return r.Body, nil
+}
+
+// contents returns the concrete paths of the string values matched by the
+// locations together with the values.
+func (r *JsonRequest) contents() ([]string, []string) {
+	paths := []string{}
+	contents := []string{}
+
+	json := string(r.Body)
+	for _, loc := range r.Locations {
+		result := gjson.Get(json, loc)
+		if !result.Exists() {
+			continue
+		}
+
+		if result.Type == gjson.String {
+			path := result.Path(json)
+			if len(path) == 0 {
+				path = loc
+			}
+
+			paths = append(paths, path)
+			contents = append(contents, result.Str)
+			continue
+		}
+
+		elems := result.Array()
+		elemPaths := result.Paths(json)
+		if len(elemPaths) != len(elems) {
+			continue
+		}
+
+		for i, elem := range elems {
+			if elem.Type == gjson.String {
+				paths = append(paths, elemPaths[i])
+				contents = append(contents, elem.Str)
+			}
+		}
+	}
+
+	return paths, contents
+}
+
+func (r *JsonRequest) update(paths, contents []string) error {
+	body := r.Body
+	for i, path := range paths {
+		if gjson.GetBytes(body, path).Str == contents[i] {
+			continue
+		}
+
+		updated, err := sjson.SetBytes(body, path, contents[i])
+		if err != nil {
+			return err
+		}
+
+		body = updated
+	}
+
+	r.Body = body
+	return nil
+}
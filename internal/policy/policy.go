@@ -11,6 +11,7 @@ import (
 	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
 	"github.com/bricks-cloud/bricksllm/internal/pii"
 	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
 	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
 	"github.com/bricks-cloud/bricksllm/internal/provider/vllm"
 	"github.com/bricks-cloud/bricksllm/internal/telemetry"
@@ -93,23 +94,25 @@ type CustomConfig struct {
 }
 
 type Policy struct {
-	Id           string        `json:"id"`
-	Name         string        `json:"name"`
-	CreatedAt    int64         `json:"createdAt"`
-	UpdatedAt    int64         `json:"updatedAt"`
-	Tags         []string      `json:"tags"`
-	Config       *Config       `json:"config"`
-	RegexConfig  *RegexConfig  `json:"regexConfig"`
-	CustomConfig *CustomConfig `json:"customConfig"`
+	Id               string        `json:"id"`
+	Name             string        `json:"name"`
+	CreatedAt        int64         `json:"createdAt"`
+	UpdatedAt        int64         `json:"updatedAt"`
+	Tags             []string      `json:"tags"`
+	Config           *Config       `json:"config"`
+	RegexConfig      *RegexConfig  `json:"regexConfig"`
+	CustomConfig     *CustomConfig `json:"customConfig"`
+	ApplyToResponses bool          `json:"applyToResponses"`
 }
 
 type UpdatePolicy struct {
-	Name         string        `json:"name"`
-	UpdatedAt    int64         `json:"updatedAt"`
-	Tags         []string      `json:"tags"`
-	Config       *Config       `json:"config"`
-	RegexConfig  *RegexConfig  `json:"regexConfig"`
-	CustomConfig *CustomConfig `json:"customConfig"`
+	Name             string        `json:"name"`
+	UpdatedAt        int64         `json:"updatedAt"`
+	Tags             []string      `json:"tags"`
+	Config           *Config       `json:"config"`
+	RegexConfig      *RegexConfig  `json:"regexConfig"`
+	CustomConfig     *CustomConfig `json:"customConfig"`
+	ApplyToResponses *bool         `json:"applyToResponses"`
 }
 
 func extractTextContents(input any) []string {
@@ -202,11 +205,7 @@ func (p *Policy) Validate() error {
 	return nil
 }
 
-func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd CustomPolicyDetector, log *zap.Logger) error {
-	if p == nil || scanner == nil || input == nil {
-		return nil
-	}
-
+func (p *Policy) shouldInspect() bool {
 	shouldInspect := false
 	if p.Config != nil {
 		for _, action := range p.Config.Rules {
@@ -232,7 +231,47 @@ func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd Custo
 		}
 	}
 
-	if !shouldInspect {
+	return shouldInspect
+}
+
+// FilterResponseContents evaluates the policy against text contents extracted
+// from a model response. It returns the contents with redactions applied along
+// with the same blocked, warning and redact errors as Filter.
+func (p *Policy) FilterResponseContents(contents []string, scanner Scanner, cd CustomPolicyDetector, log *zap.Logger) ([]string, error) {
+	if p == nil || !p.ApplyToResponses || scanner == nil || len(contents) == 0 || !p.shouldInspect() {
+		return contents, nil
+	}
+
+	result, err := p.scan(contents, scanner, cd, log)
+	if err != nil {
+		return contents, err
+	}
+
+	if result.Action == Block {
+		return contents, internal_errors.NewBlockedError("response blocked due to detected entities: " + join(result.BlockedEntities, result.BlockedRegexDefinitions, result.BlockedCustomDefinitions))
+	}
+
+	if result.Action == AllowButWarn {
+		return contents, internal_errors.NewWarningError("response warned due to detected entities: " + join(result.WarnedEntities, result.WarnedRegexDefinitions, []string{}))
+	}
+
+	if len(result.Updated) != len(contents) {
+		return contents, errors.New("updated contents length not consistent with existing content length")
+	}
+
+	if result.Action == AllowButRedact {
+		return result.Updated, internal_errors.NewRedactError("response redacted due to detected entities")
+	}
+
+	return contents, nil
+}
+
+func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd CustomPolicyDetector, log *zap.Logger) error {
+	if p == nil || scanner == nil || input == nil {
+		return nil
+	}
+
+	if !p.shouldInspect() {
 		return nil
 	}
 
@@ -434,11 +473,15 @@ func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd Custo
 
 	case *anthropic.MessagesRequest:
 		converted := input.(*anthropic.MessagesRequest)
-		newMessages := []anthropic.Message{}
+
+		fields := collectMessagesRequestFields(converted)
+		if len(fields) == 0 {
+			return nil
+		}
 
 		contents := []string{}
-		for _, message := range converted.Messages {
-			contents = append(contents, message.Content)
+		for _, field := range fields {
+			contents = append(contents, field.text)
 		}
 
 		result, err := p.scan(contents, scanner, cd, log)
@@ -454,19 +497,16 @@ func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd Custo
 			return internal_errors.NewWarningError("request warned due to detected entities: " + join(result.WarnedEntities, result.WarnedRegexDefinitions, []string{}))
 		}
 
-		if len(result.Updated) != len(converted.Messages) {
+		if len(result.Updated) != len(fields) {
 			return errors.New("updated contents length not consistent with existing content length")
 		}
 
 		for index, c := range result.Updated {
-			newMessages = append(newMessages, anthropic.Message{
-				Content: c,
-				Role:    converted.Messages[index].Role,
-			})
+			if c != fields[index].text {
+				fields[index].update(c)
+			}
 		}
 
-		converted.Messages = newMessages
-
 		if result.Action == AllowButRedact {
 			return internal_errors.NewRedactError("request redacted due to detected entities")
 		}
@@ -496,6 +536,61 @@ func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd Custo
 			return internal_errors.NewRedactError("request redacted due to detected entities")
 		}
 
+		return nil
+	case *gemini.GenerateContentRequest:
+		converted := input.(*gemini.GenerateContentRequest)
+
+		parts := []*gemini.Part{}
+		if converted.SystemInstruction != nil {
+			for index := range converted.SystemInstruction.Parts {
+				parts = append(parts, &converted.SystemInstruction.Parts[index])
+			}
+		}
+
+		for i := range converted.Contents {
+			for j := range converted.Contents[i].Parts {
+				parts = append(parts, &converted.Contents[i].Parts[j])
+			}
+		}
+
+		contents := []string{}
+		textParts := []*gemini.Part{}
+		for _, part := range parts {
+			if len(part.Text) != 0 {
+				contents = append(contents, part.Text)
+				textParts = append(textParts, part)
+			}
+		}
+
+		if len(contents) == 0 {
+			return nil
+		}
+
+		result, err := p.scan(contents, scanner, cd, log)
+		if err != nil {
+			return err
+		}
+
+		if result.Action == Block {
+			return internal_errors.NewBlockedError("request blocked due to detected entities: " + join(result.BlockedEntities, result.BlockedRegexDefinitions, result.BlockedCustomDefinitions))
+		}
+
+		if result.Action == AllowButWarn {
+			return internal_errors.NewWarningError("request warned due to detected entities: " + join(result.WarnedEntities, result.WarnedRegexDefinitions, []string{}))
+		}
+
+		if len(result.Updated) != len(textParts) {
+			return errors.New("updated contents length not consistent with existing content length")
+		}
+
+		for index, c := range result.Updated {
+			textParts[index].Text = c
+		}
+
+		if result.Action == AllowButRedact {
+			return internal_errors.NewRedactError("request redacted due to detected entities")
+		}
+
 		return nil
 	case *goopenai.AssistantRequest:
 		converted := input.(*goopenai.AssistantRequest)
@@ -812,6 +907,40 @@ func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd Custo
 			return internal_errors.NewRedactError("request redacted due to detected entities")
 		}
 
+		return nil
+	case *JsonRequest:
+		converted := input.(*JsonRequest)
+		paths, contents := converted.contents()
+		if len(contents) == 0 {
+			return nil
+		}
+
+		result, err := p.scan(contents, scanner, cd, log)
+		if err != nil {
+			return err
+		}
+
+		if result.Action == Block {
+			return internal_errors.NewBlockedError("request blocked due to detected entities: " + join(result.BlockedEntities, result.BlockedRegexDefinitions, result.BlockedCustomDefinitions))
+		}
+
+		if result.Action == AllowButWarn {
+			return internal_errors.NewWarningError("request warned due to detected entities: " + join(result.WarnedEntities, result.WarnedRegexDefinitions, []string{}))
+		}
+
+		if len(result.Updated) != len(contents) {
+			return errors.New("updated contents length not consistent with existing content length")
+		}
+
+		err = converted.update(paths, result.Updated)
+		if err != nil {
+			return err
+		}
+
+		if result.Action == AllowButRedact {
+			return internal_errors.NewRedactError("request redacted due to detected entities")
+		}
+
 		return nil
 	}
 
@@ -0,0 +1,168 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+)
+
+// Providers whose built-in cost maps can be overridden or extended by the
+// pricing catalog. Bedrock requests are priced with the anthropic prices.
+var SupportedProviders = []string{
+	"openai",
+	"anthropic",
+	"azure",
+	"deepinfra",
+	"gemini",
+}
+
+// Price is a catalog entry for a single model and cost category, such as
+// "prompt" or "completion". Cost uses the same unit as the built-in cost map
+// of the provider, which is per thousand tokens for openai and azure and per
+// million tokens for anthropic, deepinfra and gemini. A price applies to
+// requests made at or after EffectiveFrom until a newer price for the same
+// model and category takes effect.
+type Price struct {
+	Id            string  `json:"id"`
+	CreatedAt     int64   `json:"createdAt"`
+	UpdatedAt     int64   `json:"updatedAt"`
+	Provider      string  `json:"provider"`
+	Model         string  `json:"model"`
+	Category      string  `json:"category"`
+	Cost          float64 `json:"cost"`
+	EffectiveFrom int64   `json:"effectiveFrom"`
+	Archived      bool    `json:"archived"`
+}
+
+type UpdatePrice struct {
+	UpdatedAt     int64    `json:"updatedAt"`
+	Cost          *float64 `json:"cost"`
+	EffectiveFrom *int64   `json:"effectiveFrom"`
+}
+
+func isProviderSupported(provider string) bool {
+	for _, p := range SupportedProviders {
+		if p == provider {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Price) Validate() error {
+	if !isProviderSupported(p.Provider) {
+		return internal_errors.NewValidationError(fmt.Sprintf("provider must be one of [%s]", strings.Join(SupportedProviders, ", ")))
+	}
+
+	invalid := []string{}
+
+	if len(p.Model) == 0 {
+		invalid = append(invalid, "model")
+	}
+
+	if len(p.Category) == 0 {
+		invalid = append(invalid, "category")
+	}
+
+	if p.Cost < 0 || math.IsNaN(p.Cost) || math.IsInf(p.Cost, 0) {
+		invalid = append(invalid, "cost")
+	}
+
+	if p.EffectiveFrom < 0 {
+		invalid = append(invalid, "effectiveFrom")
+	}
+
+	if len(invalid) != 0 {
+		return internal_errors.NewValidationError(fmt.Sprintf("fields [%s] are invalid", strings.Join(invalid, ", ")))
+	}
+
+	return nil
+}
+
+func (p *UpdatePrice) Validate() error {
+	invalid := []string{}
+
+	if p.Cost != nil && (*p.Cost < 0 || math.IsNaN(*p.Cost) || math.IsInf(*p.Cost, 0)) {
+		invalid = append(invalid, "cost")
+	}
+
+	if p.EffectiveFrom != nil && *p.EffectiveFrom < 0 {
+		invalid = append(invalid, "effectiveFrom")
+	}
+
+	if len(invalid) != 0 {
+		return internal_errors.NewValidationError(fmt.Sprintf("fields [%s] are invalid", strings.Join(invalid, ", ")))
+	}
+
+	return nil
+}
+
+// Effective returns the prices that are in effect at the given unix time,
+// keyed by category and model. When several prices exist for the same model
+// and category the one with the latest EffectiveFrom wins.
+func Effective(prices []*Price, at int64) map[string]map[string]*Price {
+	effective := map[string]map[string]*Price{}
+
+	for _, p := range prices {
+		if p.Archived || p.EffectiveFrom > at {
+			continue
+		}
+
+		models, ok := effective[p.Category]
+		if !ok {
+			models = map[string]*Price{}
+			effective[p.Category] = models
+		}
+
+		existing, ok := models[p.Model]
+		if !ok || p.EffectiveFrom > existing.EffectiveFrom || (p.EffectiveFrom == existing.EffectiveFrom && p.UpdatedAt > existing.UpdatedAt) {
+			models[p.Model] = p
+		}
+	}
+
+	return effective
+}
+
+// Apply returns a copy of the built-in cost map with the prices in effect at
+// the given unix time applied on top of it. The built-in map is not modified.
+func Apply(base map[string]map[string]float64, prices []*Price, at int64) map[string]map[string]float64 {
+	merged := make(map[string]map[string]float64, len(base))
+	for category, models := range base {
+		merged[category] = models
+	}
+
+	for category, models := range Effective(prices, at) {
+		copied := map[string]float64{}
+		for model, cost := range merged[category] {
+			copied[model] = cost
+		}
+
+		for model, p := range models {
+			copied[model] = p.Cost
+		}
+
+		merged[category] = copied
+	}
+
+	return merged
+}
+
+// NextChange returns the earliest EffectiveFrom after the given unix time, or
+// math.MaxInt64 if no price takes effect later.
+func NextChange(prices []*Price, at int64) int64 {
+	var next int64 = math.MaxInt64
+	for _, p := range prices {
+		if p.Archived {
+			continue
+		}
+
+		if p.EffectiveFrom > at && p.EffectiveFrom < next {
+			next = p.EffectiveFrom
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,161 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffective(t *testing.T) {
+	prices := []*Price{
+		{Id: "old", Model: "gpt-4o", Category: "prompt", Cost: 0.005, EffectiveFrom: 100},
+		{Id: "new", Model: "gpt-4o", Category: "prompt", Cost: 0.0025, EffectiveFrom: 200},
+		{Id: "future", Model: "gpt-4o", Category: "prompt", Cost: 0.001, EffectiveFrom: 300},
+		{Id: "archived", Model: "gpt-4o", Category: "prompt", Cost: 0.1, EffectiveFrom: 250, Archived: true},
+		{Id: "completion", Model: "gpt-4o", Category: "completion", Cost: 0.01, EffectiveFrom: 100},
+		{Id: "stale", Model: "gpt-4o-mini", Category: "prompt", Cost: 0.0002, EffectiveFrom: 100, UpdatedAt: 1},
+		{Id: "updated", Model: "gpt-4o-mini", Category: "prompt", Cost: 0.00015, EffectiveFrom: 100, UpdatedAt: 2},
+	}
+
+	tests := []struct {
+		name     string
+		at       int64
+		expected map[string]map[string]string
+	}{
+		{
+			name:     "before any price",
+			at:       99,
+			expected: map[string]map[string]string{},
+		},
+		{
+			name: "first prices",
+			at:   100,
+			expected: map[string]map[string]string{
+				"prompt":     {"gpt-4o": "old", "gpt-4o-mini": "updated"},
+				"completion": {"gpt-4o": "completion"},
+			},
+		},
+		{
+			name: "latest price wins and archived prices are ignored",
+			at:   260,
+			expected: map[string]map[string]string{
+				"prompt":     {"gpt-4o": "new", "gpt-4o-mini": "updated"},
+				"completion": {"gpt-4o": "completion"},
+			},
+		},
+		{
+			name: "future price takes effect",
+			at:   300,
+			expected: map[string]map[string]string{
+				"prompt":     {"gpt-4o": "future", "gpt-4o-mini": "updated"},
+				"completion": {"gpt-4o": "completion"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := map[string]map[string]string{}
+			for category, models := range Effective(prices, tt.at) {
+				ids[category] = map[string]string{}
+				for model, p := range models {
+					ids[category][model] = p.Id
+				}
+			}
+
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	base := map[string]map[string]float64{
+		"prompt": {
+			"gpt-4o":      0.005,
+			"gpt-4o-mini": 0.00015,
+		},
+		"completion": {
+			"gpt-4o": 0.015,
+		},
+	}
+
+	tests := []struct {
+		name     string
+		prices   []*Price
+		at       int64
+		expected map[string]map[string]float64
+	}{
+		{
+			name:     "no prices",
+			prices:   []*Price{},
+			at:       100,
+			expected: base,
+		},
+		{
+			name: "override and add models",
+			prices: []*Price{
+				{Model: "gpt-4o", Category: "prompt", Cost: 0.0025, EffectiveFrom: 100},
+				{Model: "new-model", Category: "completion", Cost: 0.02, EffectiveFrom: 100},
+			},
+			at: 100,
+			expected: map[string]map[string]float64{
+				"prompt":     {"gpt-4o": 0.0025, "gpt-4o-mini": 0.00015},
+				"completion": {"gpt-4o": 0.015, "new-model": 0.02},
+			},
+		},
+		{
+			name: "new category",
+			prices: []*Price{
+				{Model: "text-embedding-3-small", Category: "embeddings", Cost: 0.00002, EffectiveFrom: 100},
+			},
+			at: 100,
+			expected: map[string]map[string]float64{
+				"prompt":     {"gpt-4o": 0.005, "gpt-4o-mini": 0.00015},
+				"completion": {"gpt-4o": 0.015},
+				"embeddings": {"text-embedding-3-small": 0.00002},
+			},
+		},
+		{
+			name: "prices not yet in effect",
+			prices: []*Price{
+				{Model: "gpt-4o", Category: "prompt", Cost: 0.0025, EffectiveFrom: 200},
+			},
+			at:       100,
+			expected: base,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Apply(base, tt.prices, tt.at))
+		})
+	}
+
+	assert.Equal(t, 0.005, base["prompt"]["gpt-4o"], "the built-in cost map must not be modified")
+	assert.NotContains(t, base, "embeddings")
+}
+
+func TestNextChange(t *testing.T) {
+	prices := []*Price{
+		{EffectiveFrom: 100},
+		{EffectiveFrom: 200},
+		{EffectiveFrom: 150, Archived: true},
+	}
+
+	tests := []struct {
+		name     string
+		at       int64
+		expected int64
+	}{
+		{name: "before all", at: 0, expected: 100},
+		{name: "between", at: 100, expected: 200},
+		{name: "after all", at: 200, expected: math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NextChange(prices, tt.at))
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+)
+
+const (
+	RepricingStatusPending   = "pending"
+	RepricingStatusRunning   = "running"
+	RepricingStatusCompleted = "completed"
+	RepricingStatusFailed    = "failed"
+)
+
+// Providers whose events can be repriced from their stored token counts.
+var RepricingProviders = append([]string{"bedrock"}, SupportedProviders...)
+
+// RepricingRequest selects the events to reprice. Prices is an optional price
+// table keyed by category and model that is applied on top of the built-in
+// cost map of the provider. Without it, every event is priced with the
+// catalog prices that were in effect when the event was created.
+type RepricingRequest struct {
+	Start    int64                         `json:"start"`
+	End      int64                         `json:"end"`
+	Provider string                        `json:"provider"`
+	Model    string                        `json:"model"`
+	Prices   map[string]map[string]float64 `json:"prices"`
+	DryRun   bool                          `json:"dryRun"`
+}
+
+func (r *RepricingRequest) Validate() error {
+	invalid := []string{}
+
+	if r.Start == 0 {
+		invalid = append(invalid, "start")
+	}
+
+	if r.End == 0 {
+		invalid = append(invalid, "end")
+	}
+
+	for _, models := range r.Prices {
+		for _, cost := range models {
+			if cost < 0 || math.IsNaN(cost) || math.IsInf(cost, 0) {
+				invalid = append(invalid, "prices")
+				break
+			}
+		}
+	}
+
+	if len(invalid) != 0 {
+		return internal_errors.NewValidationError(fmt.Sprintf("fields [%s] are invalid", strings.Join(invalid, ", ")))
+	}
+
+	if r.Start >= r.End {
+		return internal_errors.NewValidationError(fmt.Sprintf("start %d cannot be larger than end %d", r.Start, r.End))
+	}
+
+	if len(r.Provider) != 0 {
+		supported := false
+		for _, p := range RepricingProviders {
+			if p == r.Provider {
+				supported = true
+			}
+		}
+
+		if !supported {
+			return internal_errors.NewValidationError(fmt.Sprintf("provider must be one of [%s]", strings.Join(RepricingProviders, ", ")))
+		}
+	}
+
+	if len(r.Prices) != 0 && len(r.Provider) == 0 {
+		return internal_errors.NewValidationError("provider is required when prices are provided")
+	}
+
+	return nil
+}
+
+type ModelRepricingSummary struct {
+	Provider          string  `json:"provider"`
+	Model             string  `json:"model"`
+	NumberOfEvents    int     `json:"numberOfEvents"`
+	PreviousCostInUsd float64 `json:"previousCostInUsd"`
+	CostInUsd         float64 `json:"costInUsd"`
+	DifferenceInUsd   float64 `json:"differenceInUsd"`
+}
+
+type RepricingSummary struct {
+	PreviousCostInUsd float64                           `json:"previousCostInUsd"`
+	CostInUsd         float64                           `json:"costInUsd"`
+	DifferenceInUsd   float64                           `json:"differenceInUsd"`
+	Models            map[string]*ModelRepricingSummary `json:"models"`
+}
+
+// Add records the previous and the new cost of a repriced event.
+func (s *RepricingSummary) Add(provider, model string, previous, cost float64) {
+	s.PreviousCostInUsd += previous
+	s.CostInUsd += cost
+	s.DifferenceInUsd = s.CostInUsd - s.PreviousCostInUsd
+
+	k := provider + "/" + model
+	ms, ok := s.Models[k]
+	if !ok {
+		ms = &ModelRepricingSummary{
+			Provider: provider,
+			Model:    model,
+		}
+
+		s.Models[k] = ms
+	}
+
+	ms.NumberOfEvents++
+	ms.PreviousCostInUsd += previous
+	ms.CostInUsd += cost
+	ms.DifferenceInUsd = ms.CostInUsd - ms.PreviousCostInUsd
+}
+
+// RepricingJob tracks the progress of an asynchronous repricing run. Events
+// that cannot be priced from token counts, such as image or audio requests,
+// are counted as skipped.
+type RepricingJob struct {
+	Id        string            `json:"id"`
+	CreatedAt int64             `json:"createdAt"`
+	UpdatedAt int64             `json:"updatedAt"`
+	Status    string            `json:"status"`
+	Request   *RepricingRequest `json:"request"`
+	Total     int               `json:"total"`
+	Processed int               `json:"processed"`
+	Repriced  int               `json:"repriced"`
+	Skipped   int               `json:"skipped"`
+	Error     string            `json:"error"`
+	Summary   *RepricingSummary `json:"summary"`
+}
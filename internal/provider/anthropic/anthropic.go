@@ -1,5 +1,7 @@
 package anthropic
 
+import "encoding/json"
+
 type Metadata struct {
 	UserId string `json:"user_id"`
 }
@@ -16,21 +18,25 @@ type CompletionRequest struct {
 	Stream            bool      `json:"stream,omitempty"`
 }
 
+// Message content is either a string or a list of content blocks.
 type Message struct {
-	Content string `json:"content"`
-	Role    string `json:"role"`
+	Content interface{} `json:"content"`
+	Role    string      `json:"role"`
 }
 
 type MessagesRequest struct {
-	Model         string    `json:"model"`
-	Messages      []Message `json:"messages"`
-	MaxTokens     int       `json:"max_tokens"`
-	StopSequences []string  `json:"stop_sequences,omitempty"`
-	Temperature   float32   `json:"temperature,omitempty"`
-	TopP          int       `json:"top_p,omitempty"`
-	TopK          int       `json:"top_k,omitempty"`
-	Metadata      *Metadata `json:"metadata,omitempty"`
-	Stream        bool      `json:"stream,omitempty"`
+	Model         string      `json:"model"`
+	Messages      []Message   `json:"messages"`
+	System        interface{} `json:"system,omitempty"`
+	MaxTokens     int         `json:"max_tokens"`
+	StopSequences []string    `json:"stop_sequences,omitempty"`
+	Temperature   float32     `json:"temperature,omitempty"`
+	TopP          float32     `json:"top_p,omitempty"`
+	TopK          int         `json:"top_k,omitempty"`
+	Metadata      *Metadata   `json:"metadata,omitempty"`
+	Stream        bool        `json:"stream,omitempty"`
+	Tools         interface{} `json:"tools,omitempty"`
+	ToolChoice    interface{} `json:"tool_choice,omitempty"`
 }
 
 type CompletionResponse struct {
@@ -40,8 +46,12 @@ type CompletionResponse struct {
 }
 
 type MessageResponseContent struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Type        string          `json:"type"`
+	Text        string          `json:"text,omitempty"`
+	Id          string          `json:"id,omitempty"`
+	Name        string          `json:"name,omitempty"`
+	Input       json.RawMessage `json:"input,omitempty"`
+	PartialJson string          `json:"partial_json,omitempty"`
 }
 
 type MessagesResponse struct {
@@ -53,8 +63,10 @@ type MessagesResponse struct {
 	StopReason   string                   `json:"stop_reason"`
 	StopSequence string                   `json:"stop_sequence,omitempty"`
 	Usage        struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 	}
 }
 
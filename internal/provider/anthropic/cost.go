@@ -1,6 +1,7 @@
 package anthropic
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -31,24 +32,63 @@ var AnthropicPerMillionTokenCost = map[string]map[string]float64{
 		"claude-3.5-sonnet": 15.0,
 		"claude-3-opus":     75.0,
 	},
+	"cache-write": {
+		"claude-sonnet-4.5": 3.75,
+		"claude-sonnet-4":   3.75,
+		"claude-3.7-sonnet": 3.75,
+		"claude-opus-4.1":   18.75,
+		"claude-opus-4":     18.75,
+		"claude-3.5-haiku":  1.0,
+		"claude-3-haiku":    0.3,
+
+		"claude-3.5-sonnet": 3.75,
+		"claude-3-opus":     18.75,
+	},
+	"cache-read": {
+		"claude-sonnet-4.5": 0.3,
+		"claude-sonnet-4":   0.3,
+		"claude-3.7-sonnet": 0.3,
+		"claude-opus-4.1":   1.5,
+		"claude-opus-4":     1.5,
+		"claude-3.5-haiku":  0.08,
+		"claude-3-haiku":    0.03,
+
+		"claude-3.5-sonnet": 0.3,
+		"claude-3-opus":     1.5,
+	},
 }
 
 type tokenCounter interface {
 	Count(input string) int
 }
 
+type priceCatalog interface {
+	GetCostMap(provider string, base map[string]map[string]float64) map[string]map[string]float64
+}
+
 type CostEstimator struct {
 	tokenCostMap map[string]map[string]float64
 	tc           tokenCounter
+	pc           priceCatalog
 }
 
-func NewCostEstimator(tc tokenCounter) *CostEstimator {
+func NewCostEstimator(tc tokenCounter, pc priceCatalog) *CostEstimator {
 	return &CostEstimator{
 		tokenCostMap: AnthropicPerMillionTokenCost,
 		tc:           tc,
+		pc:           pc,
 	}
 }
 
+// costMap returns the built-in cost map with the pricing catalog applied.
+func (ce *CostEstimator) costMap() map[string]map[string]float64 {
+	if ce.pc == nil {
+		return ce.tokenCostMap
+	}
+
+	return ce.pc.GetCostMap("anthropic", ce.tokenCostMap)
+}
+
 func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTks int) (float64, error) {
 	promptCost, err := ce.EstimatePromptCost(model, promptTks)
 	if err != nil {
@@ -64,7 +104,7 @@ func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTk
 }
 
 func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["prompt"]
+	costMap, ok := ce.costMap()["prompt"]
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 
@@ -86,6 +126,39 @@ func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, err
 	return tksInFloat / 1000000 * cost, nil
 }
 
+// EstimateCachedPromptCost prices the prompt tokens written to and read from
+// the prompt cache. Models without cache rates are priced at the prompt rate.
+func (ce *CostEstimator) EstimateCachedPromptCost(model string, cacheCreationTks, cacheReadTks int) (float64, error) {
+	if cacheCreationTks == 0 && cacheReadTks == 0 {
+		return 0, nil
+	}
+
+	selected := ""
+	if strings.HasPrefix(model, "us") {
+		selected = convertAmazonModelToAnthropicModel(model)
+	} else {
+		selected = SelectModel(model)
+	}
+
+	total := 0.0
+	for kind, tks := range map[string]int{"cache-write": cacheCreationTks, "cache-read": cacheReadTks} {
+		cost, ok := ce.costMap()[kind][selected]
+		if !ok {
+			promptCost, err := ce.EstimatePromptCost(model, tks)
+			if err != nil {
+				return 0, err
+			}
+
+			total += promptCost
+			continue
+		}
+
+		total += float64(tks) / 1000000 * cost
+	}
+
+	return total, nil
+}
+
 func SelectModel(model string) string {
 	if strings.HasPrefix(model, "claude-sonnet-4.5") || strings.HasPrefix(model, "claude-sonnet-4-5") {
 		return "claude-sonnet-4.5"
@@ -106,7 +179,7 @@ func SelectModel(model string) string {
 	} else if strings.HasPrefix(model, "claude-3-opus") {
 		return "claude-3-opus"
 	}
-	return ""
+	return model
 }
 
 func convertAmazonModelToAnthropicModel(model string) string {
@@ -119,7 +192,7 @@ func convertAmazonModelToAnthropicModel(model string) string {
 }
 
 func (ce *CostEstimator) EstimateCompletionCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["completion"]
+	costMap, ok := ce.costMap()["completion"]
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 	}
@@ -146,14 +219,79 @@ func (ce *CostEstimator) Count(input string) int {
 
 var (
 	anthropicMessageOverhead = 4
+
+	// ImageTokenEstimate approximates the tokens of an image block. Images
+	// cost about width * height / 750 tokens and are downscaled to stay around
+	// this amount, so the upper bound is used since sizes are unknown.
+	ImageTokenEstimate = 1600
+
+	// anthropicToolUseOverhead is the size of the system prompt that enables
+	// tool use when tools are provided.
+	anthropicToolUseOverhead = 346
 )
 
+// countContentTokens counts the tokens of a message content, which is either a
+// string or a list of blocks.
+func (ce *CostEstimator) countContentTokens(content interface{}) int {
+	if text, ok := content.(string); ok {
+		return ce.tc.Count(text)
+	}
+
+	blocks, ok := content.([]interface{})
+	if !ok {
+		return 0
+	}
+
+	count := 0
+	for _, b := range blocks {
+		block, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch block["type"] {
+		case "text":
+			if text, ok := block["text"].(string); ok {
+				count += ce.tc.Count(text)
+			}
+		case "image":
+			count += ImageTokenEstimate
+		case "tool_use":
+			if name, ok := block["name"].(string); ok {
+				count += ce.tc.Count(name)
+			}
+
+			if input, err := json.Marshal(block["input"]); err == nil {
+				count += ce.tc.Count(string(input))
+			}
+		case "tool_result":
+			count += ce.countContentTokens(block["content"])
+		}
+	}
+
+	return count
+}
+
 func (ce *CostEstimator) CountMessagesTokens(messages []Message) int {
 	count := 0
 
 	for _, message := range messages {
-		count += ce.tc.Count(message.Content) + anthropicMessageOverhead
+		count += ce.countContentTokens(message.Content) + anthropicMessageOverhead
 	}
 
 	return count + anthropicMessageOverhead
 }
+
+// CountMessagesRequestTokens counts the prompt tokens of a messages request,
+// including the system prompt and tool definitions.
+func (ce *CostEstimator) CountMessagesRequestTokens(mr *MessagesRequest) int {
+	count := ce.CountMessagesTokens(mr.Messages) + ce.countContentTokens(mr.System)
+
+	if mr.Tools != nil {
+		if tools, err := json.Marshal(mr.Tools); err == nil {
+			count += ce.tc.Count(string(tools)) + anthropicToolUseOverhead
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,464 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	goopenai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultMaxTokens is used when an OpenAI request does not specify a limit,
+// since the messages API requires one.
+const DefaultMaxTokens = 4096
+
+type ToolDefinition struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+type ImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	Url       string `json:"url,omitempty"`
+}
+
+type ContentBlock struct {
+	Type      string       `json:"type"`
+	Text      string       `json:"text,omitempty"`
+	Id        string       `json:"id,omitempty"`
+	Name      string       `json:"name,omitempty"`
+	Input     interface{}  `json:"input,omitempty"`
+	ToolUseId string       `json:"tool_use_id,omitempty"`
+	Content   string       `json:"content,omitempty"`
+	Source    *ImageSource `json:"source,omitempty"`
+}
+
+func convertImageUrl(url string) *ImageSource {
+	if strings.HasPrefix(url, "data:") {
+		header, data, found := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+		if found {
+			return &ImageSource{
+				Type:      "base64",
+				MediaType: strings.TrimSuffix(header, ";base64"),
+				Data:      data,
+			}
+		}
+	}
+
+	return &ImageSource{
+		Type: "url",
+		Url:  url,
+	}
+}
+
+func convertToolArguments(arguments string) interface{} {
+	input := map[string]interface{}{}
+	if len(arguments) != 0 {
+		json.Unmarshal([]byte(arguments), &input)
+	}
+
+	return input
+}
+
+func convertToolChoice(choice interface{}) interface{} {
+	switch converted := choice.(type) {
+	case string:
+		switch converted {
+		case "none":
+			return map[string]interface{}{"type": "none"}
+		case "required":
+			return map[string]interface{}{"type": "any"}
+		case "auto":
+			return map[string]interface{}{"type": "auto"}
+		}
+	case goopenai.ToolChoice:
+		return map[string]interface{}{"type": "tool", "name": converted.Function.Name}
+	case *goopenai.ToolChoice:
+		return map[string]interface{}{"type": "tool", "name": converted.Function.Name}
+	case map[string]interface{}:
+		if function, ok := converted["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok {
+				return map[string]interface{}{"type": "tool", "name": name}
+			}
+		}
+	}
+
+	return nil
+}
+
+func appendMessage(messages []Message, role string, blocks []ContentBlock) []Message {
+	if len(messages) != 0 && messages[len(messages)-1].Role == role {
+		last := &messages[len(messages)-1]
+		if existing, ok := last.Content.([]ContentBlock); ok {
+			last.Content = append(existing, blocks...)
+			return messages
+		}
+
+		if text, ok := last.Content.(string); ok {
+			last.Content = append([]ContentBlock{{Type: "text", Text: text}}, blocks...)
+			return messages
+		}
+	}
+
+	// plain text messages are sent as a string.
+	if len(blocks) == 1 && blocks[0].Type == "text" {
+		return append(messages, Message{Role: role, Content: blocks[0].Text})
+	}
+
+	return append(messages, Message{Role: role, Content: blocks})
+}
+
+// ConvertChatCompletionRequest converts an OpenAI chat completion request into
+// a messages request. System and developer messages become the system prompt,
+// tool calls become tool_use blocks and tool messages become tool_result
+// blocks.
+func ConvertChatCompletionRequest(req *goopenai.ChatCompletionRequest, model string) (*MessagesRequest, error) {
+	converted := &MessagesRequest{
+		Model:         model,
+		MaxTokens:     DefaultMaxTokens,
+		StopSequences: req.Stop,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		Stream:        req.Stream,
+	}
+
+	if req.MaxCompletionTokens != 0 {
+		converted.MaxTokens = req.MaxCompletionTokens
+	} else if req.MaxTokens != 0 {
+		converted.MaxTokens = req.MaxTokens
+	}
+
+	if len(req.User) != 0 {
+		converted.Metadata = &Metadata{UserId: req.User}
+	}
+
+	system := []string{}
+	messages := []Message{}
+	for _, message := range req.Messages {
+		switch message.Role {
+		case goopenai.ChatMessageRoleSystem, goopenai.ChatMessageRoleDeveloper:
+			if len(message.Content) != 0 {
+				system = append(system, message.Content)
+			}
+
+			for _, part := range message.MultiContent {
+				if part.Type == goopenai.ChatMessagePartTypeText {
+					system = append(system, part.Text)
+				}
+			}
+
+		case goopenai.ChatMessageRoleUser:
+			blocks := []ContentBlock{}
+			if len(message.Content) != 0 {
+				blocks = append(blocks, ContentBlock{Type: "text", Text: message.Content})
+			}
+
+			for _, part := range message.MultiContent {
+				if part.Type == goopenai.ChatMessagePartTypeText {
+					blocks = append(blocks, ContentBlock{Type: "text", Text: part.Text})
+				}
+
+				if part.Type == goopenai.ChatMessagePartTypeImageURL && part.ImageURL != nil {
+					blocks = append(blocks, ContentBlock{Type: "image", Source: convertImageUrl(part.ImageURL.URL)})
+				}
+			}
+
+			if len(blocks) == 0 {
+				return nil, errors.New("user message content cannot be empty")
+			}
+
+			messages = appendMessage(messages, "user", blocks)
+
+		case goopenai.ChatMessageRoleAssistant:
+			blocks := []ContentBlock{}
+			if len(message.Content) != 0 {
+				blocks = append(blocks, ContentBlock{Type: "text", Text: message.Content})
+			}
+
+			for _, part := range message.MultiContent {
+				if part.Type == goopenai.ChatMessagePartTypeText {
+					blocks = append(blocks, ContentBlock{Type: "text", Text: part.Text})
+				}
+			}
+
+			for _, tc := range message.ToolCalls {
+				blocks = append(blocks, ContentBlock{
+					Type:  "tool_use",
+					Id:    tc.ID,
+					Name:  tc.Function.Name,
+					Input: convertToolArguments(tc.Function.Arguments),
+				})
+			}
+
+			if len(blocks) == 0 {
+				continue
+			}
+
+			messages = appendMessage(messages, "assistant", blocks)
+
+		case goopenai.ChatMessageRoleTool:
+			content := message.Content
+			for _, part := range message.MultiContent {
+				if part.Type == goopenai.ChatMessagePartTypeText {
+					content += part.Text
+				}
+			}
+
+			messages = appendMessage(messages, "user", []ContentBlock{{
+				Type:      "tool_result",
+				ToolUseId: message.ToolCallID,
+				Content:   content,
+			}})
+
+		default:
+			return nil, errors.New("message role " + message.Role + " is not supported")
+		}
+	}
+
+	if len(system) != 0 {
+		converted.System = strings.Join(system, "\n\n")
+	}
+
+	converted.Messages = messages
+
+	if len(req.Tools) != 0 {
+		tools := []ToolDefinition{}
+		for _, tool := range req.Tools {
+			if tool.Function == nil {
+				continue
+			}
+
+			var schema interface{} = map[string]interface{}{"type": "object"}
+			if tool.Function.Parameters != nil {
+				schema = tool.Function.Parameters
+			}
+
+			tools = append(tools, ToolDefinition{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				InputSchema: schema,
+			})
+		}
+
+		converted.Tools = tools
+		if req.ToolChoice != nil {
+			converted.ToolChoice = convertToolChoice(req.ToolChoice)
+		}
+	}
+
+	return converted, nil
+}
+
+func convertStopReason(reason string) goopenai.FinishReason {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return goopenai.FinishReasonStop
+	case "max_tokens":
+		return goopenai.FinishReasonLength
+	case "tool_use":
+		return goopenai.FinishReasonToolCalls
+	case "refusal":
+		return goopenai.FinishReasonContentFilter
+	case "":
+		return goopenai.FinishReasonNull
+	}
+
+	return goopenai.FinishReasonStop
+}
+
+// ConvertMessagesResponse converts a messages response into an OpenAI chat
+// completion response reporting the given model.
+func ConvertMessagesResponse(res *MessagesResponse, model string) *goopenai.ChatCompletionResponse {
+	message := goopenai.ChatCompletionMessage{
+		Role: goopenai.ChatMessageRoleAssistant,
+	}
+
+	for _, content := range res.Content {
+		if content.Type == "text" {
+			message.Content += content.Text
+		}
+
+		if content.Type == "tool_use" {
+			arguments := "{}"
+			if len(content.Input) != 0 {
+				arguments = string(content.Input)
+			}
+
+			message.ToolCalls = append(message.ToolCalls, goopenai.ToolCall{
+				ID:   content.Id,
+				Type: goopenai.ToolTypeFunction,
+				Function: goopenai.FunctionCall{
+					Name:      content.Name,
+					Arguments: arguments,
+				},
+			})
+		}
+	}
+
+	return &goopenai.ChatCompletionResponse{
+		ID:      res.Id,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []goopenai.ChatCompletionChoice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: convertStopReason(res.StopReason),
+		}},
+		Usage: goopenai.Usage{
+			PromptTokens:     res.Usage.InputTokens,
+			CompletionTokens: res.Usage.OutputTokens,
+			TotalTokens:      res.Usage.InputTokens + res.Usage.OutputTokens,
+		},
+	}
+}
+
+type streamEvent struct {
+	Type         string                 `json:"type"`
+	Index        int                    `json:"index"`
+	Message      *MessagesResponse      `json:"message,omitempty"`
+	ContentBlock MessageResponseContent `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJson string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *Error `json:"error,omitempty"`
+}
+
+// StreamConverter converts the data of messages stream events into OpenAI
+// chat completion chunks.
+type StreamConverter struct {
+	id           string
+	model        string
+	created      int64
+	includeUsage bool
+	inputTokens  int
+	outputTokens int
+	toolIndexes  map[int]int
+}
+
+func NewStreamConverter(model string, includeUsage bool) *StreamConverter {
+	return &StreamConverter{
+		model:        model,
+		created:      time.Now().Unix(),
+		includeUsage: includeUsage,
+		toolIndexes:  map[int]int{},
+	}
+}
+
+func (sc *StreamConverter) chunk(delta goopenai.ChatCompletionStreamChoiceDelta, reason goopenai.FinishReason) *goopenai.ChatCompletionStreamResponse {
+	return &goopenai.ChatCompletionStreamResponse{
+		ID:      sc.id,
+		Object:  "chat.completion.chunk",
+		Created: sc.created,
+		Model:   sc.model,
+		Choices: []goopenai.ChatCompletionStreamChoice{{
+			Index:        0,
+			Delta:        delta,
+			FinishReason: reason,
+		}},
+	}
+}
+
+// Convert returns the chunks converted from the data of a single stream event
+// and whether the stream has ended.
+func (sc *StreamConverter) Convert(data []byte) ([]interface{}, bool, error) {
+	e := &streamEvent{}
+	err := json.Unmarshal(data, e)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch e.Type {
+	case "message_start":
+		if e.Message != nil {
+			sc.id = e.Message.Id
+			sc.inputTokens = e.Message.Usage.InputTokens
+		}
+
+		return []interface{}{sc.chunk(goopenai.ChatCompletionStreamChoiceDelta{Role: goopenai.ChatMessageRoleAssistant}, goopenai.FinishReasonNull)}, false, nil
+
+	case "content_block_start":
+		if e.ContentBlock.Type == "tool_use" {
+			index := len(sc.toolIndexes)
+			sc.toolIndexes[e.Index] = index
+
+			return []interface{}{sc.chunk(goopenai.ChatCompletionStreamChoiceDelta{
+				ToolCalls: []goopenai.ToolCall{{
+					Index: &index,
+					ID:    e.ContentBlock.Id,
+					Type:  goopenai.ToolTypeFunction,
+					Function: goopenai.FunctionCall{
+						Name: e.ContentBlock.Name,
+					},
+				}},
+			}, goopenai.FinishReasonNull)}, false, nil
+		}
+
+		if len(e.ContentBlock.Text) != 0 {
+			return []interface{}{sc.chunk(goopenai.ChatCompletionStreamChoiceDelta{Content: e.ContentBlock.Text}, goopenai.FinishReasonNull)}, false, nil
+		}
+
+	case "content_block_delta":
+		if e.Delta.Type == "text_delta" {
+			return []interface{}{sc.chunk(goopenai.ChatCompletionStreamChoiceDelta{Content: e.Delta.Text}, goopenai.FinishReasonNull)}, false, nil
+		}
+
+		if e.Delta.Type == "input_json_delta" {
+			index, ok := sc.toolIndexes[e.Index]
+			if !ok {
+				return nil, false, nil
+			}
+
+			return []interface{}{sc.chunk(goopenai.ChatCompletionStreamChoiceDelta{
+				ToolCalls: []goopenai.ToolCall{{
+					Index: &index,
+					Function: goopenai.FunctionCall{
+						Arguments: e.Delta.PartialJson,
+					},
+				}},
+			}, goopenai.FinishReasonNull)}, false, nil
+		}
+
+	case "message_delta":
+		sc.outputTokens = e.Usage.OutputTokens
+
+		return []interface{}{sc.chunk(goopenai.ChatCompletionStreamChoiceDelta{}, convertStopReason(e.Delta.StopReason))}, false, nil
+
+	case "message_stop":
+		if !sc.includeUsage {
+			return nil, true, nil
+		}
+
+		return []interface{}{&goopenai.ChatCompletionStreamResponse{
+			ID:      sc.id,
+			Object:  "chat.completion.chunk",
+			Created: sc.created,
+			Model:   sc.model,
+			Choices: []goopenai.ChatCompletionStreamChoice{},
+			Usage: &goopenai.Usage{
+				PromptTokens:     sc.inputTokens,
+				CompletionTokens: sc.outputTokens,
+				TotalTokens:      sc.inputTokens + sc.outputTokens,
+			},
+		}}, true, nil
+
+	case "error":
+		if e.Error != nil {
+			return []interface{}{&ErrorResponse{Error: e.Error}}, true, nil
+		}
+
+		return nil, true, nil
+	}
+
+	return nil, false, nil
+}
@@ -0,0 +1,163 @@
+package anthropic
+
+import (
+	"testing"
+
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamConverter_Convert(t *testing.T) {
+	tests := []struct {
+		name              string
+		includeUsage      bool
+		events            []string
+		expectedContent   string
+		expectedArguments string
+		expectedToolName  string
+		expectedReason    goopenai.FinishReason
+		expectedUsage     *goopenai.Usage
+		expectedError     bool
+		expectedDone      bool
+	}{
+		{
+			name:         "text with usage",
+			includeUsage: true,
+			events: []string{
+				`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+				`{"type":"message_stop"}`,
+			},
+			expectedContent: "Hello world",
+			expectedReason:  goopenai.FinishReasonStop,
+			expectedUsage:   &goopenai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+			expectedDone:    true,
+		},
+		{
+			name: "text without usage",
+			events: []string{
+				`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":1}}`,
+				`{"type":"message_stop"}`,
+			},
+			expectedContent: "Hi",
+			expectedReason:  goopenai.FinishReasonLength,
+			expectedDone:    true,
+		},
+		{
+			name: "tool use",
+			events: []string{
+				`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+				`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather"}}`,
+				`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+				`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":8}}`,
+				`{"type":"message_stop"}`,
+			},
+			expectedArguments: `{"city":"Paris"}`,
+			expectedToolName:  "get_weather",
+			expectedReason:    goopenai.FinishReasonToolCalls,
+			expectedDone:      true,
+		},
+		{
+			name: "error",
+			events: []string{
+				`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			},
+			expectedError: true,
+			expectedDone:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := NewStreamConverter("claude-3-5-sonnet", tt.includeUsage)
+
+			content := ""
+			arguments := ""
+			toolName := ""
+			var reason goopenai.FinishReason
+			var usage *goopenai.Usage
+			errored := false
+			done := false
+
+			for _, data := range tt.events {
+				require.False(t, done, "no events are expected after the stream ended")
+
+				chunks, ended, err := sc.Convert([]byte(data))
+				require.NoError(t, err)
+				done = ended
+
+				for _, chunk := range chunks {
+					converted, ok := chunk.(*goopenai.ChatCompletionStreamResponse)
+					if !ok {
+						assert.IsType(t, &ErrorResponse{}, chunk)
+						errored = true
+						continue
+					}
+
+					assert.Equal(t, "msg_1", converted.ID)
+					assert.Equal(t, "claude-3-5-sonnet", converted.Model)
+
+					if converted.Usage != nil {
+						usage = converted.Usage
+					}
+
+					for _, choice := range converted.Choices {
+						content += choice.Delta.Content
+						if len(choice.FinishReason) != 0 && choice.FinishReason != goopenai.FinishReasonNull {
+							reason = choice.FinishReason
+						}
+
+						for _, call := range choice.Delta.ToolCalls {
+							require.NotNil(t, call.Index)
+							assert.Equal(t, 0, *call.Index)
+							toolName += call.Function.Name
+							arguments += call.Function.Arguments
+						}
+					}
+				}
+			}
+
+			assert.Equal(t, tt.expectedContent, content)
+			assert.Equal(t, tt.expectedArguments, arguments)
+			assert.Equal(t, tt.expectedToolName, toolName)
+			assert.Equal(t, tt.expectedReason, reason)
+			assert.Equal(t, tt.expectedUsage, usage)
+			assert.Equal(t, tt.expectedError, errored)
+			assert.Equal(t, tt.expectedDone, done)
+		})
+	}
+}
+
+func TestStreamConverter_ConvertInvalidData(t *testing.T) {
+	_, _, err := NewStreamConverter("claude-3-5-sonnet", false).Convert([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestConvertStopReason(t *testing.T) {
+	tests := []struct {
+		reason   string
+		expected goopenai.FinishReason
+	}{
+		{reason: "end_turn", expected: goopenai.FinishReasonStop},
+		{reason: "stop_sequence", expected: goopenai.FinishReasonStop},
+		{reason: "max_tokens", expected: goopenai.FinishReasonLength},
+		{reason: "tool_use", expected: goopenai.FinishReasonToolCalls},
+		{reason: "refusal", expected: goopenai.FinishReasonContentFilter},
+		{reason: "", expected: goopenai.FinishReasonNull},
+		{reason: "unknown", expected: goopenai.FinishReasonStop},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			assert.Equal(t, tt.expected, convertStopReason(tt.reason))
+		})
+	}
+}
@@ -46,14 +46,29 @@ var AzureOpenAiPerThousandTokenCost = map[string]map[string]float64{
 	},
 }
 
+type priceCatalog interface {
+	GetCostMap(provider string, base map[string]map[string]float64) map[string]map[string]float64
+}
+
 type CostEstimator struct {
 	tokenCostMap map[string]map[string]float64
+	pc           priceCatalog
 }
 
-func NewCostEstimator() *CostEstimator {
+func NewCostEstimator(pc priceCatalog) *CostEstimator {
 	return &CostEstimator{
 		tokenCostMap: AzureOpenAiPerThousandTokenCost,
+		pc:           pc,
+	}
+}
+
+// costMap returns the built-in cost map with the pricing catalog applied.
+func (ce *CostEstimator) costMap() map[string]map[string]float64 {
+	if ce.pc == nil {
+		return ce.tokenCostMap
 	}
+
+	return ce.pc.GetCostMap("azure", ce.tokenCostMap)
 }
 
 func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTks int) (float64, error) {
@@ -71,7 +86,7 @@ func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTk
 }
 
 func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["prompt"]
+	costMap, ok := ce.costMap()["prompt"]
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 	}
@@ -86,7 +101,7 @@ func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, err
 }
 
 func (ce *CostEstimator) EstimateEmbeddingsInputCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["embeddings"]
+	costMap, ok := ce.costMap()["embeddings"]
 	if !ok {
 		return 0, errors.New("embeddings token cost is not provided")
 
@@ -102,7 +117,7 @@ func (ce *CostEstimator) EstimateEmbeddingsInputCost(model string, tks int) (flo
 }
 
 func (ce *CostEstimator) EstimateCompletionCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["completion"]
+	costMap, ok := ce.costMap()["completion"]
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 	}
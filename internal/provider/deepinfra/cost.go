@@ -25,18 +25,33 @@ var DeepinfraPerMillionTokenCost = map[string]map[string]float64{
 	},
 }
 
+type priceCatalog interface {
+	GetCostMap(provider string, base map[string]map[string]float64) map[string]map[string]float64
+}
+
 type CostEstimator struct {
 	tokenCostMap map[string]map[string]float64
+	pc           priceCatalog
 }
 
-func NewCostEstimator() *CostEstimator {
+func NewCostEstimator(pc priceCatalog) *CostEstimator {
 	return &CostEstimator{
 		tokenCostMap: DeepinfraPerMillionTokenCost,
+		pc:           pc,
+	}
+}
+
+// costMap returns the built-in cost map with the pricing catalog applied.
+func (ce *CostEstimator) costMap() map[string]map[string]float64 {
+	if ce.pc == nil {
+		return ce.tokenCostMap
 	}
+
+	return ce.pc.GetCostMap("deepinfra", ce.tokenCostMap)
 }
 
 func (ce *CostEstimator) EstimateEmbeddingsInputCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["prompt"]
+	costMap, ok := ce.costMap()["prompt"]
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 
@@ -0,0 +1,152 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var GeminiPerMillionTokenCost = map[string]map[string]float64{
+	"prompt": {
+		"gemini-2.5-pro":        1.25,
+		"gemini-2.5-flash":      0.3,
+		"gemini-2.5-flash-lite": 0.1,
+		"gemini-2.0-flash":      0.1,
+		"gemini-2.0-flash-lite": 0.075,
+
+		"gemini-1.5-pro":      1.25,
+		"gemini-1.5-flash":    0.075,
+		"gemini-1.5-flash-8b": 0.0375,
+	},
+	"completion": {
+		"gemini-2.5-pro":        10.0,
+		"gemini-2.5-flash":      2.5,
+		"gemini-2.5-flash-lite": 0.4,
+		"gemini-2.0-flash":      0.4,
+		"gemini-2.0-flash-lite": 0.3,
+
+		"gemini-1.5-pro":      5.0,
+		"gemini-1.5-flash":    0.3,
+		"gemini-1.5-flash-8b": 0.15,
+	},
+}
+
+type tokenCounter interface {
+	Count(input string) int
+}
+
+type priceCatalog interface {
+	GetCostMap(provider string, base map[string]map[string]float64) map[string]map[string]float64
+}
+
+type CostEstimator struct {
+	tokenCostMap map[string]map[string]float64
+	tc           tokenCounter
+	pc           priceCatalog
+}
+
+func NewCostEstimator(tc tokenCounter, pc priceCatalog) *CostEstimator {
+	return &CostEstimator{
+		tokenCostMap: GeminiPerMillionTokenCost,
+		tc:           tc,
+		pc:           pc,
+	}
+}
+
+// costMap returns the built-in cost map with the pricing catalog applied.
+func (ce *CostEstimator) costMap() map[string]map[string]float64 {
+	if ce.pc == nil {
+		return ce.tokenCostMap
+	}
+
+	return ce.pc.GetCostMap("gemini", ce.tokenCostMap)
+}
+
+func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTks int) (float64, error) {
+	promptCost, err := ce.EstimatePromptCost(model, promptTks)
+	if err != nil {
+		return 0, err
+	}
+
+	completionCost, err := ce.EstimateCompletionCost(model, completionTks)
+	if err != nil {
+		return 0, err
+	}
+
+	return promptCost + completionCost, nil
+}
+
+func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, error) {
+	costMap, ok := ce.costMap()["prompt"]
+	if !ok {
+		return 0, errors.New("prompt token cost is not provided")
+	}
+
+	cost, ok := costMap[SelectModel(model)]
+	if !ok {
+		return 0, fmt.Errorf("%s is not present in the cost map provided", model)
+	}
+
+	tksInFloat := float64(tks)
+	return tksInFloat / 1000000 * cost, nil
+}
+
+func (ce *CostEstimator) EstimateCompletionCost(model string, tks int) (float64, error) {
+	costMap, ok := ce.costMap()["completion"]
+	if !ok {
+		return 0, errors.New("completion token cost is not provided")
+	}
+
+	cost, ok := costMap[SelectModel(model)]
+	if !ok {
+		return 0, fmt.Errorf("%s is not present in the cost map provided", model)
+	}
+
+	tksInFloat := float64(tks)
+	return tksInFloat / 1000000 * cost, nil
+}
+
+func SelectModel(model string) string {
+	model = strings.TrimPrefix(strings.ToLower(model), "models/")
+
+	if strings.HasPrefix(model, "gemini-2.5-pro") {
+		return "gemini-2.5-pro"
+	} else if strings.HasPrefix(model, "gemini-2.5-flash-lite") {
+		return "gemini-2.5-flash-lite"
+	} else if strings.HasPrefix(model, "gemini-2.5-flash") {
+		return "gemini-2.5-flash"
+	} else if strings.HasPrefix(model, "gemini-2.0-flash-lite") {
+		return "gemini-2.0-flash-lite"
+	} else if strings.HasPrefix(model, "gemini-2.0-flash") {
+		return "gemini-2.0-flash"
+	} else if strings.HasPrefix(model, "gemini-1.5-pro") {
+		return "gemini-1.5-pro"
+	} else if strings.HasPrefix(model, "gemini-1.5-flash-8b") {
+		return "gemini-1.5-flash-8b"
+	} else if strings.HasPrefix(model, "gemini-1.5-flash") {
+		return "gemini-1.5-flash"
+	}
+
+	return model
+}
+
+func (ce *CostEstimator) Count(input string) int {
+	return ce.tc.Count(input)
+}
+
+func (ce *CostEstimator) CountContentsTokens(r *GenerateContentRequest) int {
+	if r == nil {
+		return 0
+	}
+
+	count := 0
+	for _, content := range r.Contents {
+		count += ce.tc.Count(content.GetText())
+	}
+
+	if r.SystemInstruction != nil {
+		count += ce.tc.Count(r.SystemInstruction.GetText())
+	}
+
+	return count
+}
@@ -0,0 +1,119 @@
+package gemini
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	GenerateContentAction       = "generateContent"
+	StreamGenerateContentAction = "streamGenerateContent"
+)
+
+type Part struct {
+	Text                string          `json:"text,omitempty"`
+	Thought             bool            `json:"thought,omitempty"`
+	ThoughtSignature    string          `json:"thoughtSignature,omitempty"`
+	InlineData          json.RawMessage `json:"inlineData,omitempty"`
+	FileData            json.RawMessage `json:"fileData,omitempty"`
+	FunctionCall        json.RawMessage `json:"functionCall,omitempty"`
+	FunctionResponse    json.RawMessage `json:"functionResponse,omitempty"`
+	ExecutableCode      json.RawMessage `json:"executableCode,omitempty"`
+	CodeExecutionResult json.RawMessage `json:"codeExecutionResult,omitempty"`
+	VideoMetadata       json.RawMessage `json:"videoMetadata,omitempty"`
+}
+
+type Content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []Part `json:"parts"`
+}
+
+type GenerateContentRequest struct {
+	Contents          []Content         `json:"contents"`
+	SystemInstruction *Content          `json:"systemInstruction,omitempty"`
+	Tools             []json.RawMessage `json:"tools,omitempty"`
+	ToolConfig        json.RawMessage   `json:"toolConfig,omitempty"`
+	SafetySettings    []json.RawMessage `json:"safetySettings,omitempty"`
+	GenerationConfig  json.RawMessage   `json:"generationConfig,omitempty"`
+	CachedContent     string            `json:"cachedContent,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+}
+
+type Candidate struct {
+	Content      *Content `json:"content,omitempty"`
+	FinishReason string   `json:"finishReason,omitempty"`
+	Index        int      `json:"index"`
+}
+
+type UsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+}
+
+type GenerateContentResponse struct {
+	Candidates     []Candidate     `json:"candidates"`
+	PromptFeedback json.RawMessage `json:"promptFeedback,omitempty"`
+	UsageMetadata  *UsageMetadata  `json:"usageMetadata,omitempty"`
+	ModelVersion   string          `json:"modelVersion,omitempty"`
+	ResponseId     string          `json:"responseId,omitempty"`
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+type ErrorResponse struct {
+	Error *Error `json:"error"`
+}
+
+// ParseModelAction splits the last path segment of a Gemini request such as
+// "gemini-2.5-flash:streamGenerateContent" into the model and the action.
+func ParseModelAction(param string) (string, string) {
+	trimmed := strings.TrimPrefix(param, "/")
+	index := strings.LastIndex(trimmed, ":")
+	if index == -1 {
+		return trimmed, ""
+	}
+
+	return trimmed[:index], trimmed[index+1:]
+}
+
+// GetText concatenates the text of all non thought parts in the content.
+func (c *Content) GetText() string {
+	if c == nil {
+		return ""
+	}
+
+	text := ""
+	for _, part := range c.Parts {
+		if !part.Thought {
+			text += part.Text
+		}
+	}
+
+	return text
+}
+
+// GetText returns the text of the first candidate of the response.
+func (r *GenerateContentResponse) GetText() string {
+	if r == nil || len(r.Candidates) == 0 {
+		return ""
+	}
+
+	return r.Candidates[0].Content.GetText()
+}
+
+// CompletionTokens returns the number of billable output tokens. Thinking
+// tokens are charged at the output rate.
+func (u *UsageMetadata) CompletionTokens() int {
+	if u == nil {
+		return 0
+	}
+
+	return u.CandidatesTokenCount + u.ThoughtsTokenCount
+}
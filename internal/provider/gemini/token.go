@@ -0,0 +1,28 @@
+package gemini
+
+import (
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// TokenCounter approximates Gemini token counts. Google does not publish the
+// Gemini tokenizer, so cl100k_base is used when the upstream response does not
+// carry usage metadata.
+type TokenCounter struct {
+	encoder *tiktoken.Tiktoken
+}
+
+func NewTokenCounter() (*TokenCounter, error) {
+	encoder, err := tiktoken.GetEncoding("cl100k_base")
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenCounter{
+		encoder: encoder,
+	}, nil
+}
+
+func (tc *TokenCounter) Count(input string) int {
+	token := tc.encoder.Encode(input, nil, nil)
+	return len(token)
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+type healthState struct {
+	failures     int
+	ejectedUntil time.Time
+}
+
+// HealthTracker passively tracks upstream responses per provider setting and
+// temporarily ejects a setting after consecutive 429 or 5xx responses.
+type HealthTracker struct {
+	mu        sync.Mutex
+	threshold int
+	duration  time.Duration
+	states    map[string]*healthState
+}
+
+func NewHealthTracker(threshold int, duration time.Duration) *HealthTracker {
+	return &HealthTracker{
+		threshold: threshold,
+		duration:  duration,
+		states:    map[string]*healthState{},
+	}
+}
+
+func isUnhealthyStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+func (ht *HealthTracker) RecordStatus(settingId string, status int) {
+	if len(settingId) == 0 || ht.threshold <= 0 {
+		return
+	}
+
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	state, ok := ht.states[settingId]
+	if !ok {
+		state = &healthState{}
+		ht.states[settingId] = state
+	}
+
+	if !isUnhealthyStatus(status) {
+		if status < http.StatusBadRequest {
+			state.failures = 0
+		}
+
+		return
+	}
+
+	state.failures++
+	if state.failures >= ht.threshold {
+		state.ejectedUntil = time.Now().Add(ht.duration)
+		state.failures = 0
+	}
+}
+
+func (ht *HealthTracker) IsHealthy(settingId string) bool {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	state, ok := ht.states[settingId]
+	if !ok {
+		return true
+	}
+
+	return time.Now().After(state.ejectedUntil)
+}
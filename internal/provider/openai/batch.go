@@ -0,0 +1,155 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// BatchDiscount is the share of the regular price that is charged for
+// requests processed through the batch API.
+const BatchDiscount = 0.5
+
+const BatchStatusCompleted = "completed"
+
+type Batch struct {
+	Id           string `json:"id"`
+	Endpoint     string `json:"endpoint"`
+	InputFileId  string `json:"input_file_id"`
+	OutputFileId string `json:"output_file_id"`
+	Status       string `json:"status"`
+}
+
+type batchOutputUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+type batchOutputLine struct {
+	Response *struct {
+		StatusCode int `json:"status_code"`
+		Body       struct {
+			Model string            `json:"model"`
+			Usage *batchOutputUsage `json:"usage"`
+		} `json:"body"`
+	} `json:"response"`
+}
+
+// BatchUsage is the aggregated usage of the succeeded requests of a batch
+// that were priced as the same model.
+type BatchUsage struct {
+	Model              string
+	NumberOfRequests   int
+	PromptTokens       int
+	CachedPromptTokens int
+	CompletionTokens   int
+	CostInUsd          float64
+}
+
+// BatchCost is the usage of a batch broken down by model. Succeeded requests
+// that cannot be priced are counted in UnpricedRequests and left out.
+type BatchCost struct {
+	Usages           []*BatchUsage
+	CostInUsd        float64
+	UnpricedRequests int
+}
+
+var snapshotSuffix = regexp.MustCompile(`-\d{4}-\d{2}-\d{2}$`)
+
+// estimateBatchRequestCost prices a request of a batch by the model of its
+// response. OpenAI responds with dated snapshot names such as
+// gpt-4o-mini-2024-07-18, which are priced as their model alias unless the
+// snapshot has a price of its own. It returns the model the request was
+// priced as.
+func (ce *CostEstimator) estimateBatchRequestCost(isEmbeddings bool, model string, u *batchOutputUsage, cached int) (string, float64, error) {
+	price := func(model string) (float64, error) {
+		if isEmbeddings {
+			return ce.EstimateEmbeddingsInputCost(model, u.PromptTokens)
+		}
+
+		return ce.EstimateTotalCostWithCachedTokens(model, u.PromptTokens, cached, u.CompletionTokens)
+	}
+
+	cost, err := price(model)
+	if err == nil {
+		return model, cost, nil
+	}
+
+	alias := snapshotSuffix.ReplaceAllString(model, "")
+	if alias == model {
+		return model, 0, err
+	}
+
+	cost, err = price(alias)
+	return alias, cost, err
+}
+
+// EstimateBatchCost prices the JSONL output file of a completed batch. Each
+// succeeded request is priced at the regular rate of its model for the batch
+// endpoint before the batch discount is applied.
+func (ce *CostEstimator) EstimateBatchCost(endpoint string, output []byte) (*BatchCost, error) {
+	isEmbeddings := strings.HasSuffix(endpoint, "/embeddings")
+	if !isEmbeddings && !strings.HasSuffix(endpoint, "/chat/completions") && !strings.HasSuffix(endpoint, "/completions") {
+		return nil, errors.New("batch endpoint is not supported: " + endpoint)
+	}
+
+	bc := &BatchCost{
+		Usages: []*BatchUsage{},
+	}
+
+	usages := map[string]*BatchUsage{}
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		ol := &batchOutputLine{}
+		err := json.Unmarshal(line, ol)
+		if err != nil {
+			bc.UnpricedRequests++
+			continue
+		}
+
+		if ol.Response == nil || ol.Response.StatusCode != http.StatusOK || ol.Response.Body.Usage == nil {
+			continue
+		}
+
+		u := ol.Response.Body.Usage
+
+		cached := 0
+		if u.PromptTokensDetails != nil {
+			cached = u.PromptTokensDetails.CachedTokens
+		}
+
+		model, cost, err := ce.estimateBatchRequestCost(isEmbeddings, ol.Response.Body.Model, u, cached)
+		if err != nil {
+			bc.UnpricedRequests++
+			continue
+		}
+
+		usage, ok := usages[model]
+		if !ok {
+			usage = &BatchUsage{
+				Model: model,
+			}
+
+			usages[model] = usage
+			bc.Usages = append(bc.Usages, usage)
+		}
+
+		usage.NumberOfRequests++
+		usage.PromptTokens += u.PromptTokens
+		usage.CachedPromptTokens += cached
+		usage.CompletionTokens += u.CompletionTokens
+		usage.CostInUsd += cost * BatchDiscount
+		bc.CostInUsd += cost * BatchDiscount
+	}
+
+	return bc, nil
+}
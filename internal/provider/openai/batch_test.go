@@ -0,0 +1,124 @@
+package openai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var batchTestCostMap = map[string]map[string]float64{
+	"prompt": {
+		"gpt-4o":            0.002,
+		"gpt-4o-2024-05-13": 0.005,
+		"gpt-4o-mini":       0.0002,
+	},
+	"completion": {
+		"gpt-4o":            0.008,
+		"gpt-4o-2024-05-13": 0.015,
+		"gpt-4o-mini":       0.0008,
+	},
+	"embeddings": {
+		"text-embedding-3-small": 0.00002,
+	},
+}
+
+func batchOutputFile(lines ...string) []byte {
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func TestCostEstimator_EstimateBatchCost(t *testing.T) {
+	ce := NewCostEstimator(batchTestCostMap, nil, nil)
+
+	tests := []struct {
+		name     string
+		endpoint string
+		output   []byte
+		expected *BatchCost
+	}{
+		{
+			name:     "snapshot names are priced as their alias",
+			endpoint: "/v1/chat/completions",
+			output: batchOutputFile(
+				`{"custom_id":"1","response":{"status_code":200,"body":{"model":"gpt-4o-mini-2024-07-18","usage":{"prompt_tokens":1000,"completion_tokens":1000}}}}`,
+				`{"custom_id":"2","response":{"status_code":200,"body":{"model":"gpt-4o-mini","usage":{"prompt_tokens":1000,"completion_tokens":0}}}}`,
+			),
+			expected: &BatchCost{
+				Usages: []*BatchUsage{
+					{Model: "gpt-4o-mini", NumberOfRequests: 2, PromptTokens: 2000, CompletionTokens: 1000, CostInUsd: 0.0006},
+				},
+				CostInUsd: 0.0006,
+			},
+		},
+		{
+			name:     "snapshots with their own price keep it and models are kept apart",
+			endpoint: "/v1/chat/completions",
+			output: batchOutputFile(
+				`{"custom_id":"1","response":{"status_code":200,"body":{"model":"gpt-4o-2024-05-13","usage":{"prompt_tokens":1000,"completion_tokens":1000}}}}`,
+				`{"custom_id":"2","response":{"status_code":200,"body":{"model":"gpt-4o-2024-08-06","usage":{"prompt_tokens":1000,"completion_tokens":1000}}}}`,
+			),
+			expected: &BatchCost{
+				Usages: []*BatchUsage{
+					{Model: "gpt-4o-2024-05-13", NumberOfRequests: 1, PromptTokens: 1000, CompletionTokens: 1000, CostInUsd: 0.01},
+					{Model: "gpt-4o", NumberOfRequests: 1, PromptTokens: 1000, CompletionTokens: 1000, CostInUsd: 0.005},
+				},
+				CostInUsd: 0.015,
+			},
+		},
+		{
+			name:     "unpriceable and failed requests are skipped",
+			endpoint: "/v1/chat/completions",
+			output: batchOutputFile(
+				`{"custom_id":"1","response":{"status_code":200,"body":{"model":"unknown-model","usage":{"prompt_tokens":1000,"completion_tokens":1000}}}}`,
+				`{"custom_id":"2","response":{"status_code":500,"body":{}}}`,
+				`{"custom_id":"3","response":{"status_code":200,"body":{"model":"gpt-4o-mini","usage":{"prompt_tokens":1000,"completion_tokens":1000}}}}`,
+			),
+			expected: &BatchCost{
+				Usages: []*BatchUsage{
+					{Model: "gpt-4o-mini", NumberOfRequests: 1, PromptTokens: 1000, CompletionTokens: 1000, CostInUsd: 0.0005},
+				},
+				CostInUsd:        0.0005,
+				UnpricedRequests: 1,
+			},
+		},
+		{
+			name:     "embeddings",
+			endpoint: "/v1/embeddings",
+			output: batchOutputFile(
+				`{"custom_id":"1","response":{"status_code":200,"body":{"model":"text-embedding-3-small","usage":{"prompt_tokens":100000,"total_tokens":100000}}}}`,
+			),
+			expected: &BatchCost{
+				Usages: []*BatchUsage{
+					{Model: "text-embedding-3-small", NumberOfRequests: 1, PromptTokens: 100000, CostInUsd: 0.001},
+				},
+				CostInUsd: 0.001,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, err := ce.EstimateBatchCost(tt.endpoint, tt.output)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected.UnpricedRequests, bc.UnpricedRequests)
+			assert.InDelta(t, tt.expected.CostInUsd, bc.CostInUsd, 1e-9)
+			require.Len(t, bc.Usages, len(tt.expected.Usages))
+
+			for i, expected := range tt.expected.Usages {
+				actual := bc.Usages[i]
+				assert.Equal(t, expected.Model, actual.Model)
+				assert.Equal(t, expected.NumberOfRequests, actual.NumberOfRequests)
+				assert.Equal(t, expected.PromptTokens, actual.PromptTokens)
+				assert.Equal(t, expected.CompletionTokens, actual.CompletionTokens)
+				assert.InDelta(t, expected.CostInUsd, actual.CostInUsd, 1e-9)
+			}
+		})
+	}
+}
+
+func TestCostEstimator_EstimateBatchCost_UnsupportedEndpoint(t *testing.T) {
+	_, err := NewCostEstimator(batchTestCostMap, nil, nil).EstimateBatchCost("/v1/images/generations", nil)
+	assert.Error(t, err)
+}
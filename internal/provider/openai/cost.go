@@ -387,18 +387,33 @@ type tokenCounter interface {
 	Count(model string, input string) (int, error)
 }
 
+type priceCatalog interface {
+	GetCostMap(provider string, base map[string]map[string]float64) map[string]map[string]float64
+}
+
 type CostEstimator struct {
 	tokenCostMap map[string]map[string]float64
 	tc           tokenCounter
+	pc           priceCatalog
 }
 
-func NewCostEstimator(m map[string]map[string]float64, tc tokenCounter) *CostEstimator {
+func NewCostEstimator(m map[string]map[string]float64, tc tokenCounter, pc priceCatalog) *CostEstimator {
 	return &CostEstimator{
 		tokenCostMap: m,
 		tc:           tc,
+		pc:           pc,
 	}
 }
 
+// costMap returns the built-in cost map with the pricing catalog applied.
+func (ce *CostEstimator) costMap() map[string]map[string]float64 {
+	if ce.pc == nil {
+		return ce.tokenCostMap
+	}
+
+	return ce.pc.GetCostMap("openai", ce.tokenCostMap)
+}
+
 func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTks int) (float64, error) {
 	totalTokens := int64(promptTks + completionTks)
 	model = ModelWithContextLength(model, totalTokens)
@@ -415,8 +430,38 @@ func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTk
 	return promptCost + completionCost, nil
 }
 
+// EstimateTotalCostWithCachedTokens prices cached prompt tokens at the cached
+// prompt rate and the rest of the prompt at the regular rate. Models without a
+// cached prompt rate are priced at the regular rate.
+func (ce *CostEstimator) EstimateTotalCostWithCachedTokens(model string, promptTks, cachedTks, completionTks int) (float64, error) {
+	if cachedTks <= 0 || cachedTks > promptTks {
+		return ce.EstimateTotalCost(model, promptTks, completionTks)
+	}
+
+	model = ModelWithContextLength(model, int64(promptTks+completionTks))
+	cachedCost, err := ce.estimateResponseApiTokensCost("cached-prompt", model, int64(cachedTks))
+	if err != nil {
+		cachedCost, err = ce.EstimatePromptCost(model, cachedTks)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	promptCost, err := ce.EstimatePromptCost(model, promptTks-cachedTks)
+	if err != nil {
+		return 0, err
+	}
+
+	completionCost, err := ce.EstimateCompletionCost(model, completionTks)
+	if err != nil {
+		return 0, err
+	}
+
+	return promptCost + cachedCost + completionCost, nil
+}
+
 func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["prompt"]
+	costMap, ok := ce.costMap()["prompt"]
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 
@@ -432,7 +477,7 @@ func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, err
 }
 
 func (ce *CostEstimator) EstimateEmbeddingsInputCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["embeddings"]
+	costMap, ok := ce.costMap()["embeddings"]
 	if !ok {
 		return 0, errors.New("embeddings token cost is not provided")
 
@@ -448,7 +493,7 @@ func (ce *CostEstimator) EstimateEmbeddingsInputCost(model string, tks int) (flo
 }
 
 func (ce *CostEstimator) EstimateCompletionCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["completion"]
+	costMap, ok := ce.costMap()["completion"]
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 	}
@@ -560,7 +605,7 @@ func (ce *CostEstimator) estimateImageByMetadata(model string, metadata *ImageRe
 	var totalCost float64
 
 	textInputTokens := metadata.Usage.InputTokensDetails.TextTokens
-	textInputCostMap, ok := ce.tokenCostMap["prompt"]
+	textInputCostMap, ok := ce.costMap()["prompt"]
 	if !ok {
 		return 0, errors.New("images input tokens cost map is not provided")
 	}
@@ -568,7 +613,7 @@ func (ce *CostEstimator) estimateImageByMetadata(model string, metadata *ImageRe
 	totalCost += (float64(textInputTokens) / 1000) * textInputCost
 
 	imageInputTokens := metadata.Usage.InputTokensDetails.ImageTokens
-	imageInputCostMap, ok := ce.tokenCostMap["images-tokens-input"]
+	imageInputCostMap, ok := ce.costMap()["images-tokens-input"]
 	if !ok {
 		return 0, errors.New("images input tokens cost map is not provided")
 	}
@@ -576,7 +621,7 @@ func (ce *CostEstimator) estimateImageByMetadata(model string, metadata *ImageRe
 	totalCost += (float64(imageInputTokens) / 1000) * imageInputCost
 
 	outputTokens := metadata.Usage.OutputTokens
-	imageOutputCostMap, ok := ce.tokenCostMap["images-tokens-output"]
+	imageOutputCostMap, ok := ce.costMap()["images-tokens-output"]
 	if !ok {
 		return 0, errors.New("images output tokens cost map is not provided")
 	}
@@ -592,7 +637,7 @@ func (ce *CostEstimator) EstimateImagesCost(model, quality, resolution string, m
 		return mCost, nil
 	}
 
-	costMap, ok := ce.tokenCostMap["images"]
+	costMap, ok := ce.costMap()["images"]
 	if !ok {
 		return 0, errors.New("images cost map is not provided")
 	}
@@ -736,7 +781,7 @@ func prepareGptImageQuality(quality string) (string, error) {
 func (ce *CostEstimator) EstimateTranscriptionCost(secs float64, model string, usage *TranscriptionResponseUsage) (float64, error) {
 	if usage != nil {
 		inputTokens := usage.InputTokens
-		costMap, ok := ce.tokenCostMap["transcription-input"]
+		costMap, ok := ce.costMap()["transcription-input"]
 		if !ok {
 			return 0, errors.New("transcription input token cost map is not provided")
 		}
@@ -746,7 +791,7 @@ func (ce *CostEstimator) EstimateTranscriptionCost(secs float64, model string, u
 		}
 
 		outputTokens := usage.OutputTokens
-		costMap, ok = ce.tokenCostMap["transcription-output"]
+		costMap, ok = ce.costMap()["transcription-output"]
 		if !ok {
 			return 0, errors.New("transcription output token cost map is not provided")
 		}
@@ -757,7 +802,7 @@ func (ce *CostEstimator) EstimateTranscriptionCost(secs float64, model string, u
 
 		return (float64(inputTokens)/1000)*inputCost + (float64(outputTokens)/1000)*outputCost, nil
 	}
-	costMap, ok := ce.tokenCostMap["audio"]
+	costMap, ok := ce.costMap()["audio"]
 	if !ok {
 		return 0, errors.New("audio cost map is not provided")
 	}
@@ -771,7 +816,7 @@ func (ce *CostEstimator) EstimateTranscriptionCost(secs float64, model string, u
 }
 
 func (ce *CostEstimator) EstimateSpeechCost(input string, model string) (float64, error) {
-	costMap, ok := ce.tokenCostMap["audio"]
+	costMap, ok := ce.costMap()["audio"]
 	if !ok {
 		return 0, errors.New("audio cost map is not provided")
 	}
@@ -785,7 +830,7 @@ func (ce *CostEstimator) EstimateSpeechCost(input string, model string) (float64
 }
 
 func (ce *CostEstimator) EstimateFinetuningCost(num int, model string) (float64, error) {
-	costMap, ok := ce.tokenCostMap["finetune"]
+	costMap, ok := ce.costMap()["finetune"]
 	if !ok {
 		return 0, errors.New("audio cost map is not provided")
 	}
@@ -883,7 +928,7 @@ func (ce *CostEstimator) EstimateVideoCost(metadata *VideoResponseMetadata) (flo
 	if metadata == nil {
 		return 0, errors.New("metadata is nil")
 	}
-	costMap, ok := ce.tokenCostMap["video"]
+	costMap, ok := ce.costMap()["video"]
 	if !ok {
 		return 0, errors.New("video cost map is not provided")
 	}
@@ -941,7 +986,7 @@ func extendedToolType(toolType, model string) string {
 }
 
 func (ce *CostEstimator) estimateResponseApiTokensCost(costMapKey, model string, tks int64) (float64, error) {
-	costMap, ok := ce.tokenCostMap[costMapKey]
+	costMap, ok := ce.costMap()[costMapKey]
 	if !ok {
 		return 0, errors.New("cost map is not provided")
 	}
@@ -11,6 +11,8 @@ type Setting struct {
 	Name          string            `json:"name"`
 	AllowedModels []string          `json:"allowedModels"`
 	CostMap       *CostMap          `json:"costMap"`
+	Weight        int               `json:"weight"`
+	ModelMap      map[string]string `json:"modelMap"`
 }
 
 type CostMap struct {
@@ -19,16 +21,40 @@ type CostMap struct {
 	EmbeddingsCostPerModel map[string]float64 `json:"embeddingsCostPerModel"`
 }
 
+// Prices reports whether the cost map has a custom price for the model.
+func (cm *CostMap) Prices(model string) bool {
+	if cm == nil {
+		return false
+	}
+
+	_, prompt := cm.PromptCostPerModel[model]
+	_, embeddings := cm.EmbeddingsCostPerModel[model]
+
+	return prompt || embeddings
+}
+
 func (s *Setting) GetParam(key string) string {
 	return s.Setting[key]
 }
 
+// GetWeight returns the share of traffic the setting receives when a key
+// rotates between settings. Settings without a weight default to 1.
+func (s *Setting) GetWeight() int {
+	if s.Weight <= 0 {
+		return 1
+	}
+
+	return s.Weight
+}
+
 type UpdateSetting struct {
 	UpdatedAt     int64             `json:"updatedAt"`
 	Setting       map[string]string `json:"setting,omitempty"`
 	Name          *string           `json:"name"`
 	AllowedModels *[]string         `json:"allowedModels,omitempty"`
 	CostMap       *CostMap          `json:"costMap,omitempty"`
+	Weight        *int              `json:"weight,omitempty"`
+	ModelMap      map[string]string `json:"modelMap,omitempty"`
 }
 
 func EstimateCostWithCostMap(model string, tks int, div float64, costMap map[string]float64) (float64, error) {
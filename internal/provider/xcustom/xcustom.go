@@ -3,6 +3,7 @@ package xcustom
 import (
 	"fmt"
 	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/tidwall/gjson"
 	"net/http"
 	"regexp"
 
@@ -10,19 +11,37 @@ import (
 )
 
 var XCustomSettingFields = struct {
-	ApiKey       string
-	Endpoint     string
-	AuthLocation string
-	AuthTemplate string
-	AuthTarget   string
-	AuthMask     string
+	ApiKey                   string
+	Endpoint                 string
+	AuthLocation             string
+	AuthTemplate             string
+	AuthTarget               string
+	AuthMask                 string
+	ModelLocation            string
+	PromptTokensLocation     string
+	CompletionTokensLocation string
+	MessagesLocation         string
+	UserLocation             string
 }{
-	ApiKey:       "apikey",
-	Endpoint:     "endpoint",
-	AuthLocation: "authLocation",
-	AuthTemplate: "authTemplate",
-	AuthTarget:   "authTarget",
-	AuthMask:     "authMask",
+	ApiKey:                   "apikey",
+	Endpoint:                 "endpoint",
+	AuthLocation:             "authLocation",
+	AuthTemplate:             "authTemplate",
+	AuthTarget:               "authTarget",
+	AuthMask:                 "authMask",
+	ModelLocation:            "modelLocation",
+	PromptTokensLocation:     "promptTokensLocation",
+	CompletionTokensLocation: "completionTokensLocation",
+	MessagesLocation:         "messagesLocation",
+	UserLocation:             "userLocation",
+}
+
+// Usage holds the model and token counts extracted from an xCustom request
+// and response.
+type Usage struct {
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
 }
 
 type AuthLocation string
@@ -99,3 +118,65 @@ func GetAuthLocation(raw string) AuthLocation {
 		return AuthLocations.Unknown
 	}
 }
+
+// TracksUsage reports whether the setting declares where token usage is
+// located in the provider responses.
+func TracksUsage(pSetting *provider.Setting) bool {
+	return len(pSetting.GetParam(XCustomSettingFields.PromptTokensLocation)) != 0 ||
+		len(pSetting.GetParam(XCustomSettingFields.CompletionTokensLocation)) != 0
+}
+
+// ExtractUsage updates u with the values found in a JSON document using the
+// locations declared in the setting. Values that are not present are left
+// unchanged so that it can be applied to every event of a stream.
+func ExtractUsage(pSetting *provider.Setting, body []byte, u *Usage) {
+	if !gjson.ValidBytes(body) {
+		return
+	}
+
+	if loc := pSetting.GetParam(XCustomSettingFields.ModelLocation); len(loc) != 0 {
+		result := gjson.GetBytes(body, loc)
+		if len(result.Str) != 0 {
+			u.Model = result.Str
+		}
+	}
+
+	if loc := pSetting.GetParam(XCustomSettingFields.PromptTokensLocation); len(loc) != 0 {
+		result := gjson.GetBytes(body, loc)
+		if result.Type == gjson.Number {
+			u.PromptTokens = int(result.Int())
+		}
+	}
+
+	if loc := pSetting.GetParam(XCustomSettingFields.CompletionTokensLocation); len(loc) != 0 {
+		result := gjson.GetBytes(body, loc)
+		if result.Type == gjson.Number {
+			u.CompletionTokens = int(result.Int())
+		}
+	}
+}
+
+// GetRequestString returns the string found in the request body at the
+// location declared by the setting field.
+func GetRequestString(pSetting *provider.Setting, field string, body []byte) string {
+	loc := pSetting.GetParam(field)
+	if len(loc) == 0 {
+		return ""
+	}
+
+	return gjson.GetBytes(body, loc).Str
+}
+
+// GetMessagesLocations returns the comma separated locations of the request
+// contents that policies are applied to.
+func GetMessagesLocations(pSetting *provider.Setting) []string {
+	locations := []string{}
+	for _, loc := range strings.Split(pSetting.GetParam(XCustomSettingFields.MessagesLocation), ",") {
+		loc = strings.TrimSpace(loc)
+		if len(loc) != 0 {
+			locations = append(locations, loc)
+		}
+	}
+
+	return locations
+}
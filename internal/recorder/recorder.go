@@ -1,8 +1,13 @@
 package recorder
 
 import (
+	"errors"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/credit"
 	"github.com/bricks-cloud/bricksllm/internal/event"
 	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/util"
 )
 
 type Recorder struct {
@@ -13,12 +18,18 @@ type Recorder struct {
 	ce            CostEstimator
 	es            EventsStore
 	reqLimitStore Store
+	ic            IdempotencyCache
+	cs            CreditStore
 }
 
 type EventsStore interface {
 	InsertEvent(e *event.Event) error
 }
 
+type CreditStore interface {
+	DebitCredits(t *credit.Transaction) error
+}
+
 type Store interface {
 	IncrementCounter(keyId string, incr int64) error
 }
@@ -27,12 +38,19 @@ type Cache interface {
 	IncrementCounter(keyId string, rateLimitUnit key.TimeUnit, incr int64) error
 }
 
+// IdempotencyCache keeps track of the operations that have already been
+// recorded for an event so that redelivered events are not counted twice.
+type IdempotencyCache interface {
+	Claim(key string) (bool, error)
+	Release(key string) error
+}
+
 type CostEstimator interface {
 	EstimatePromptCost(model string, tks int) (float64, error)
 	EstimateCompletionCost(model string, tks int) (float64, error)
 }
 
-func NewRecorder(s, us Store, c, uc Cache, ce CostEstimator, es EventsStore, reqLimitStore Store) *Recorder {
+func NewRecorder(s, us Store, c, uc Cache, ce CostEstimator, es EventsStore, reqLimitStore Store, ic IdempotencyCache, cs CreditStore) *Recorder {
 	return &Recorder{
 		s:             s,
 		c:             c,
@@ -41,17 +59,54 @@ func NewRecorder(s, us Store, c, uc Cache, ce CostEstimator, es EventsStore, req
 		ce:            ce,
 		es:            es,
 		reqLimitStore: reqLimitStore,
+		ic:            ic,
+		cs:            cs,
 	}
 }
 
-func (r *Recorder) RecordUserSpend(userId string, micros int64, costLimitUnit key.TimeUnit) error {
-	err := r.us.IncrementCounter(userId, micros)
+// Once runs record unless the operation has already been claimed for the
+// given event. The operation is claimed before record runs, so that an event
+// reclaimed while it is still being processed is not counted twice, and the
+// claim is released when record fails so that the event can be retried.
+func (r *Recorder) Once(eventId, operation string, record func() error) error {
+	if r.ic == nil || len(eventId) == 0 {
+		return record()
+	}
+
+	key := eventId + ":" + operation
+	claimed, err := r.ic.Claim(key)
+	if err != nil {
+		return err
+	}
+
+	if !claimed {
+		return nil
+	}
+
+	err = record()
+	if err != nil {
+		if rerr := r.ic.Release(key); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *Recorder) RecordUserSpend(eventId, userId string, micros int64, costLimitUnit key.TimeUnit) error {
+	err := r.Once(eventId, "user_spend", func() error {
+		return r.us.IncrementCounter(userId, micros)
+	})
 	if err != nil {
 		return err
 	}
 
 	if len(costLimitUnit) != 0 {
-		err = r.uc.IncrementCounter(userId, costLimitUnit, int64(micros))
+		err = r.Once(eventId, "user_spend_limit", func() error {
+			return r.uc.IncrementCounter(userId, costLimitUnit, int64(micros))
+		})
 		if err != nil {
 			return err
 		}
@@ -60,14 +115,30 @@ func (r *Recorder) RecordUserSpend(userId string, micros int64, costLimitUnit ke
 	return nil
 }
 
-func (r *Recorder) RecordKeySpend(keyId string, micros int64, costLimitUnit key.TimeUnit) error {
-	err := r.s.IncrementCounter(keyId, micros)
+// RecordKeySpend adds the spend of an event to the counters of its key. The
+// spend is also added to the per model counter of the key when the key limits
+// the cost of the model, which is the name the limit is configured under.
+func (r *Recorder) RecordKeySpend(eventId, keyId, model string, micros int64, costLimitUnit, modelCostLimitUnit key.TimeUnit) error {
+	err := r.Once(eventId, "key_spend", func() error {
+		return r.s.IncrementCounter(keyId, micros)
+	})
 	if err != nil {
 		return err
 	}
 
 	if len(costLimitUnit) != 0 {
-		err = r.c.IncrementCounter(keyId, costLimitUnit, int64(micros))
+		err = r.Once(eventId, "key_spend_limit", func() error {
+			return r.c.IncrementCounter(keyId, costLimitUnit, int64(micros))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(model) != 0 && len(modelCostLimitUnit) != 0 {
+		err = r.Once(eventId, "key_model_spend_limit", func() error {
+			return r.c.IncrementCounter(key.GetModelLimitCounterId(keyId, model), modelCostLimitUnit, micros)
+		})
 		if err != nil {
 			return err
 		}
@@ -76,8 +147,32 @@ func (r *Recorder) RecordKeySpend(keyId string, micros int64, costLimitUnit key.
 	return nil
 }
 
-func (r *Recorder) RecordKeyRequestSpent(keyId string) error {
-	return r.reqLimitStore.IncrementCounter(keyId, 1)
+// RecordBudgetSpend adds the spend of an event to the cost limit counter of a
+// budget that covers the key of the event.
+func (r *Recorder) RecordBudgetSpend(eventId, budgetId string, micros int64, costLimitUnit key.TimeUnit) error {
+	return r.Once(eventId, "budget_spend_limit:"+budgetId, func() error {
+		return r.c.IncrementCounter(budgetId, costLimitUnit, micros)
+	})
+}
+
+// RecordUserDebit debits the cost of an event from the prepaid credit balance
+// of a user. The ledger keeps a single debit per event, so redelivered events
+// are not debited twice.
+func (r *Recorder) RecordUserDebit(eventId, userId string, costInUsd float64) error {
+	return r.cs.DebitCredits(&credit.Transaction{
+		Id:          util.NewUuid(),
+		UserId:      userId,
+		Type:        credit.DebitTransactionType,
+		AmountInUsd: -costInUsd,
+		EventId:     eventId,
+		CreatedAt:   time.Now().Unix(),
+	})
+}
+
+func (r *Recorder) RecordKeyRequestSpent(eventId, keyId string) error {
+	return r.Once(eventId, "key_request", func() error {
+		return r.reqLimitStore.IncrementCounter(keyId, 1)
+	})
 }
 
 func (r *Recorder) RecordEvent(e *event.Event) error {
@@ -0,0 +1,53 @@
+package recorder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryIdempotencyCache struct {
+	keys map[string]bool
+}
+
+func (c *memoryIdempotencyCache) Claim(key string) (bool, error) {
+	if c.keys[key] {
+		return false, nil
+	}
+
+	c.keys[key] = true
+	return true, nil
+}
+
+func (c *memoryIdempotencyCache) Release(key string) error {
+	delete(c.keys, key)
+	return nil
+}
+
+func TestRecorder_Once(t *testing.T) {
+	ic := &memoryIdempotencyCache{keys: map[string]bool{}}
+	r := NewRecorder(nil, nil, nil, nil, nil, nil, nil, ic, nil)
+
+	calls := 0
+	record := func() error {
+		calls++
+		return nil
+	}
+
+	assert.NoError(t, r.Once("event", "key_tokens", record))
+	assert.NoError(t, r.Once("event", "key_tokens", record))
+	assert.Equal(t, 1, calls, "a redelivered event must not be recorded twice")
+
+	assert.NoError(t, r.Once("event", "user_tokens", record))
+	assert.Equal(t, 2, calls, "operations are claimed separately")
+
+	failed := errors.New("redis is down")
+	assert.ErrorIs(t, r.Once("other", "key_tokens", func() error { return failed }), failed)
+	assert.NoError(t, r.Once("other", "key_tokens", record))
+	assert.Equal(t, 3, calls, "a failed operation must be released for the retry")
+
+	assert.NoError(t, r.Once("", "key_tokens", record))
+	assert.NoError(t, r.Once("", "key_tokens", record))
+	assert.Equal(t, 5, calls, "events without an id are not deduplicated")
+}
@@ -1,6 +1,7 @@
 package route
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -25,9 +26,21 @@ type recorder interface {
 	RecordEvent(e *event.Event) error
 }
 
+const (
+	CacheModeExact    = "exact"
+	CacheModeSemantic = "semantic"
+)
+
 type CacheConfig struct {
-	Enabled bool   `json:"enabled"`
-	Ttl     string `json:"ttl"`
+	Enabled             bool    `json:"enabled"`
+	Ttl                 string  `json:"ttl"`
+	Mode                string  `json:"mode"`
+	SimilarityThreshold float64 `json:"similarityThreshold"`
+	EmbeddingModel      string  `json:"embeddingModel"`
+}
+
+func (cc *CacheConfig) IsSemantic() bool {
+	return cc != nil && cc.Enabled && cc.Mode == CacheModeSemantic
 }
 
 type Step struct {
@@ -325,6 +338,12 @@ func (r *Route) RunStepsV2(req *Request, rec recorder, log *zap.Logger, kc *key.
 				return err
 			}
 
+			if req.Stream {
+				hreq.Header.Set("Accept", "text/event-stream")
+				hreq.Header.Set("Cache-Control", "no-cache")
+				hreq.Header.Set("Connection", "keep-alive")
+			}
+
 			res, err := req.Client.Do(hreq)
 			if err != nil {
 				return err
@@ -348,6 +367,23 @@ func (r *Route) RunStepsV2(req *Request, rec recorder, log *zap.Logger, kc *key.
 				return errors.New("response is not okay")
 			}
 
+			if req.Stream {
+				// a stream that fails before sending its first byte can still
+				// be retried or handed over to the next step.
+				buffered := bufio.NewReader(res.Body)
+				if _, err := buffered.Peek(1); err != nil {
+					res.Body.Close()
+					response.Response = nil
+					evt.Status = http.StatusBadGateway
+					return err
+				}
+
+				res.Body = &bufferedReadCloser{
+					Reader: buffered,
+					Closer: res.Body,
+				}
+			}
+
 			if kc.ShouldLogResponse {
 				evt.Response = body
 			}
@@ -623,6 +659,12 @@ type Request struct {
 	PolicyId      string
 	Action        string
 	CorrelationId string
+	Stream        bool
+}
+
+type bufferedReadCloser struct {
+	*bufio.Reader
+	io.Closer
 }
 
 func (r *Request) GetSettingValue(provider string, param string) (string, error) {
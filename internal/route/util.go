@@ -2,6 +2,7 @@ package route
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/bricks-cloud/bricksllm/internal/hasher"
 	"github.com/bricks-cloud/bricksllm/internal/util"
@@ -36,3 +37,65 @@ func ComputeCacheKeyForChatCompletionRequest(path string, req *goopenai.ChatComp
 
 	return hasher.Hash(fmt.Sprintf("%s-%s-%s", path, input, req.User))
 }
+
+// GetSemanticCacheNamespace scopes semantic cache entries of a route to the
+// key and the end user of a request, the same way the exact match cache key
+// includes the user. The system prompt and the turns before the last user
+// message are hashed into the namespace, so that only questions asked in the
+// exact same context are compared with each other.
+func GetSemanticCacheNamespace(routeId, keyId string, req *goopenai.ChatCompletionRequest) string {
+	if req == nil {
+		return fmt.Sprintf("%s:%s::", routeId, keyId)
+	}
+
+	var sb strings.Builder
+	last := getLastUserMessageIndex(req.Messages)
+	for i, m := range req.Messages {
+		if i == last {
+			continue
+		}
+
+		sb.WriteString(m.Role)
+		sb.WriteString(": ")
+		sb.WriteString(getMessageText(m))
+		sb.WriteString("\n")
+	}
+
+	return fmt.Sprintf("%s:%s:%s:%s", routeId, keyId, req.User, hasher.Hash(sb.String()))
+}
+
+// GetSemanticCacheInput returns the text embedded for semantic cache lookups,
+// which is the last user message of the conversation.
+func GetSemanticCacheInput(req *goopenai.ChatCompletionRequest) string {
+	if req == nil {
+		return ""
+	}
+
+	last := getLastUserMessageIndex(req.Messages)
+	if last == -1 {
+		return ""
+	}
+
+	return getMessageText(req.Messages[last])
+}
+
+func getLastUserMessageIndex(messages []goopenai.ChatCompletionMessage) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == goopenai.ChatMessageRoleUser {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func getMessageText(m goopenai.ChatCompletionMessage) string {
+	content := m.Content
+	for _, part := range m.MultiContent {
+		if part.Type == goopenai.ChatMessagePartTypeText {
+			content += part.Text
+		}
+	}
+
+	return content
+}
@@ -0,0 +1,121 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/hasher"
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSemanticCacheNamespace(t *testing.T) {
+	tutor := []goopenai.ChatCompletionMessage{
+		{Role: goopenai.ChatMessageRoleSystem, Content: "you are a tutor"},
+		{Role: goopenai.ChatMessageRoleUser, Content: "what is a prime number?"},
+	}
+
+	tests := []struct {
+		name     string
+		keyId    string
+		req      *goopenai.ChatCompletionRequest
+		expected string
+	}{
+		{
+			name:     "system prompt",
+			keyId:    "key",
+			req:      &goopenai.ChatCompletionRequest{User: "alice", Messages: tutor},
+			expected: "route:key:alice:" + hasher.Hash("system: you are a tutor\n"),
+		},
+		{
+			name:  "last user message is left out",
+			keyId: "key",
+			req: &goopenai.ChatCompletionRequest{User: "alice", Messages: []goopenai.ChatCompletionMessage{
+				{Role: goopenai.ChatMessageRoleSystem, Content: "you are a tutor"},
+				{Role: goopenai.ChatMessageRoleUser, Content: "what is an even number?"},
+			}},
+			expected: "route:key:alice:" + hasher.Hash("system: you are a tutor\n"),
+		},
+		{
+			name:  "earlier turns",
+			keyId: "key",
+			req: &goopenai.ChatCompletionRequest{Messages: []goopenai.ChatCompletionMessage{
+				{Role: goopenai.ChatMessageRoleUser, Content: "hi"},
+				{Role: goopenai.ChatMessageRoleAssistant, Content: "hello"},
+				{Role: goopenai.ChatMessageRoleUser, Content: "what is a prime number?"},
+			}},
+			expected: "route:key::" + hasher.Hash("user: hi\nassistant: hello\n"),
+		},
+		{
+			name:     "other key",
+			keyId:    "other",
+			req:      &goopenai.ChatCompletionRequest{User: "alice", Messages: tutor},
+			expected: "route:other:alice:" + hasher.Hash("system: you are a tutor\n"),
+		},
+		{
+			name:     "no request",
+			keyId:    "key",
+			req:      nil,
+			expected: "route:key::",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, GetSemanticCacheNamespace("route", tt.keyId, tt.req))
+		})
+	}
+}
+
+func TestGetSemanticCacheInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *goopenai.ChatCompletionRequest
+		expected string
+	}{
+		{
+			name: "last user message",
+			req: &goopenai.ChatCompletionRequest{
+				Messages: []goopenai.ChatCompletionMessage{
+					{Role: goopenai.ChatMessageRoleSystem, Content: "be brief"},
+					{Role: goopenai.ChatMessageRoleUser, Content: "hi"},
+					{Role: goopenai.ChatMessageRoleAssistant, Content: "hello"},
+					{Role: goopenai.ChatMessageRoleUser, Content: "how are you?"},
+				},
+			},
+			expected: "how are you?",
+		},
+		{
+			name: "text parts",
+			req: &goopenai.ChatCompletionRequest{
+				Messages: []goopenai.ChatCompletionMessage{
+					{Role: goopenai.ChatMessageRoleUser, MultiContent: []goopenai.ChatMessagePart{
+						{Type: goopenai.ChatMessagePartTypeText, Text: "describe "},
+						{Type: goopenai.ChatMessagePartTypeImageURL, ImageURL: &goopenai.ChatMessageImageURL{URL: "https://example.com/a.png"}},
+						{Type: goopenai.ChatMessagePartTypeText, Text: "this"},
+					}},
+				},
+			},
+			expected: "describe this",
+		},
+		{
+			name: "no user message",
+			req: &goopenai.ChatCompletionRequest{
+				Messages: []goopenai.ChatCompletionMessage{
+					{Role: goopenai.ChatMessageRoleSystem, Content: "be brief"},
+				},
+			},
+			expected: "",
+		},
+		{
+			name:     "no request",
+			req:      nil,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, GetSemanticCacheInput(tt.req))
+		})
+	}
+}
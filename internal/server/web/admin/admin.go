@@ -70,7 +70,7 @@ type AdminServer struct {
 	m      KeyManager
 }
 
-func NewAdminServer(log *zap.Logger, mode string, m KeyManager, krm KeyReportingManager, psm ProviderSettingsManager, cpm CustomProvidersManager, rm RouteManager, pm PoliciesManager, um UserManager, adminPass, xCodioSignSecret string) (*AdminServer, error) {
+func NewAdminServer(log *zap.Logger, mode string, m KeyManager, krm KeyReportingManager, psm ProviderSettingsManager, cpm CustomProvidersManager, rm RouteManager, pm PoliciesManager, um UserManager, prm PricingManager, rpm RepricingManager, bm BudgetManager, crm CreditManager, adminPass, xCodioSignSecret string) (*AdminServer, error) {
 	router := gin.New()
 
 	prod := mode == "production"
@@ -120,6 +120,23 @@ func NewAdminServer(log *zap.Logger, mode string, m KeyManager, krm KeyReporting
 	router.PATCH("/api/users/:id", getUpdateUserHandler(um, prod))
 	router.PATCH("/api/users", getUpdateUserViaTagsAndUserIdHandler(um, prod))
 	router.GET("/api/users", getGetUsersHandler(um, prod))
+	router.POST("/api/users/:id/credits", getCreateCreditTransactionHandler(crm, prod))
+	router.GET("/api/users/:id/credits", getGetCreditsHandler(crm, prod))
+
+	router.POST("/api/pricing/prices", getCreatePriceHandler(prm, prod))
+	router.GET("/api/pricing/prices", getGetPricesHandler(prm, prod))
+	router.GET("/api/pricing/prices/:id", getGetPriceHandler(prm, prod))
+	router.PATCH("/api/pricing/prices/:id", getUpdatePriceHandler(prm, prod))
+	router.DELETE("/api/pricing/prices/:id", getDeletePriceHandler(prm, prod))
+	router.POST("/api/pricing/repricing-jobs", getCreateRepricingJobHandler(rpm, prod))
+	router.GET("/api/pricing/repricing-jobs", getGetRepricingJobsHandler(rpm))
+	router.GET("/api/pricing/repricing-jobs/:id", getGetRepricingJobHandler(rpm, prod))
+
+	router.POST("/api/budgets", getCreateBudgetHandler(bm, prod))
+	router.GET("/api/budgets", getGetBudgetsHandler(bm, prod))
+	router.GET("/api/budgets/:id", getGetBudgetHandler(bm, prod))
+	router.PATCH("/api/budgets/:id", getUpdateBudgetHandler(bm, prod))
+	router.DELETE("/api/budgets/:id", getDeleteBudgetHandler(bm, prod))
 
 	srv := &http.Server{
 		Addr:    ":8001",
@@ -160,7 +177,22 @@ func (as *AdminServer) Run() {
 		as.log.Info("PORT 8001 | POST   | /api/users is set up for creating a user")
 		as.log.Info("PORT 8001 | GET    | /api/users is set up for retrieving users")
 		as.log.Info("PORT 8001 | PATCH  | /api/users is set up for updating a user")
+		as.log.Info("PORT 8001 | POST   | /api/users/:id/credits is set up for topping up or adjusting the credit balance of a user")
+		as.log.Info("PORT 8001 | GET    | /api/users/:id/credits is set up for retrieving the credit balance and transactions of a user")
 		as.log.Info("PORT 8001 | POST   | /api/reporting/top-key-rings is set up retrieving top key rings")
+		as.log.Info("PORT 8001 | POST   | /api/pricing/prices is set up for creating a price")
+		as.log.Info("PORT 8001 | GET    | /api/pricing/prices is set up for retrieving prices")
+		as.log.Info("PORT 8001 | GET    | /api/pricing/prices/:id is set up for retrieving a price")
+		as.log.Info("PORT 8001 | PATCH  | /api/pricing/prices/:id is set up for updating a price")
+		as.log.Info("PORT 8001 | DELETE | /api/pricing/prices/:id is set up for deleting a price")
+		as.log.Info("PORT 8001 | POST   | /api/pricing/repricing-jobs is set up for repricing historical events")
+		as.log.Info("PORT 8001 | GET    | /api/pricing/repricing-jobs is set up for retrieving repricing jobs")
+		as.log.Info("PORT 8001 | GET    | /api/pricing/repricing-jobs/:id is set up for retrieving the progress of a repricing job")
+		as.log.Info("PORT 8001 | POST   | /api/budgets is set up for creating a budget")
+		as.log.Info("PORT 8001 | GET    | /api/budgets is set up for retrieving budgets")
+		as.log.Info("PORT 8001 | GET    | /api/budgets/:id is set up for retrieving a budget")
+		as.log.Info("PORT 8001 | PATCH  | /api/budgets/:id is set up for updating a budget")
+		as.log.Info("PORT 8001 | DELETE | /api/budgets/:id is set up for deleting a budget")
 
 		if err := as.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			as.log.Sugar().Fatalf("error admin server listening: %v", err)
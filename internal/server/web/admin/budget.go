@@ -0,0 +1,376 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/budget"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+type BudgetManager interface {
+	CreateBudget(p *budget.Budget) (*budget.Budget, error)
+	UpdateBudget(id string, ub *budget.UpdateBudget) (*budget.Budget, error)
+	DeleteBudget(id string) error
+	GetBudget(id string) (*budget.Budget, error)
+	GetBudgets(keyRing, tag string) ([]*budget.Budget, error)
+}
+
+func getCreateBudgetHandler(m BudgetManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_create_budget_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_create_budget_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/budgets"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			logError(log, "error when reading create a budget request body", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/request-body-read",
+				Title:    "request body reader error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		b := &budget.Budget{}
+		err = json.Unmarshal(data, b)
+		if err != nil {
+			logError(log, "error when unmarshalling create a budget request body", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/json-unmarshal",
+				Title:    "json unmarshaller error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		created, err := m.CreateBudget(b)
+		if err != nil {
+			errType := "internal"
+
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_create_budget_handler.create_budget_error", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(validationError); ok {
+				errType = "validation"
+				c.JSON(http.StatusBadRequest, &ErrorResponse{
+					Type:     "/errors/validation",
+					Title:    "budget validation failed",
+					Status:   http.StatusBadRequest,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when creating a budget", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/budget-manager",
+				Title:    "creating a budget error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_create_budget_handler.success", nil, 1)
+		c.JSON(http.StatusOK, created)
+	}
+}
+
+func getUpdateBudgetHandler(m BudgetManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_update_budget_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_update_budget_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/budgets/:id"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			logError(log, "error when reading update a budget request body", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/request-body-read",
+				Title:    "request body reader error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		ub := &budget.UpdateBudget{}
+		err = json.Unmarshal(data, ub)
+		if err != nil {
+			logError(log, "error when unmarshalling update a budget request body", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/json-unmarshal",
+				Title:    "json unmarshaller error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		updated, err := m.UpdateBudget(c.Param("id"), ub)
+		if err != nil {
+			errType := "internal"
+
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_update_budget_handler.update_budget_error", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(validationError); ok {
+				errType = "validation"
+				c.JSON(http.StatusBadRequest, &ErrorResponse{
+					Type:     "/errors/validation",
+					Title:    "budget validation failed",
+					Status:   http.StatusBadRequest,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			if _, ok := err.(notFoundError); ok {
+				errType = "not_found"
+				c.JSON(http.StatusNotFound, &ErrorResponse{
+					Type:     "/errors/budget-not-found",
+					Title:    "budget not found error",
+					Status:   http.StatusNotFound,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when updating a budget", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/budget-manager",
+				Title:    "updating a budget error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_update_budget_handler.success", nil, 1)
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+func getDeleteBudgetHandler(m BudgetManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_delete_budget_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_delete_budget_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/budgets/:id"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		err := m.DeleteBudget(c.Param("id"))
+		if err != nil {
+			errType := "internal"
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_delete_budget_handler.delete_budget_err", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(notFoundError); ok {
+				errType = "not_found"
+
+				logError(log, "budget not found", prod, err)
+				c.JSON(http.StatusNotFound, &ErrorResponse{
+					Type:     "/errors/budget-not-found",
+					Title:    "budget not found error",
+					Status:   http.StatusNotFound,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when deleting a budget", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/budget-manager",
+				Title:    "deleting a budget error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_delete_budget_handler.success", nil, 1)
+		c.Status(http.StatusOK)
+	}
+}
+
+func getGetBudgetHandler(m BudgetManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_get_budget_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_get_budget_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/budgets/:id"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		b, err := m.GetBudget(c.Param("id"))
+		if err != nil {
+			errType := "internal"
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_get_budget_handler.get_budget_err", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(notFoundError); ok {
+				errType = "not_found"
+
+				logError(log, "budget not found", prod, err)
+				c.JSON(http.StatusNotFound, &ErrorResponse{
+					Type:     "/errors/budget-not-found",
+					Title:    "budget not found error",
+					Status:   http.StatusNotFound,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when getting a budget", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/budget-manager",
+				Title:    "getting a budget error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_get_budget_handler.success", nil, 1)
+		c.JSON(http.StatusOK, b)
+	}
+}
+
+func getGetBudgetsHandler(m BudgetManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_get_budgets_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_get_budgets_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/budgets"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		bs, err := m.GetBudgets(c.Query("keyRing"), c.Query("tag"))
+		if err != nil {
+			telemetry.Incr("bricksllm.admin.get_get_budgets_handler.get_budgets_err", nil, 1)
+
+			logError(log, "error when getting budgets", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/budget-manager",
+				Title:    "getting budgets error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_get_budgets_handler.success", nil, 1)
+		c.JSON(http.StatusOK, bs)
+	}
+}
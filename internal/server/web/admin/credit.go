@@ -0,0 +1,217 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/credit"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+type CreditManager interface {
+	CreateTransaction(userId string, ct *credit.CreateTransaction) (*credit.Transaction, error)
+	GetCredits(userId string, offset, limit int) (*credit.Credits, error)
+}
+
+func getCreateCreditTransactionHandler(m CreditManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_create_credit_transaction_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_create_credit_transaction_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/users/:id/credits"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			logError(log, "error when reading create a credit transaction request body", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/request-body-read",
+				Title:    "request body reader error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		ct := &credit.CreateTransaction{}
+		err = json.Unmarshal(data, ct)
+		if err != nil {
+			logError(log, "error when unmarshalling create a credit transaction request body", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/json-unmarshal",
+				Title:    "json unmarshaller error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		created, err := m.CreateTransaction(c.Param("id"), ct)
+		if err != nil {
+			errType := "internal"
+
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_create_credit_transaction_handler.create_transaction_error", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(validationError); ok {
+				errType = "validation"
+				c.JSON(http.StatusBadRequest, &ErrorResponse{
+					Type:     "/errors/validation",
+					Title:    "credit transaction validation failed",
+					Status:   http.StatusBadRequest,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			if _, ok := err.(notFoundError); ok {
+				errType = "not_found"
+				c.JSON(http.StatusNotFound, &ErrorResponse{
+					Type:     "/errors/user-not-found",
+					Title:    "user not found error",
+					Status:   http.StatusNotFound,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when creating a credit transaction", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/credit-manager",
+				Title:    "creating a credit transaction error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_create_credit_transaction_handler.success", nil, 1)
+		c.JSON(http.StatusOK, created)
+	}
+}
+
+func getGetCreditsHandler(m CreditManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_get_credits_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_get_credits_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/users/:id/credits"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		offset := 0
+		offsetStr, ok := c.GetQuery("offset")
+		if ok {
+			parsed, err := strconv.Atoi(offsetStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, &ErrorResponse{
+					Type:     "/errors/bad-filters",
+					Title:    "bad offset query param",
+					Status:   http.StatusBadRequest,
+					Detail:   "offset query param cannot be converted to integer",
+					Instance: path,
+				})
+				return
+			}
+
+			offset = parsed
+		}
+
+		limit := 0
+		limitStr, ok := c.GetQuery("limit")
+		if ok {
+			parsed, err := strconv.Atoi(limitStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, &ErrorResponse{
+					Type:     "/errors/bad-filters",
+					Title:    "bad limit query param",
+					Status:   http.StatusBadRequest,
+					Detail:   "limit query param cannot be converted to integer",
+					Instance: path,
+				})
+				return
+			}
+
+			limit = parsed
+		}
+
+		credits, err := m.GetCredits(c.Param("id"), offset, limit)
+		if err != nil {
+			errType := "internal"
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_get_credits_handler.get_credits_err", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(notFoundError); ok {
+				errType = "not_found"
+
+				logError(log, "user not found", prod, err)
+				c.JSON(http.StatusNotFound, &ErrorResponse{
+					Type:     "/errors/user-not-found",
+					Title:    "user not found error",
+					Status:   http.StatusNotFound,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when getting credits", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/credit-manager",
+				Title:    "getting credits error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_get_credits_handler.success", nil, 1)
+		c.JSON(http.StatusOK, credits)
+	}
+}
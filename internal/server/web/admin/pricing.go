@@ -0,0 +1,521 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/pricing"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+type PricingManager interface {
+	CreatePrice(p *pricing.Price) (*pricing.Price, error)
+	UpdatePrice(id string, p *pricing.UpdatePrice) (*pricing.Price, error)
+	DeletePrice(id string) error
+	GetPrice(id string) (*pricing.Price, error)
+	GetPrices(provider, model string) ([]*pricing.Price, error)
+}
+
+func getCreatePriceHandler(m PricingManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_create_price_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_create_price_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/pricing/prices"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			logError(log, "error when reading create a price request body", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/request-body-read",
+				Title:    "request body reader error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		p := &pricing.Price{}
+		err = json.Unmarshal(data, p)
+		if err != nil {
+			logError(log, "error when unmarshalling create a price request body", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/json-unmarshal",
+				Title:    "json unmarshaller error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		created, err := m.CreatePrice(p)
+		if err != nil {
+			errType := "internal"
+
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_create_price_handler.create_price_error", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(validationError); ok {
+				errType = "validation"
+				c.JSON(http.StatusBadRequest, &ErrorResponse{
+					Type:     "/errors/validation",
+					Title:    "price validation failed",
+					Status:   http.StatusBadRequest,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when creating a price", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/pricing-manager",
+				Title:    "creating a price error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_create_price_handler.success", nil, 1)
+		c.JSON(http.StatusOK, created)
+	}
+}
+
+func getUpdatePriceHandler(m PricingManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_update_price_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_update_price_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/pricing/prices/:id"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			logError(log, "error when reading update a price request body", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/request-body-read",
+				Title:    "request body reader error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		p := &pricing.UpdatePrice{}
+		err = json.Unmarshal(data, p)
+		if err != nil {
+			logError(log, "error when unmarshalling update a price request body", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/json-unmarshal",
+				Title:    "json unmarshaller error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		updated, err := m.UpdatePrice(c.Param("id"), p)
+		if err != nil {
+			errType := "internal"
+
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_update_price_handler.update_price_error", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(validationError); ok {
+				errType = "validation"
+				c.JSON(http.StatusBadRequest, &ErrorResponse{
+					Type:     "/errors/validation",
+					Title:    "price validation failed",
+					Status:   http.StatusBadRequest,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			if _, ok := err.(notFoundError); ok {
+				errType = "not_found"
+				c.JSON(http.StatusNotFound, &ErrorResponse{
+					Type:     "/errors/price-not-found",
+					Title:    "price not found error",
+					Status:   http.StatusNotFound,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when updating a price", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/pricing-manager",
+				Title:    "updating a price error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_update_price_handler.success", nil, 1)
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+func getDeletePriceHandler(m PricingManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_delete_price_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_delete_price_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/pricing/prices/:id"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		err := m.DeletePrice(c.Param("id"))
+		if err != nil {
+			errType := "internal"
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_delete_price_handler.delete_price_err", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(notFoundError); ok {
+				errType = "not_found"
+
+				logError(log, "price not found", prod, err)
+				c.JSON(http.StatusNotFound, &ErrorResponse{
+					Type:     "/errors/price-not-found",
+					Title:    "price not found error",
+					Status:   http.StatusNotFound,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when deleting a price", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/pricing-manager",
+				Title:    "deleting a price error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_delete_price_handler.success", nil, 1)
+		c.Status(http.StatusOK)
+	}
+}
+
+func getGetPriceHandler(m PricingManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_get_price_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_get_price_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/pricing/prices/:id"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		p, err := m.GetPrice(c.Param("id"))
+		if err != nil {
+			errType := "internal"
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_get_price_handler.get_price_err", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(notFoundError); ok {
+				errType = "not_found"
+
+				logError(log, "price not found", prod, err)
+				c.JSON(http.StatusNotFound, &ErrorResponse{
+					Type:     "/errors/price-not-found",
+					Title:    "price not found error",
+					Status:   http.StatusNotFound,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when getting a price", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/pricing-manager",
+				Title:    "getting a price error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_get_price_handler.success", nil, 1)
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+func getGetPricesHandler(m PricingManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_get_prices_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_get_prices_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/pricing/prices"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		ps, err := m.GetPrices(c.Query("provider"), c.Query("model"))
+		if err != nil {
+			telemetry.Incr("bricksllm.admin.get_get_prices_handler.get_prices_err", nil, 1)
+
+			logError(log, "error when getting prices", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/pricing-manager",
+				Title:    "getting prices error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_get_prices_handler.success", nil, 1)
+		c.JSON(http.StatusOK, ps)
+	}
+}
+
+type RepricingManager interface {
+	CreateRepricingJob(r *pricing.RepricingRequest) (*pricing.RepricingJob, error)
+	GetRepricingJob(id string) (*pricing.RepricingJob, error)
+	GetRepricingJobs() []*pricing.RepricingJob
+}
+
+func getCreateRepricingJobHandler(m RepricingManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_create_repricing_job_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_create_repricing_job_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/pricing/repricing-jobs"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			logError(log, "error when reading create a repricing job request body", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/request-body-read",
+				Title:    "request body reader error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		r := &pricing.RepricingRequest{}
+		err = json.Unmarshal(data, r)
+		if err != nil {
+			logError(log, "error when unmarshalling create a repricing job request body", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/json-unmarshal",
+				Title:    "json unmarshaller error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		job, err := m.CreateRepricingJob(r)
+		if err != nil {
+			errType := "internal"
+
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_create_repricing_job_handler.create_repricing_job_error", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(validationError); ok {
+				errType = "validation"
+				c.JSON(http.StatusBadRequest, &ErrorResponse{
+					Type:     "/errors/validation",
+					Title:    "repricing request validation failed",
+					Status:   http.StatusBadRequest,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when creating a repricing job", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/repricing-manager",
+				Title:    "creating a repricing job error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_create_repricing_job_handler.success", nil, 1)
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+func getGetRepricingJobHandler(m RepricingManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_get_repricing_job_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_get_repricing_job_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/pricing/repricing-jobs/:id"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		job, err := m.GetRepricingJob(c.Param("id"))
+		if err != nil {
+			telemetry.Incr("bricksllm.admin.get_get_repricing_job_handler.get_repricing_job_err", nil, 1)
+
+			logError(log, "repricing job not found", prod, err)
+			c.JSON(http.StatusNotFound, &ErrorResponse{
+				Type:     "/errors/repricing-job-not-found",
+				Title:    "repricing job not found error",
+				Status:   http.StatusNotFound,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_get_repricing_job_handler.success", nil, 1)
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+func getGetRepricingJobsHandler(m RepricingManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		telemetry.Incr("bricksllm.admin.get_get_repricing_jobs_handler.requests", nil, 1)
+
+		c.JSON(http.StatusOK, m.GetRepricingJobs())
+	}
+}
@@ -26,6 +26,8 @@ type anthropicEstimator interface {
 	EstimatePromptCost(model string, tks int) (float64, error)
 	Count(input string) int
 	CountMessagesTokens(messages []anthropic.Message) int
+	CountMessagesRequestTokens(mr *anthropic.MessagesRequest) int
+	EstimateCachedPromptCost(model string, cacheCreationTks, cacheReadTks int) (float64, error)
 }
 
 func copyHttpHeaders(source *http.Request, dest *http.Request, removeUseAgent bool) {
@@ -387,11 +389,22 @@ func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstim
 					telemetry.Incr("bricksllm.proxy.get_messages_handler.estimate_total_cost_error", nil, 1)
 					logError(log, "error when estimating anthropic cost", prod, err)
 				}
+
+				cachedCost, err := e.EstimateCachedPromptCost(model, completionRes.Usage.CacheCreationInputTokens, completionRes.Usage.CacheReadInputTokens)
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_messages_handler.estimate_cached_prompt_cost_error", nil, 1)
+					logError(log, "error when estimating anthropic cached prompt cost", prod, err)
+				}
+
+				cost += cachedCost
+				promptTokens += completionRes.Usage.CacheCreationInputTokens + completionRes.Usage.CacheReadInputTokens
 			}
 
 			c.Set("costInUsd", cost)
 			c.Set("promptTokenCount", promptTokens)
 			c.Set("completionTokenCount", completionTokens)
+			c.Set("cachedPromptTokenCount", completionRes.Usage.CacheReadInputTokens)
+			c.Set("cacheCreationTokenCount", completionRes.Usage.CacheCreationInputTokens)
 
 			c.Data(res.StatusCode, "application/json", bytes)
 			return
@@ -437,11 +450,19 @@ func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstim
 				logError(log, "error when estimating anthropic prompt cost", prod, err)
 			}
 
-			totalCost = cost + estimatedPromptCost
+			cachedCost, err := e.EstimateCachedPromptCost(model, response.Usage.CacheCreationInputTokens, response.Usage.CacheReadInputTokens)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_messages_handler.estimate_cached_prompt_cost_error", nil, 1)
+				logError(log, "error when estimating anthropic cached prompt cost", prod, err)
+			}
+
+			totalCost = cost + estimatedPromptCost + cachedCost
 
 			c.Set("costInUsd", totalCost)
-			c.Set("promptTokenCount", response.Usage.InputTokens)
+			c.Set("promptTokenCount", response.Usage.InputTokens+response.Usage.CacheCreationInputTokens+response.Usage.CacheReadInputTokens)
 			c.Set("completionTokenCount", tks)
+			c.Set("cachedPromptTokenCount", response.Usage.CacheReadInputTokens)
+			c.Set("cacheCreationTokenCount", response.Usage.CacheCreationInputTokens)
 		}()
 
 		telemetry.Incr("bricksllm.proxy.get_messages_handler.streaming_requests", nil, 1)
@@ -548,6 +569,8 @@ func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstim
 				}
 
 				response.Usage.InputTokens = messageStart.Message.Usage.InputTokens
+				response.Usage.CacheCreationInputTokens = messageStart.Message.Usage.CacheCreationInputTokens
+				response.Usage.CacheReadInputTokens = messageStart.Message.Usage.CacheReadInputTokens
 			}
 
 			if eventName == " message_delta" {
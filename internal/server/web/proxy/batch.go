@@ -0,0 +1,353 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/batch"
+	"github.com/bricks-cloud/bricksllm/internal/event"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/message"
+	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/gin-gonic/gin"
+	goopenai "github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+type batchStorage interface {
+	CreateBatch(b *batch.Batch) error
+	GetBatch(id string) (*batch.Batch, error)
+	MarkBatchRecorded(id, status string, costInUsd float64, recordedAt int64) (bool, error)
+}
+
+func getCreateBatchHandler(prod bool, client http.Client, bs batchStorage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.proxy.get_create_batch_handler.requests", nil, 1)
+
+		if c == nil || c.Request == nil {
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] context is empty")
+			return
+		}
+
+		raw, exists := c.Get("key")
+		kc, ok := raw.(*key.ResponseKey)
+		if !exists || !ok {
+			telemetry.Incr("bricksllm.proxy.get_create_batch_handler.api_key_not_registered", nil, 1)
+			JSON(c, http.StatusUnauthorized, "[BricksLLM] api key is not registered")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.GetDuration("requestTimeout"))
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/batches", c.Request.Body)
+		if err != nil {
+			logError(log, "error when creating openai http request", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to create openai http request")
+			return
+		}
+
+		copyHttpHeaders(c.Request, req, c.GetBool("removeUserAgent"))
+
+		start := time.Now()
+		res, err := client.Do(req)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_create_batch_handler.http_client_error", nil, 1)
+
+			logError(log, "error when sending http request to openai", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to send http request to openai")
+			return
+		}
+
+		defer res.Body.Close()
+
+		for name, values := range res.Header {
+			for _, value := range values {
+				c.Header(name, value)
+			}
+		}
+
+		dur := time.Since(start)
+		telemetry.Timing("bricksllm.proxy.get_create_batch_handler.latency", dur, nil, 1)
+
+		bytes, err := io.ReadAll(res.Body)
+		if err != nil {
+			logError(log, "error when reading openai http batch response body", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read openai response body")
+			return
+		}
+
+		if res.StatusCode == http.StatusOK {
+			telemetry.Incr("bricksllm.proxy.get_create_batch_handler.success", nil, 1)
+			telemetry.Timing("bricksllm.proxy.get_create_batch_handler.success_latency", dur, nil, 1)
+
+			b := &openai.Batch{}
+			err = json.Unmarshal(bytes, b)
+			if err != nil {
+				logError(log, "error when unmarshalling openai http batch response body", prod, err)
+			}
+
+			if err == nil && len(b.Id) != 0 {
+				err = bs.CreateBatch(&batch.Batch{
+					Id:        b.Id,
+					KeyId:     kc.KeyId,
+					UserId:    c.GetString("userId"),
+					CreatedAt: time.Now().Unix(),
+					UpdatedAt: time.Now().Unix(),
+					Endpoint:  b.Endpoint,
+					Status:    b.Status,
+				})
+
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_create_batch_handler.create_batch_error", nil, 1)
+					logError(log, "error when storing openai batch", prod, err)
+				}
+			}
+
+			c.Data(res.StatusCode, "application/json", bytes)
+			return
+		}
+
+		telemetry.Timing("bricksllm.proxy.get_create_batch_handler.error_latency", dur, nil, 1)
+		telemetry.Incr("bricksllm.proxy.get_create_batch_handler.error_response", nil, 1)
+
+		errorRes := &goopenai.ErrorResponse{}
+		err = json.Unmarshal(bytes, errorRes)
+		if err != nil {
+			logError(log, "error when unmarshalling openai batch error response body", prod, err)
+		}
+
+		logOpenAiError(log, prod, errorRes)
+
+		c.Data(res.StatusCode, "application/json", bytes)
+	}
+}
+
+// getRetrieveBatchHandler records the spend of a batch against the key that
+// created it the first time the batch is retrieved as completed. The output
+// file is priced in the background so that retrieval is not slowed down.
+func getRetrieveBatchHandler(prod bool, client http.Client, bs batchStorage, km KeyManager, e estimator, pub publisher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.proxy.get_retrieve_batch_handler.requests", nil, 1)
+
+		if c == nil || c.Request == nil {
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] context is empty")
+			return
+		}
+
+		timeout := c.GetDuration("requestTimeout")
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.openai.com/v1/batches/"+c.Param("batch_id"), nil)
+		if err != nil {
+			logError(log, "error when creating openai http request", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to create openai http request")
+			return
+		}
+
+		copyHttpHeaders(c.Request, req, c.GetBool("removeUserAgent"))
+
+		start := time.Now()
+		res, err := client.Do(req)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_retrieve_batch_handler.http_client_error", nil, 1)
+
+			logError(log, "error when sending http request to openai", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to send http request to openai")
+			return
+		}
+
+		defer res.Body.Close()
+
+		for name, values := range res.Header {
+			for _, value := range values {
+				c.Header(name, value)
+			}
+		}
+
+		dur := time.Since(start)
+		telemetry.Timing("bricksllm.proxy.get_retrieve_batch_handler.latency", dur, nil, 1)
+
+		bytes, err := io.ReadAll(res.Body)
+		if err != nil {
+			logError(log, "error when reading openai http batch response body", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read openai response body")
+			return
+		}
+
+		if res.StatusCode == http.StatusOK {
+			telemetry.Incr("bricksllm.proxy.get_retrieve_batch_handler.success", nil, 1)
+			telemetry.Timing("bricksllm.proxy.get_retrieve_batch_handler.success_latency", dur, nil, 1)
+
+			b := &openai.Batch{}
+			err = json.Unmarshal(bytes, b)
+			if err != nil {
+				logError(log, "error when unmarshalling openai http batch response body", prod, err)
+			}
+
+			if err == nil && b.Status == openai.BatchStatusCompleted && len(b.OutputFileId) != 0 {
+				// the request is reused by gin once the handler returns.
+				header := req.Header.Clone()
+				go recordBatchSpend(log, prod, client, header, timeout, b, bs, km, e, pub)
+			}
+
+			c.Data(res.StatusCode, "application/json", bytes)
+			return
+		}
+
+		telemetry.Timing("bricksllm.proxy.get_retrieve_batch_handler.error_latency", dur, nil, 1)
+		telemetry.Incr("bricksllm.proxy.get_retrieve_batch_handler.error_response", nil, 1)
+
+		errorRes := &goopenai.ErrorResponse{}
+		err = json.Unmarshal(bytes, errorRes)
+		if err != nil {
+			logError(log, "error when unmarshalling openai batch error response body", prod, err)
+		}
+
+		logOpenAiError(log, prod, errorRes)
+
+		c.Data(res.StatusCode, "application/json", bytes)
+	}
+}
+
+func recordBatchSpend(log *zap.Logger, prod bool, client http.Client, header http.Header, timeout time.Duration, b *openai.Batch, bs batchStorage, km KeyManager, e estimator, pub publisher) {
+	stored, err := bs.GetBatch(b.Id)
+	if err != nil {
+		if _, ok := err.(notFoundError); ok {
+			telemetry.Incr("bricksllm.proxy.record_batch_spend.batch_not_tracked", nil, 1)
+			return
+		}
+
+		telemetry.Incr("bricksllm.proxy.record_batch_spend.get_batch_error", nil, 1)
+		logError(log, "error when getting openai batch", prod, err)
+		return
+	}
+
+	if stored.RecordedAt != 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.openai.com/v1/files/"+b.OutputFileId+"/content", nil)
+	if err != nil {
+		logError(log, "error when creating openai http request", prod, err)
+		return
+	}
+
+	req.Header = header
+
+	res, err := client.Do(req)
+	if err != nil {
+		telemetry.Incr("bricksllm.proxy.record_batch_spend.http_client_error", nil, 1)
+		logError(log, "error when retrieving openai batch output file", prod, err)
+		return
+	}
+
+	defer res.Body.Close()
+
+	output, err := io.ReadAll(res.Body)
+	if err != nil {
+		logError(log, "error when reading openai batch output file", prod, err)
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		telemetry.Incr("bricksllm.proxy.record_batch_spend.error_response", nil, 1)
+		logError(log, "error when retrieving openai batch output file", prod, fmt.Errorf("status code: %d", res.StatusCode))
+		return
+	}
+
+	endpoint := stored.Endpoint
+	if len(endpoint) == 0 {
+		endpoint = b.Endpoint
+	}
+
+	bc, err := e.EstimateBatchCost(endpoint, output)
+	if err != nil {
+		telemetry.Incr("bricksllm.proxy.record_batch_spend.estimate_batch_cost_error", nil, 1)
+		logError(log, "error when estimating openai batch cost", prod, err)
+		return
+	}
+
+	if bc.UnpricedRequests != 0 {
+		telemetry.Incr("bricksllm.proxy.record_batch_spend.unpriced_requests", nil, 1)
+		logError(log, "error when pricing openai batch requests", prod, fmt.Errorf("%d requests of batch %s cannot be priced", bc.UnpricedRequests, b.Id))
+	}
+
+	kcs, err := km.GetKeys(nil, []string{stored.KeyId}, "")
+	if err != nil {
+		telemetry.Incr("bricksllm.proxy.record_batch_spend.get_keys_error", nil, 1)
+		logError(log, "error when getting the key of an openai batch", prod, err)
+		return
+	}
+
+	if len(kcs) != 1 {
+		telemetry.Incr("bricksllm.proxy.record_batch_spend.key_not_found", nil, 1)
+		return
+	}
+
+	// the batch is marked only once its key is resolved, so that a failed
+	// lookup leaves the batch to be recorded on a later retrieval.
+	recorded, err := bs.MarkBatchRecorded(b.Id, b.Status, bc.CostInUsd, time.Now().Unix())
+	if err != nil {
+		telemetry.Incr("bricksllm.proxy.record_batch_spend.mark_batch_recorded_error", nil, 1)
+		logError(log, "error when marking openai batch as recorded", prod, err)
+		return
+	}
+
+	if !recorded {
+		return
+	}
+
+	// an event is published per model so that spend is attributed to the
+	// models of the batch.
+	for _, usage := range bc.Usages {
+		metadata, err := json.Marshal(map[string]any{
+			"batchId":          b.Id,
+			"endpoint":         endpoint,
+			"numberOfRequests": usage.NumberOfRequests,
+		})
+		if err != nil {
+			metadata = []byte(`{}`)
+		}
+
+		pub.Publish(message.Message{
+			Type: "event",
+			Data: &event.EventWithRequestAndContent{
+				Key: kcs[0],
+				Event: &event.Event{
+					Id:                     util.NewUuid(),
+					CreatedAt:              time.Now().Unix(),
+					Tags:                   kcs[0].Tags,
+					KeyId:                  stored.KeyId,
+					CostInUsd:              usage.CostInUsd,
+					Provider:               "openai",
+					Model:                  usage.Model,
+					Status:                 http.StatusOK,
+					PromptTokenCount:       usage.PromptTokens,
+					CompletionTokenCount:   usage.CompletionTokens,
+					CachedPromptTokenCount: usage.CachedPromptTokens,
+					Path:                   "/api/providers/openai/v1/batches/" + b.Id,
+					Method:                 http.MethodGet,
+					Request:                []byte(`{}`),
+					Response:               []byte(`{}`),
+					UserId:                 stored.UserId,
+					Metadata:               metadata,
+				},
+			},
+		})
+	}
+
+	telemetry.Incr("bricksllm.proxy.record_batch_spend.success", nil, 1)
+}
@@ -78,6 +78,7 @@ func getChatCompletionHandler(prod, private bool, client http.Client, e estimato
 			}
 
 			var cost float64 = 0
+			cachedTokens := 0
 			chatRes := &goopenai.ChatCompletionResponse{}
 			telemetry.Incr("bricksllm.proxy.get_chat_completion_handler.success", nil, 1)
 			telemetry.Timing("bricksllm.proxy.get_chat_completion_handler.success_latency", dur, nil, 1)
@@ -89,7 +90,11 @@ func getChatCompletionHandler(prod, private bool, client http.Client, e estimato
 
 			if err == nil {
 				logChatCompletionResponse(log, prod, private, chatRes)
-				cost, err = e.EstimateTotalCost(model, chatRes.Usage.PromptTokens, chatRes.Usage.CompletionTokens)
+				if chatRes.Usage.PromptTokensDetails != nil {
+					cachedTokens = chatRes.Usage.PromptTokensDetails.CachedTokens
+				}
+
+				cost, err = e.EstimateTotalCostWithCachedTokens(model, chatRes.Usage.PromptTokens, cachedTokens, chatRes.Usage.CompletionTokens)
 				if err != nil {
 					telemetry.Incr("bricksllm.proxy.get_chat_completion_handler.estimate_total_cost_error", nil, 1)
 					logError(log, "error when estimating openai cost", prod, err)
@@ -115,6 +120,7 @@ func getChatCompletionHandler(prod, private bool, client http.Client, e estimato
 			c.Set("costInUsd", cost)
 			c.Set("promptTokenCount", chatRes.Usage.PromptTokens)
 			c.Set("completionTokenCount", chatRes.Usage.CompletionTokens)
+			c.Set("cachedPromptTokenCount", cachedTokens)
 
 			c.Data(res.StatusCode, "application/json", bytes)
 			return
@@ -147,10 +153,48 @@ func getChatCompletionHandler(prod, private bool, client http.Client, e estimato
 		buffer := bufio.NewReader(res.Body)
 		content := ""
 		streamingResponse := [][]byte{}
+		var usage *goopenai.Usage
 		defer func() {
 			c.Set("content", content)
 			c.Set("streaming_response", bytes.Join(streamingResponse, []byte{'\n'}))
 
+			// the usage is only streamed when stream_options.include_usage is
+			// set. Otherwise tokens are estimated when the event is handled.
+			if usage == nil {
+				return
+			}
+
+			cachedTokens := 0
+			if usage.PromptTokensDetails != nil {
+				cachedTokens = usage.PromptTokensDetails.CachedTokens
+			}
+
+			cost, err := e.EstimateTotalCostWithCachedTokens(model, usage.PromptTokens, cachedTokens, usage.CompletionTokens)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_chat_completion_handler.estimate_total_cost_with_cached_tokens_error", nil, 1)
+				logError(log, "error when estimating openai streaming cost", prod, err)
+			}
+
+			m, exists := c.Get("cost_map")
+			if exists {
+				converted, ok := m.(*provider.CostMap)
+				if ok {
+					newCost, err := provider.EstimateTotalCostWithCostMaps(model, usage.PromptTokens, usage.CompletionTokens, 1000, converted.PromptCostPerModel, converted.CompletionCostPerModel)
+					if err != nil {
+						logError(log, "error when estimating openai streaming chat completions total cost with cost maps", prod, err)
+						telemetry.Incr("bricksllm.proxy.get_chat_completion_handler.estimate_streaming_total_cost_with_cost_maps_error", nil, 1)
+					}
+
+					if newCost != 0 {
+						cost = newCost
+					}
+				}
+			}
+
+			c.Set("costInUsd", cost)
+			c.Set("promptTokenCount", usage.PromptTokens)
+			c.Set("completionTokenCount", usage.CompletionTokens)
+			c.Set("cachedPromptTokenCount", cachedTokens)
 		}()
 
 		telemetry.Incr("bricksllm.proxy.get_chat_completion_handler.streaming_requests", nil, 1)
@@ -216,6 +260,10 @@ func getChatCompletionHandler(prod, private bool, client http.Client, e estimato
 				if len(chatCompletionStreamResp.Choices) > 0 && len(chatCompletionStreamResp.Choices[0].Delta.Content) != 0 {
 					content += chatCompletionStreamResp.Choices[0].Delta.Content
 				}
+
+				if chatCompletionStreamResp.Usage != nil {
+					usage = chatCompletionStreamResp.Usage
+				}
 			}
 
 			return true
@@ -0,0 +1,395 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type geminiEstimator interface {
+	EstimateTotalCost(model string, promptTks, completionTks int) (float64, error)
+	EstimateCompletionCost(model string, tks int) (float64, error)
+	EstimatePromptCost(model string, tks int) (float64, error)
+	Count(input string) int
+	CountContentsTokens(r *gemini.GenerateContentRequest) int
+}
+
+func buildGeminiUrl(c *gin.Context, model, action string) string {
+	query := url.Values{}
+	for k, v := range c.Request.URL.Query() {
+		if k != "key" {
+			query[k] = v
+		}
+	}
+
+	target := "https://generativelanguage.googleapis.com/v1beta/models/" + model + ":" + action
+	if len(query) != 0 {
+		target += "?" + query.Encode()
+	}
+
+	return target
+}
+
+func estimateGeminiCost(c *gin.Context, e geminiEstimator, model string, promptTks, completionTks int) (float64, error) {
+	raw, exists := c.Get("cost_map")
+	if exists {
+		converted, ok := raw.(*provider.CostMap)
+		if ok {
+			cost, err := provider.EstimateTotalCostWithCostMaps(model, promptTks, completionTks, 1000, converted.PromptCostPerModel, converted.CompletionCostPerModel)
+			if err == nil && cost != 0 {
+				return cost, nil
+			}
+		}
+	}
+
+	return e.EstimateTotalCost(model, promptTks, completionTks)
+}
+
+func getGeminiGenerateContentHandler(prod, private bool, client http.Client, e geminiEstimator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.requests", nil, 1)
+
+		if c == nil || c.Request == nil {
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] context is empty")
+			return
+		}
+
+		model, action := gemini.ParseModelAction(c.Param("model"))
+		if action != gemini.GenerateContentAction && action != gemini.StreamGenerateContentAction {
+			telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.action_not_supported", nil, 1)
+			JSON(c, http.StatusNotFound, "[BricksLLM] gemini action is not supported")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			logError(log, "error when reading gemini request body", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read gemini request body")
+			return
+		}
+
+		gr := &gemini.GenerateContentRequest{}
+		err = json.Unmarshal(body, gr)
+		if err != nil {
+			logError(log, "error when unmarshalling gemini generate content request", prod, err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.GetDuration("requestTimeout"))
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, buildGeminiUrl(c, model, action), bytes.NewReader(body))
+		if err != nil {
+			logError(log, "error when creating gemini http request", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to create gemini http request")
+			return
+		}
+
+		copyHttpHeaders(c.Request, req, c.GetBool("removeUserAgent"))
+		req.Header.Del("Authorization")
+		req.Header.Del("x-api-key")
+		req.Header.Del("api-key")
+
+		isStreaming := c.GetBool("stream")
+		isSse := c.Query("alt") == "sse"
+		if isStreaming && isSse {
+			req.Header.Set("Accept", "text/event-stream")
+			req.Header.Set("Cache-Control", "no-cache")
+			req.Header.Set("Connection", "keep-alive")
+		}
+
+		start := time.Now()
+		res, err := client.Do(req)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.http_client_error", nil, 1)
+
+			logError(log, "error when sending http request to gemini", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to send http request to gemini")
+			return
+		}
+
+		defer res.Body.Close()
+
+		for name, values := range res.Header {
+			for _, value := range values {
+				c.Header(name, value)
+			}
+		}
+
+		if !isStreaming && res.StatusCode == http.StatusOK {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.proxy.get_gemini_generate_content_handler.latency", dur, nil, 1)
+
+			bytes, err := io.ReadAll(res.Body)
+			if err != nil {
+				logError(log, "error when reading gemini http generate content response body", prod, err)
+				JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read gemini response body")
+				return
+			}
+
+			var cost float64 = 0
+			completionTokens := 0
+			promptTokens := 0
+
+			gres := &gemini.GenerateContentResponse{}
+			telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.success", nil, 1)
+			telemetry.Timing("bricksllm.proxy.get_gemini_generate_content_handler.success_latency", dur, nil, 1)
+
+			err = json.Unmarshal(bytes, gres)
+			if err != nil {
+				logError(log, "error when unmarshalling gemini http generate content response body", prod, err)
+			}
+
+			if err == nil {
+				logGeminiGenerateContentResponse(log, gres, prod, private)
+
+				if gres.UsageMetadata != nil {
+					promptTokens = gres.UsageMetadata.PromptTokenCount
+					completionTokens = gres.UsageMetadata.CompletionTokens()
+				} else {
+					promptTokens = e.CountContentsTokens(gr)
+					completionTokens = e.Count(gres.GetText())
+				}
+
+				cost, err = estimateGeminiCost(c, e, model, promptTokens, completionTokens)
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.estimate_total_cost_error", nil, 1)
+					logError(log, "error when estimating gemini cost", prod, err)
+				}
+
+				c.Set("content", gres.GetText())
+			}
+
+			c.Set("costInUsd", cost)
+			c.Set("promptTokenCount", promptTokens)
+			c.Set("completionTokenCount", completionTokens)
+
+			c.Data(res.StatusCode, "application/json", bytes)
+			return
+		}
+
+		if res.StatusCode != http.StatusOK {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.proxy.get_gemini_generate_content_handler.error_latency", dur, nil, 1)
+			telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.error_response", nil, 1)
+			bytes, err := io.ReadAll(res.Body)
+			if err != nil {
+				logError(log, "error when reading gemini http generate content response body", prod, err)
+				JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read gemini response body")
+				return
+			}
+
+			logGeminiErrorResponse(log, bytes, prod)
+			c.Data(res.StatusCode, "application/json", bytes)
+			return
+		}
+
+		buffer := bufio.NewReader(res.Body)
+
+		content := ""
+		var usage *gemini.UsageMetadata
+		streamingResponse := [][]byte{}
+		defer func() {
+			promptTokens := 0
+			completionTokens := 0
+
+			if usage != nil {
+				promptTokens = usage.PromptTokenCount
+				completionTokens = usage.CompletionTokens()
+			} else {
+				promptTokens = e.CountContentsTokens(gr)
+				completionTokens = e.Count(content)
+			}
+
+			cost, err := estimateGeminiCost(c, e, model, promptTokens, completionTokens)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.estimate_total_cost_error", nil, 1)
+				logError(log, "error when estimating gemini stream cost", prod, err)
+			}
+
+			c.Set("content", content)
+			c.Set("costInUsd", cost)
+			c.Set("promptTokenCount", promptTokens)
+			c.Set("completionTokenCount", completionTokens)
+		}()
+
+		telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.streaming_requests", nil, 1)
+
+		if !isSse {
+			// without alt=sse gemini streams a single json array, which is
+			// forwarded as is and parsed once the stream ends.
+			c.Stream(func(w io.Writer) bool {
+				chunk := make([]byte, 4096)
+				n, err := buffer.Read(chunk)
+				if n > 0 {
+					streamingResponse = append(streamingResponse, chunk[:n])
+					w.Write(chunk[:n])
+				}
+
+				if err != nil {
+					if err != io.EOF {
+						telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.read_bytes_error", nil, 1)
+						logError(log, "error when reading bytes from gemini streaming response", prod, err)
+					}
+
+					return false
+				}
+
+				return true
+			})
+
+			data := bytes.Join(streamingResponse, []byte{})
+			c.Set("streaming_response", data)
+
+			chunks := []*gemini.GenerateContentResponse{}
+			err = json.Unmarshal(data, &chunks)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.stream_response_unmarshall_error", nil, 1)
+				logError(log, "error when unmarshalling gemini stream response", prod, err)
+			}
+
+			for _, chunk := range chunks {
+				content += chunk.GetText()
+				if chunk.UsageMetadata != nil {
+					usage = chunk.UsageMetadata
+				}
+			}
+
+			telemetry.Timing("bricksllm.proxy.get_gemini_generate_content_handler.streaming_latency", time.Since(start), nil, 1)
+			return
+		}
+
+		defer func() {
+			c.Set("streaming_response", bytes.Join(streamingResponse, []byte{'\n'}))
+		}()
+
+		c.Stream(func(w io.Writer) bool {
+			raw, err := buffer.ReadBytes('\n')
+			if err != nil {
+				if err == io.EOF {
+					return false
+				}
+
+				if errors.Is(err, context.DeadlineExceeded) {
+					telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.context_deadline_exceeded_error", nil, 1)
+					logError(log, "context deadline exceeded when reading bytes from gemini streaming response", prod, err)
+
+					return false
+				}
+
+				telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.read_bytes_error", nil, 1)
+				logError(log, "error when reading bytes from gemini streaming response", prod, err)
+
+				apiErr := &gemini.ErrorResponse{
+					Error: &gemini.Error{
+						Code:    http.StatusInternalServerError,
+						Status:  "bricksllm_error",
+						Message: err.Error(),
+					},
+				}
+
+				bytes, err := json.Marshal(apiErr)
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.json_marshal_error", nil, 1)
+					logError(log, "error when marshalling bytes for gemini streaming error response", prod, err)
+					return false
+				}
+
+				c.SSEvent("", " "+string(bytes))
+				return false
+			}
+
+			streamingResponse = append(streamingResponse, raw)
+
+			noSpaceLine := bytes.TrimSpace(raw)
+			if !bytes.HasPrefix(noSpaceLine, headerData) {
+				return true
+			}
+
+			noPrefixLine := bytes.TrimPrefix(noSpaceLine, headerData)
+			c.SSEvent("", " "+string(noPrefixLine))
+
+			chunk := &gemini.GenerateContentResponse{}
+			err = json.Unmarshal(noPrefixLine, chunk)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_gemini_generate_content_handler.stream_response_unmarshall_error", nil, 1)
+				logError(log, "error when unmarshalling gemini stream response", prod, err)
+				return true
+			}
+
+			content += chunk.GetText()
+			if chunk.UsageMetadata != nil {
+				usage = chunk.UsageMetadata
+			}
+
+			return true
+		})
+
+		telemetry.Timing("bricksllm.proxy.get_gemini_generate_content_handler.streaming_latency", time.Since(start), nil, 1)
+	}
+}
+
+func logGeminiGenerateContentRequest(log *zap.Logger, model string, gr *gemini.GenerateContentRequest, prod, private bool) {
+	if prod {
+		fields := []zapcore.Field{
+			zap.String("model", model),
+			zap.Any("generationConfig", gr.GenerationConfig),
+		}
+
+		if !private {
+			fields = append(fields, zap.Any("contents", gr.Contents))
+			fields = append(fields, zap.Any("systemInstruction", gr.SystemInstruction))
+		}
+
+		log.Info("gemini generate content request", fields...)
+	}
+}
+
+func logGeminiGenerateContentResponse(log *zap.Logger, gres *gemini.GenerateContentResponse, prod, private bool) {
+	if prod {
+		fields := []zapcore.Field{
+			zap.String("modelVersion", gres.ModelVersion),
+			zap.String("responseId", gres.ResponseId),
+			zap.Any("usageMetadata", gres.UsageMetadata),
+		}
+
+		if !private {
+			fields = append(fields, zap.Any("candidates", gres.Candidates))
+		}
+
+		log.Info("gemini generate content response", fields...)
+	}
+}
+
+func logGeminiErrorResponse(log *zap.Logger, data []byte, prod bool) {
+	er := &gemini.ErrorResponse{}
+	err := json.Unmarshal(data, er)
+	if err != nil {
+		logError(log, "error when unmarshalling gemini error response", prod, err)
+		return
+	}
+
+	if prod {
+		fields := []zapcore.Field{}
+
+		if er.Error != nil {
+			fields = append(fields, zap.Any("error", er.Error))
+		}
+
+		log.Info("gemini error response", fields...)
+	}
+}
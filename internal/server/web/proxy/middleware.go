@@ -19,8 +19,10 @@ import (
 	"github.com/bricks-cloud/bricksllm/internal/event"
 	"github.com/bricks-cloud/bricksllm/internal/key"
 	"github.com/bricks-cloud/bricksllm/internal/message"
+	"github.com/bricks-cloud/bricksllm/internal/policy"
 	"github.com/bricks-cloud/bricksllm/internal/provider"
 	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
 	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
 	"github.com/bricks-cloud/bricksllm/internal/provider/vllm"
 	"github.com/bricks-cloud/bricksllm/internal/route"
@@ -56,6 +58,8 @@ type estimator interface {
 	EstimateChatCompletionStreamCostWithTokenCounts(model, content string) (int, float64, error)
 	EstimateCompletionCost(model string, tks int) (float64, error)
 	EstimateTotalCost(model string, promptTks, completionTks int) (float64, error)
+	EstimateTotalCostWithCachedTokens(model string, promptTks, cachedTks, completionTks int) (float64, error)
+	EstimateBatchCost(endpoint string, output []byte) (*openai.BatchCost, error)
 	EstimateEmbeddingsInputCost(model string, tks int) (float64, error)
 	EstimateChatCompletionPromptTokenCounts(model string, r *goopenai.ChatCompletionRequest) (int, error)
 	EstimateResponseApiTotalCost(model string, usage responsesOpenai.ResponseUsage) (float64, error)
@@ -77,16 +81,63 @@ type deepinfraEstimator interface {
 	EstimateEmbeddingsInputCost(model string, tks int) (float64, error)
 }
 
+type priceCatalog interface {
+	HasModel(provider, model string) bool
+}
+
 type authenticator interface {
 	AuthenticateHttpRequest(req *http.Request, xCustomProviderId string) (*key.ResponseKey, []*provider.Setting, error)
+	AuthenticateUnifiedRequest(req *http.Request, model string) (*key.ResponseKey, *provider.Setting, error)
+	AuthenticateKey(req *http.Request) (*key.ResponseKey, error)
+	RewriteAuthHeader(req *http.Request, used *provider.Setting) error
 }
 
 type validator interface {
 	Validate(k *key.ResponseKey, promptCost float64) error
+	ValidateTokenLimit(k *key.ResponseKey, promptTks int) error
+	ValidateRateLimit(k *key.ResponseKey) error
+	ValidateBudgets(k *key.ResponseKey) error
+	ValidateModelLimits(k *key.ResponseKey, model string) error
+}
+
+type userValidator interface {
+	ValidateTokenLimit(u *user.User, promptTks int) error
 }
 
 type rateLimitManager interface {
-	Increment(keyId string, timeUnit key.TimeUnit) error
+	Increment(keyId string, timeUnit key.TimeUnit, algorithm key.RateLimitAlgorithm) error
+	IncrementTokens(keyId string, timeUnit key.TimeUnit, tks int64) error
+	IncrementUserTokens(id string, timeUnit key.TimeUnit, tks int64) error
+}
+
+type rateLimitError interface {
+	Error() string
+	RateLimit()
+}
+
+type tokenLimitError interface {
+	Error() string
+	TokenLimit()
+}
+
+type budgetLimitError interface {
+	Error() string
+	BudgetLimit()
+}
+
+type concurrencyLimitError interface {
+	Error() string
+	ConcurrencyLimit()
+}
+
+type concurrencyManager interface {
+	Acquire(id string, limit int) (string, error)
+	Release(id, leaseId string) error
+}
+
+type modelLimitError interface {
+	Error() string
+	ModelLimit()
 }
 
 type accessCache interface {
@@ -106,6 +157,10 @@ func JSON(c *gin.Context, code int, message string) {
 	})
 }
 
+type healthRecorder interface {
+	RecordStatus(settingId string, status int)
+}
+
 type notAuthorizedError interface {
 	Authenticated()
 }
@@ -180,7 +235,7 @@ type CustomPolicyDetector interface {
 	Detect(input []string, requirements []string) (bool, error)
 }
 
-func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManager, a authenticator, prod, private bool, log *zap.Logger, pub publisher, prefix string, ac accessCache, uac userAccessCache, client http.Client, scanner Scanner, cd CustomPolicyDetector, um userManager, removeUserAgent bool) gin.HandlerFunc {
+func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManager, a authenticator, prod, private bool, log *zap.Logger, pub publisher, prefix string, ca cache, ac accessCache, uac userAccessCache, client http.Client, scanner Scanner, cd CustomPolicyDetector, um userManager, hr healthRecorder, v validator, uv userValidator, ae anthropicEstimator, pc priceCatalog, cm concurrencyManager, rlm rateLimitManager, removeUserAgent bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c == nil || c.Request == nil {
 			JSON(c, http.StatusInternalServerError, "[BricksLLM] request is empty")
@@ -193,6 +248,18 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			return
 		}
 
+		// unified requests are dispatched to a provider route, which goes
+		// through this middleware on its own.
+		if isUnifiedPath(c.FullPath()) {
+			return
+		}
+
+		// usage requests are authenticated by their handler and are neither
+		// limited nor recorded as events.
+		if c.FullPath() == keyUsagePath {
+			return
+		}
+
 		if removeUserAgent {
 			c.Set("removeUserAgent", removeUserAgent)
 		}
@@ -214,12 +281,15 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 		userId := ""
 
 		var policyInput any = nil
+		var messagesRequest *anthropic.MessagesRequest
 
 		customId := c.Request.Header.Get("X-CUSTOM-EVENT-ID")
 
 		metadataBytes := []byte(`{}`)
 		metadata := c.Request.Header.Get("X-METADATA")
 
+		attempts := []retryAttempt{}
+
 		defer func() {
 			dur := time.Since(start)
 			latency := int(dur.Milliseconds())
@@ -247,6 +317,20 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 				}
 			}
 
+			if len(attempts) > 1 {
+				data, err := json.Marshal(&retryMetadata{
+					Metadata: metadata,
+					Attempts: attempts,
+				})
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_middleware.json_marshal_retry_metadata_err", nil, 1)
+				}
+
+				if err == nil {
+					metadataBytes = data
+				}
+			}
+
 			telemetry.Timing("bricksllm.proxy.get_middleware.proxy_latency_in_ms", dur, nil, 1)
 
 			selectedProvider := getProvider(c)
@@ -267,28 +351,32 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			}, 1)
 
 			evt := &event.Event{
-				Id:                   util.NewUuid(),
-				CreatedAt:            time.Now().Unix(),
-				Tags:                 tags,
-				KeyId:                keyId,
-				CostInUsd:            c.GetFloat64("costInUsd"),
-				Provider:             selectedProvider,
-				Model:                c.GetString("model"),
-				Status:               c.Writer.Status(),
-				PromptTokenCount:     c.GetInt("promptTokenCount"),
-				CompletionTokenCount: c.GetInt("completionTokenCount"),
-				LatencyInMs:          latency,
-				Path:                 c.Request.URL.Path,
-				Method:               c.Request.Method,
-				CustomId:             customId,
-				Request:              requestBytes,
-				Response:             responseBytes,
-				UserId:               userId,
-				PolicyId:             c.GetString("policyId"),
-				Action:               c.GetString("action"),
-				RouteId:              c.GetString("routeId"),
-				CorrelationId:        cid,
-				Metadata:             metadataBytes,
+				Id:                      util.NewUuid(),
+				CreatedAt:               time.Now().Unix(),
+				Tags:                    tags,
+				KeyId:                   keyId,
+				CostInUsd:               c.GetFloat64("costInUsd"),
+				Provider:                selectedProvider,
+				Model:                   c.GetString("model"),
+				Status:                  c.Writer.Status(),
+				PromptTokenCount:        c.GetInt("promptTokenCount"),
+				CompletionTokenCount:    c.GetInt("completionTokenCount"),
+				CachedPromptTokenCount:  c.GetInt("cachedPromptTokenCount"),
+				CacheCreationTokenCount: c.GetInt("cacheCreationTokenCount"),
+				LatencyInMs:             latency,
+				Path:                    c.Request.URL.Path,
+				Method:                  c.Request.Method,
+				CustomId:                customId,
+				Request:                 requestBytes,
+				Response:                responseBytes,
+				UserId:                  userId,
+				PolicyId:                c.GetString("policyId"),
+				Action:                  c.GetString("action"),
+				ResponseAction:          c.GetString("response_action"),
+				RouteId:                 c.GetString("routeId"),
+				CorrelationId:           cid,
+				Metadata:                metadataBytes,
+				CacheHit:                c.GetBool("cache_hit"),
 			}
 
 			enrichedEvent.Event = evt
@@ -348,6 +436,15 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			return
 		}
 
+		settings, err = pinUnifiedSetting(c.Request, a, settings)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_middleware.pin_unified_setting_error", nil, 1)
+			logError(logWithCid, "error when pinning unified provider setting", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] internal authentication error")
+			c.Abort()
+			return
+		}
+
 		c.Set("key", kc)
 		c.Set("settings", settings)
 
@@ -408,6 +505,8 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			c.Request.Body = io.NopCloser(bytes.NewReader(body))
 		}
 
+		forwardedBody := body
+
 		if c.FullPath() == "/api/providers/anthropic/v1/complete" {
 			logCompletionRequest(logWithCid, body, prod, private)
 
@@ -478,6 +577,7 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 
 			c.Set("model", mr.Model)
 
+			messagesRequest = mr
 			policyInput = mr
 		}
 
@@ -501,9 +601,33 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 
 			c.Set("model", mr.Model)
 
+			messagesRequest = mr
 			policyInput = mr
 		}
 
+		if c.FullPath() == "/api/providers/gemini/v1beta/models/:model" {
+			model, action := gemini.ParseModelAction(c.Param("model"))
+
+			gr := &gemini.GenerateContentRequest{}
+			err = json.Unmarshal(body, gr)
+			if err != nil {
+				logError(logWithCid, "error when unmarshalling gemini generate content request", prod, err)
+				return
+			}
+
+			logGeminiGenerateContentRequest(logWithCid, model, gr, prod, private)
+
+			enrichedEvent.Request = gr
+
+			if action == gemini.StreamGenerateContentAction {
+				c.Set("stream", true)
+			}
+
+			c.Set("model", model)
+
+			policyInput = gr
+		}
+
 		if strings.HasPrefix(c.FullPath(), "/api/custom/providers/:provider") {
 			providerName := c.Param("provider")
 
@@ -605,14 +729,16 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 				logRequest(logWithCid, prod, private, ccr)
 
 				if ccr.Stream {
-					telemetry.Incr("bricksllm.proxy.get_middleware.streaming_not_allowed", nil, 1)
-					JSON(c, http.StatusForbidden, "[BricksLLM] streaming is not allowed")
-					c.Abort()
-					return
+					c.Set("stream", true)
 				}
 
-				if rc.CacheConfig != nil && rc.CacheConfig.Enabled {
+				if rc.CacheConfig != nil && rc.CacheConfig.Enabled && !ccr.Stream {
 					c.Set("cache_key", route.ComputeCacheKeyForChatCompletionRequest(r, ccr))
+
+					if rc.CacheConfig.IsSemantic() {
+						c.Set("semantic_cache_namespace", route.GetSemanticCacheNamespace(rc.Id, kc.KeyId, ccr))
+						c.Set("semantic_cache_input", route.GetSemanticCacheInput(ccr))
+					}
 				}
 
 				policyInput = ccr
@@ -1017,6 +1143,36 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			c.Set("model", model)
 		}
 
+		if c.FullPath() == "/api/providers/xCustom/:x_provider_id/*wildcard" && len(settings) == 1 {
+			selected := settings[0]
+
+			model := xcustom.GetRequestString(selected, xcustom.XCustomSettingFields.ModelLocation, body)
+			if len(model) != 0 {
+				c.Set("model", model)
+			}
+
+			// requests without a model would otherwise pass the allowed models check.
+			if len(model) == 0 && len(selected.GetParam(xcustom.XCustomSettingFields.ModelLocation)) != 0 && len(selected.AllowedModels) != 0 {
+				telemetry.Incr("bricksllm.proxy.get_middleware.x_custom_model_missing", nil, 1)
+				JSON(c, http.StatusForbidden, "[BricksLLM] model is required")
+				c.Abort()
+				return
+			}
+
+			xUserId := xcustom.GetRequestString(selected, xcustom.XCustomSettingFields.UserLocation, body)
+			if len(xUserId) != 0 {
+				userId = xUserId
+			}
+
+			locations := xcustom.GetMessagesLocations(selected)
+			if len(locations) != 0 && len(body) != 0 {
+				policyInput = &policy.JsonRequest{
+					Body:      body,
+					Locations: locations,
+				}
+			}
+		}
+
 		if len(kc.AllowedPaths) != 0 && !containsPath(kc.AllowedPaths, c.FullPath(), c.Request.Method) {
 			telemetry.Incr("bricksllm.proxy.get_middleware.path_not_allowed", nil, 1)
 			JSON(c, http.StatusForbidden, "[BricksLLM] path is not allowed")
@@ -1031,7 +1187,7 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			c.Abort()
 			return
 		}
-		if !isModelSupported(c.FullPath(), model) {
+		if !isModelSupported(c.FullPath(), model, pc) {
 			telemetry.Incr("bricksllm.proxy.get_middleware.model_not_supported", nil, 1)
 			JSON(c, http.StatusBadRequest, "[BricksLLM] model is not supported")
 			c.Abort()
@@ -1271,6 +1427,72 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			return
 		}
 
+		if kc.RateLimitOverTime != 0 && kc.RateLimitAlgorithm == key.SlidingWindowRateLimitAlgorithm {
+			if err := v.ValidateRateLimit(kc); err != nil {
+				if _, ok := err.(rateLimitError); ok {
+					telemetry.Incr("bricksllm.proxy.get_middleware.sliding_window_rate_limited", nil, 1)
+					JSON(c, http.StatusTooManyRequests, "[BricksLLM] too many requests")
+					c.Abort()
+					return
+				}
+
+				telemetry.Incr("bricksllm.proxy.get_middleware.validate_rate_limit_error", nil, 1)
+				logError(logWithCid, "error when validating rate limit", prod, err)
+			}
+		}
+
+		if err := v.ValidateBudgets(kc); err != nil {
+			if _, ok := err.(budgetLimitError); ok {
+				telemetry.Incr("bricksllm.proxy.get_middleware.budget_limited", nil, 1)
+				JSON(c, http.StatusTooManyRequests, "[BricksLLM] "+err.Error())
+				c.Abort()
+				return
+			}
+
+			telemetry.Incr("bricksllm.proxy.get_middleware.validate_budgets_error", nil, 1)
+			logError(logWithCid, "error when validating budgets", prod, err)
+		}
+
+		if err := v.ValidateModelLimits(kc, c.GetString("model")); err != nil {
+			if _, ok := err.(modelLimitError); ok {
+				telemetry.Incr("bricksllm.proxy.get_middleware.model_limited", nil, 1)
+				JSON(c, http.StatusTooManyRequests, "[BricksLLM] "+err.Error())
+				c.Abort()
+				return
+			}
+
+			telemetry.Incr("bricksllm.proxy.get_middleware.validate_model_limits_error", nil, 1)
+			logError(logWithCid, "error when validating model limits", prod, err)
+		}
+
+		// messages requests are counted with the anthropic tokenizer so that
+		// images and tools are accounted for.
+		estimateTokens := func() int {
+			if messagesRequest != nil {
+				return ae.CountMessagesRequestTokens(messagesRequest)
+			}
+
+			return estimatePromptTokens(body)
+		}
+
+		promptTks := 0
+		if kc.TokenLimitOverTime != 0 {
+			promptTks = estimateTokens()
+
+			if err := v.ValidateTokenLimit(kc, promptTks); err != nil {
+				if _, ok := err.(tokenLimitError); ok {
+					telemetry.Incr("bricksllm.proxy.get_middleware.token_limited", nil, 1)
+					JSON(c, http.StatusTooManyRequests, "[BricksLLM] token limit exceeded")
+					c.Abort()
+					return
+				}
+
+				telemetry.Incr("bricksllm.proxy.get_middleware.validate_token_limit_error", nil, 1)
+				logError(logWithCid, "error when validating token limit", prod, err)
+			}
+		}
+
+		var u *user.User
 		if len(userId) != 0 {
 			c.Set("userId", userId)
 			us, err := um.GetUsers(kc.Tags, nil, []string{userId}, 0, 0)
@@ -1287,6 +1509,13 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 					return
 				}
 
+				if us[0].CreditBalanceInUsd != nil && *us[0].CreditBalanceInUsd <= 0 {
+					telemetry.Incr("bricksllm.proxy.get_middleware.user_credits_exhausted", nil, 1)
+					JSON(c, http.StatusPaymentRequired, fmt.Sprintf("[BricksLLM] credit balance is exhausted for user: %s", userId))
+					c.Abort()
+					return
+				}
+
 				model := c.GetString("model")
 				if len(us[0].AllowedModels) != 0 && !contains(us[0].AllowedModels, model) {
 					telemetry.Incr("bricksllm.proxy.get_middleware.user_requested_model_not_allowed", nil, 1)
@@ -1308,6 +1537,26 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 					c.Abort()
 					return
 				}
+
+				if us[0].TokenLimitOverTime != 0 {
+					if kc.TokenLimitOverTime == 0 {
+						promptTks = estimateTokens()
+					}
+
+					if err := uv.ValidateTokenLimit(us[0], promptTks); err != nil {
+						if _, ok := err.(tokenLimitError); ok {
+							telemetry.Incr("bricksllm.proxy.get_middleware.user_token_limited", nil, 1)
+							JSON(c, http.StatusTooManyRequests, fmt.Sprintf("[BricksLLM] token limit exceeded for user: %s", userId))
+							c.Abort()
+							return
+						}
+
+						telemetry.Incr("bricksllm.proxy.get_middleware.validate_user_token_limit_error", nil, 1)
+						logError(logWithCid, "error when validating user token limit", prod, err)
+					}
+				}
+
+				u = us[0]
 			}
 
 			if len(us) > 1 {
@@ -1315,6 +1564,35 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			}
 		}
 
+		// the estimated prompt tokens are counted right away so that concurrent
+		// requests cannot all pass the check. They are reconciled with the
+		// actual usage when the event of the request is handled.
+		if promptTks > 0 {
+			reservation := &event.TokenReservation{
+				ReservedAt: time.Now().Unix(),
+			}
+
+			if kc.TokenLimitOverTime != 0 {
+				if err := rlm.IncrementTokens(kc.KeyId, kc.TokenLimitUnit, int64(promptTks)); err != nil {
+					telemetry.Incr("bricksllm.proxy.get_middleware.reserve_tokens_error", nil, 1)
+					logError(logWithCid, "error when reserving key tokens", prod, err)
+				} else {
+					reservation.KeyTokens = promptTks
+				}
+			}
+
+			if u != nil && u.TokenLimitOverTime != 0 {
+				if err := rlm.IncrementUserTokens(u.Id, u.TokenLimitUnit, int64(promptTks)); err != nil {
+					telemetry.Incr("bricksllm.proxy.get_middleware.reserve_user_tokens_error", nil, 1)
+					logError(logWithCid, "error when reserving user tokens", prod, err)
+				} else {
+					reservation.UserTokens = promptTks
+				}
+			}
+
+			enrichedEvent.TokenReservation = reservation
+		}
+
 		if p != nil {
 			c.Set("policyId", p.Id)
 		}
@@ -1350,7 +1628,12 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 
 			data, err := json.Marshal(policyInput)
 			if err == nil {
+				// the marshalled body can differ in length from the original one,
+				// which matters for requests forwarded as they are.
 				c.Request.Body = io.NopCloser(bytes.NewReader(data))
+				c.Request.ContentLength = int64(len(data))
+				c.Request.Header.Del("Content-Length")
+				forwardedBody = data
 
 				if kc.ShouldLogRequest {
 					requestBytes = data
@@ -1358,11 +1641,121 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			}
 		}
 
-		c.Next()
+		cacheKey := ""
+		if shouldCacheResponse(c, kc) {
+			cacheKey = computeResponseCacheKey(kc.KeyId, c.FullPath(), body)
+
+			cached, err := ca.GetBytes(cacheKey)
+			if err == nil && len(cached) != 0 {
+				telemetry.Incr("bricksllm.proxy.get_middleware.response_cache_hit", nil, 1)
+
+				if kc.ShouldLogResponse {
+					responseBytes = cached
+				}
+
+				c.Set("cache_hit", true)
+				c.Data(http.StatusOK, "application/json", cached)
+				c.Abort()
+				return
+			}
+		}
+
+		if kc.MaxConcurrentRequests != 0 {
+			leaseId, err := cm.Acquire(kc.KeyId, kc.MaxConcurrentRequests)
+			if _, ok := err.(concurrencyLimitError); ok {
+				telemetry.Incr("bricksllm.proxy.get_middleware.concurrency_limited", nil, 1)
+				JSON(c, http.StatusTooManyRequests, "[BricksLLM] too many concurrent requests")
+				c.Abort()
+				return
+			}
+
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_middleware.acquire_concurrency_lease_error", nil, 1)
+				logError(logWithCid, "error when acquiring concurrency lease", prod, err)
+			}
+
+			if err == nil {
+				defer func() {
+					if err := cm.Release(kc.KeyId, leaseId); err != nil {
+						telemetry.Incr("bricksllm.proxy.get_middleware.release_concurrency_lease_error", nil, 1)
+						logError(logWithCid, "error when releasing concurrency lease", prod, err)
+					}
+				}()
+			}
+		}
+
+		if u != nil && u.MaxConcurrentRequests != 0 {
+			leaseId, err := cm.Acquire(u.Id, u.MaxConcurrentRequests)
+			if _, ok := err.(concurrencyLimitError); ok {
+				telemetry.Incr("bricksllm.proxy.get_middleware.user_concurrency_limited", nil, 1)
+				JSON(c, http.StatusTooManyRequests, fmt.Sprintf("[BricksLLM] too many concurrent requests for user: %s", userId))
+				c.Abort()
+				return
+			}
+
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_middleware.acquire_user_concurrency_lease_error", nil, 1)
+				logError(logWithCid, "error when acquiring user concurrency lease", prod, err)
+			}
+
+			if err == nil {
+				defer func() {
+					if err := cm.Release(u.Id, leaseId); err != nil {
+						telemetry.Incr("bricksllm.proxy.get_middleware.release_user_concurrency_lease_error", nil, 1)
+						logError(logWithCid, "error when releasing user concurrency lease", prod, err)
+					}
+				}()
+			}
+		}
+
+		var rpw *responsePolicyWriter
+		if shouldApplyResponsePolicy(c, p) {
+			rpw = newResponsePolicyWriter(c, p, scanner, cd, logWithCid, prod)
+			c.Writer = rpw
+		}
+
+		candidates := getRetryCandidates(c, kc, settings)
+		if len(candidates) != 0 {
+			attempts = runWithRetry(c, a, hr, logWithCid, prod, candidates, forwardedBody)
+
+			if cm, ok := c.Get("cost_map"); ok {
+				if converted, ok := cm.(*provider.CostMap); ok {
+					enrichedEvent.CostMap = converted
+				}
+			}
+		}
+
+		if len(candidates) == 0 {
+			c.Next()
+
+			if len(settings) != 0 && strings.HasPrefix(c.FullPath(), "/api/providers/") {
+				hr.RecordStatus(settings[0].Id, c.Writer.Status())
+			}
+		}
+
+		if rpw != nil {
+			rpw.finish()
+			c.Writer = rpw.ResponseWriter
+
+			if len(rpw.action) != 0 {
+				c.Set("response_action", rpw.action)
+			}
+		}
+
+		if len(cacheKey) != 0 && c.Writer.Status() == http.StatusOK && blw.body.Len() != 0 {
+			err := ca.StoreBytes(cacheKey, blw.body.Bytes(), kc.CacheConfig.GetTtl())
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_middleware.store_cached_response_error", nil, 1)
+				logError(logWithCid, "error when storing cached response", prod, err)
+			}
+		}
 
 		if kc.ShouldLogResponse {
 			if c.GetBool("stream") {
 				streamingResponse, ok := c.Get("streaming_response")
+				if action := c.GetString("response_action"); action == "redacted" || action == "blocked" {
+					streamingResponse, ok = blw.body.Bytes(), true
+				}
 
 				if ok {
 					bs, _ := streamingResponse.([]byte)
@@ -1438,6 +1831,7 @@ var openaiModels = map[string]struct{}{}
 var anthropicModels = map[string]struct{}{}
 var azureOpenAIModels = map[string]struct{}{}
 var deepinfraModels = map[string]struct{}{}
+var geminiModels = map[string]struct{}{}
 
 func init() {
 	// openai models
@@ -1448,6 +1842,8 @@ func init() {
 	initByCostMap(azureOpenAIModels, azure.AzureOpenAiPerThousandTokenCost)
 	// deepinfra models
 	initByCostMap(deepinfraModels, deepinfra.DeepinfraPerMillionTokenCost)
+	// gemini models
+	initByCostMap(geminiModels, gemini.GeminiPerMillionTokenCost)
 }
 
 func initByCostMap(target map[string]struct{}, source map[string]map[string]float64) {
@@ -1458,7 +1854,7 @@ func initByCostMap(target map[string]struct{}, source map[string]map[string]floa
 	}
 }
 
-func isModelSupported(path, model string) bool {
+func isModelSupported(path, model string, pc priceCatalog) bool {
 	if len(model) == 0 {
 		return true
 	}
@@ -1467,6 +1863,9 @@ func isModelSupported(path, model string) bool {
 	if strings.HasPrefix(path, "/api/providers/anthropic") {
 		targetModel = anthropic.SelectModel(targetModel)
 	}
+	if strings.HasPrefix(path, "/api/providers/gemini") {
+		targetModel = gemini.SelectModel(targetModel)
+	}
 	models := modelsMapByPath(path)
 	if models == nil {
 		return true
@@ -1474,7 +1873,26 @@ func isModelSupported(path, model string) bool {
 	if _, ok := models[targetModel]; ok {
 		return true
 	}
-	return false
+	return pc != nil && pc.HasModel(providerByPath(path), targetModel)
+}
+
+func providerByPath(path string) string {
+	if strings.HasPrefix(path, "/api/providers/openai") {
+		return "openai"
+	}
+	if strings.HasPrefix(path, "/api/providers/anthropic") {
+		return "anthropic"
+	}
+	if strings.HasPrefix(path, "/api/providers/azure/openai") {
+		return "azure"
+	}
+	if strings.HasPrefix(path, "/api/providers/deepinfra") {
+		return "deepinfra"
+	}
+	if strings.HasPrefix(path, "/api/providers/gemini") {
+		return "gemini"
+	}
+	return ""
 }
 
 func modelsMapByPath(path string) map[string]struct{} {
@@ -1490,5 +1908,8 @@ func modelsMapByPath(path string) map[string]struct{} {
 	if strings.HasPrefix(path, "/api/providers/deepinfra") {
 		return deepinfraModels
 	}
+	if strings.HasPrefix(path, "/api/providers/gemini") {
+		return geminiModels
+	}
 	return nil
 }
@@ -82,14 +82,14 @@ func CorsMiddleware() gin.HandlerFunc {
 	}
 }
 
-func NewProxyServer(log *zap.Logger, mode, privacyMode string, c cache, m KeyManager, rm routeManager, a authenticator, psm ProviderSettingsManager, cpm CustomProvidersManager, ks keyStorage, e estimator, ae anthropicEstimator, aoe azureEstimator, v validator, r recorder, pub publisher, rlm rateLimitManager, timeout time.Duration, ac accessCache, uac userAccessCache, pm PoliciesManager, scanner Scanner, cd CustomPolicyDetector, die deepinfraEstimator, um userManager, removeAgentHeaders bool) (*ProxyServer, error) {
+func NewProxyServer(log *zap.Logger, mode, privacyMode string, c cache, sc semanticCache, m KeyManager, rm routeManager, a authenticator, psm ProviderSettingsManager, cpm CustomProvidersManager, ks keyStorage, e estimator, ae anthropicEstimator, aoe azureEstimator, v validator, r recorder, pub publisher, rlm rateLimitManager, timeout time.Duration, ac accessCache, uac userAccessCache, pm PoliciesManager, scanner Scanner, cd CustomPolicyDetector, die deepinfraEstimator, ge geminiEstimator, um userManager, uv userValidator, hr healthRecorder, pc priceCatalog, bs batchStorage, cm concurrencyManager, kum keyUsageManager, removeAgentHeaders bool) (*ProxyServer, error) {
 	router := gin.New()
 	prod := mode == "production"
 	private := privacyMode == "strict"
 
 	router.Use(CorsMiddleware())
 	router.Use(getTimeoutMiddleware(timeout))
-	router.Use(getMiddleware(cpm, rm, pm, a, prod, private, log, pub, "proxy", ac, uac, http.Client{}, scanner, cd, um, removeAgentHeaders))
+	router.Use(getMiddleware(cpm, rm, pm, a, prod, private, log, pub, "proxy", c, ac, uac, http.Client{}, scanner, cd, um, hr, v, uv, ae, pc, cm, rlm, removeAgentHeaders))
 
 	client := http.Client{}
 
@@ -99,6 +99,9 @@ func NewProxyServer(log *zap.Logger, mode, privacyMode string, c cache, m KeyMan
 	// health check
 	router.GET("/api/health", getGetHealthCheckHandler())
 
+	// key usage
+	router.GET(keyUsagePath, getKeyUsageHandler(a, kum, prod, log))
+
 	// audios
 	router.POST("/api/providers/openai/v1/audio/speech", getSpeechHandler(prod, client))
 	router.POST("/api/providers/openai/v1/audio/transcriptions", getTranscriptionsHandler(prod, client, e))
@@ -180,8 +183,8 @@ func NewProxyServer(log *zap.Logger, mode, privacyMode string, c cache, m KeyMan
 	router.GET("/api/providers/openai/v1/files/:file_id/content", getPassThroughHandler(prod, private, client))
 
 	// batch
-	router.POST("/api/providers/openai/v1/batches", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/batches/:batch_id", getPassThroughHandler(prod, private, client))
+	router.POST("/api/providers/openai/v1/batches", getCreateBatchHandler(prod, client, bs))
+	router.GET("/api/providers/openai/v1/batches/:batch_id", getRetrieveBatchHandler(prod, client, bs, m, e, pub))
 	router.POST("/api/providers/openai/v1/batches/:batch_id/cancel", getPassThroughHandler(prod, private, client))
 	router.GET("/api/providers/openai/v1/batches", getPassThroughHandler(prod, private, client))
 
@@ -203,6 +206,9 @@ func NewProxyServer(log *zap.Logger, mode, privacyMode string, c cache, m KeyMan
 	router.POST("/api/providers/bedrock/anthropic/v1/complete", getBedrockCompletionHandler(prod, ae))
 	router.POST("/api/providers/bedrock/anthropic/v1/messages", getBedrockMessagesHandler(prod, ae))
 
+	// gemini
+	router.POST("/api/providers/gemini/v1beta/models/:model", getGeminiGenerateContentHandler(prod, private, client, ge))
+
 	// vllm
 	router.POST("/api/providers/vllm/v1/chat/completions", getVllmChatCompletionsHandler(prod, private, client))
 	router.POST("/api/providers/vllm/v1/completions", getVllmCompletionsHandler(prod, private, client))
@@ -212,11 +218,15 @@ func NewProxyServer(log *zap.Logger, mode, privacyMode string, c cache, m KeyMan
 	router.POST("/api/providers/deepinfra/v1/completions", getDeepinfraCompletionsHandler(prod, private, client))
 	router.POST("/api/providers/deepinfra/v1/embeddings", getDeepinfraEmbeddingsHandler(prod, private, client, die))
 
+	// unified
+	router.POST("/v1/chat/completions", getUnifiedHandler(router, a, prod, log))
+	router.POST("/v1/embeddings", getUnifiedHandler(router, a, prod, log))
+
 	// custom provider
 	router.POST("/api/custom/providers/:provider/*wildcard", getCustomProviderHandler(prod, client))
 
 	// custom route
-	router.POST("/api/routes/*route", getRouteHandler(prod, c, aoe, e, client, r))
+	router.POST("/api/routes/*route", getRouteHandler(prod, c, sc, aoe, e, client, r))
 
 	// vector store
 	router.POST("/api/providers/openai/v1/vector_stores", getCreateVectorStoreHandler(prod, client))
@@ -889,14 +899,6 @@ func buildProxyUrl(c *gin.Context) (string, error) {
 		return "https://api.openai.com/v1/files/" + c.Param("file_id") + "/content", nil
 	}
 
-	if c.FullPath() == "/api/providers/openai/v1/batches" && c.Request.Method == http.MethodPost {
-		return "https://api.openai.com/v1/batches", nil
-	}
-
-	if c.FullPath() == "/api/providers/openai/v1/batches/:batch_id" && c.Request.Method == http.MethodGet {
-		return "https://api.openai.com/v1/batches/" + c.Param("batch_id"), nil
-	}
-
 	if c.FullPath() == "/api/providers/openai/v1/batches/:batch_id/cancel" && c.Request.Method == http.MethodPost {
 		return "https://api.openai.com/v1/batches/" + c.Param("batch_id") + "/cancel", nil
 	}
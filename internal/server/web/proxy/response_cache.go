@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bricks-cloud/bricksllm/internal/hasher"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/gin-gonic/gin"
+)
+
+const cacheBypassHeader = "X-BRICKSLLM-CACHE-BYPASS"
+
+var responseCacheablePaths = map[string]struct{}{
+	"/api/providers/openai/v1/chat/completions": {},
+	"/api/providers/openai/v1/embeddings":       {},
+	"/api/providers/anthropic/v1/messages":      {},
+}
+
+func shouldCacheResponse(c *gin.Context, kc *key.ResponseKey) bool {
+	if kc == nil || kc.CacheConfig == nil || !kc.CacheConfig.Enabled {
+		return false
+	}
+
+	if c.Request.Method != http.MethodPost || c.GetBool("stream") {
+		return false
+	}
+
+	if _, ok := responseCacheablePaths[c.FullPath()]; !ok {
+		return false
+	}
+
+	return !isCacheBypassed(c.Request)
+}
+
+func isCacheBypassed(req *http.Request) bool {
+	if strings.ToLower(req.Header.Get(cacheBypassHeader)) == "true" {
+		return true
+	}
+
+	cc := strings.ToLower(req.Header.Get("Cache-Control"))
+	return strings.Contains(cc, "no-cache") || strings.Contains(cc, "no-store")
+}
+
+func computeResponseCacheKey(keyId, path string, body []byte) string {
+	return hasher.Hash(fmt.Sprintf("%s-%s-%s", keyId, path, string(body)))
+}
@@ -0,0 +1,551 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bricks-cloud/bricksllm/internal/policy"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"go.uber.org/zap"
+
+	goopenai "github.com/sashabaranov/go-openai"
+)
+
+// streamPolicyWindow is the number of server sent events buffered before the
+// policy is evaluated against a streaming response.
+const streamPolicyWindow = 10
+
+// streamPolicyOverlap is the number of trailing bytes of streamed text that are
+// held back after each evaluation and scanned again with the next window, so
+// that entities split across windows are still detected.
+const streamPolicyOverlap = 128
+
+// redactionMask is what the policy replaces redacted entities with.
+const redactionMask = "***"
+
+var responsePolicyPaths = map[string]struct{}{
+	"/api/providers/openai/v1/chat/completions": {},
+	"/api/providers/openai/v1/completions":      {},
+	"/api/providers/anthropic/v1/complete":      {},
+	"/api/providers/anthropic/v1/messages":      {},
+	"/api/providers/vllm/v1/chat/completions":   {},
+	"/api/providers/vllm/v1/completions":        {},
+}
+
+var actionPriorities = map[string]int{
+	"":         0,
+	"allowed":  1,
+	"redacted": 2,
+	"warned":   3,
+	"blocked":  4,
+}
+
+func shouldApplyResponsePolicy(c *gin.Context, p *policy.Policy) bool {
+	if p == nil || !p.ApplyToResponses {
+		return false
+	}
+
+	_, ok := responsePolicyPaths[c.FullPath()]
+	return ok
+}
+
+// responseContent is a text content found at path within a response body or
+// stream event. Contents with the same group belong to the same text, such as
+// a choice or a content block, and are concatenated in order before scanning.
+type responseContent struct {
+	group string
+	path  string
+}
+
+// getResponseContents returns the text contents within an OpenAI, Anthropic
+// or vLLM response body or stream event.
+func getResponseContents(data []byte) []responseContent {
+	contents := []responseContent{}
+
+	for i, choice := range gjson.GetBytes(data, "choices").Array() {
+		index := int64(i)
+		if idx := choice.Get("index"); idx.Exists() {
+			index = idx.Int()
+		}
+
+		group := fmt.Sprintf("choice.%d", index)
+		for _, field := range []string{"message.content", "delta.content", "text"} {
+			if choice.Get(field).Type == gjson.String {
+				contents = append(contents, responseContent{group: group, path: fmt.Sprintf("choices.%d.%s", i, field)})
+			}
+		}
+	}
+
+	for i, block := range gjson.GetBytes(data, "content").Array() {
+		if block.Get("text").Type == gjson.String {
+			contents = append(contents, responseContent{group: fmt.Sprintf("block.%d", i), path: fmt.Sprintf("content.%d.text", i)})
+		}
+	}
+
+	if gjson.GetBytes(data, "completion").Type == gjson.String {
+		contents = append(contents, responseContent{group: "completion", path: "completion"})
+	}
+
+	for _, path := range []string{"delta.text", "content_block.text"} {
+		if gjson.GetBytes(data, path).Type == gjson.String {
+			contents = append(contents, responseContent{group: fmt.Sprintf("block.%d", gjson.GetBytes(data, "index").Int()), path: path})
+		}
+	}
+
+	return contents
+}
+
+// contentSegment is the part of a concatenated text that comes from the
+// content at path of a payload.
+type contentSegment struct {
+	payload int
+	path    string
+	start   int
+	end     int
+}
+
+type contentText struct {
+	text     strings.Builder
+	segments []contentSegment
+}
+
+// redactedSpan is a range of an original text that was replaced with the
+// redaction mask.
+type redactedSpan struct {
+	start int
+	end   int
+}
+
+// getRedactedSpans aligns a redacted text with its original and returns the
+// ranges of the original that were replaced with the redaction mask.
+func getRedactedSpans(original, redacted string) []redactedSpan {
+	spans := []redactedSpan{}
+
+	i, j := 0, 0
+	for i < len(original) && j < len(redacted) {
+		if original[i] == redacted[j] {
+			i++
+			j++
+			continue
+		}
+
+		if !strings.HasPrefix(redacted[j:], redactionMask) {
+			break
+		}
+
+		j += len(redactionMask)
+		rest := redacted[j:]
+
+		end := len(original) - len(rest)
+		if next := strings.Index(rest, redactionMask); next >= 0 {
+			idx := strings.Index(original[i+1:], rest[:next])
+			if idx < 0 {
+				break
+			}
+
+			end = i + 1 + idx
+		}
+
+		if end <= i {
+			break
+		}
+
+		spans = append(spans, redactedSpan{start: i, end: end})
+		i = end
+	}
+
+	return spans
+}
+
+// redactSegment returns the text of the original range [start, end) with the
+// redacted spans replaced. A span is masked within the segment it starts in.
+func redactSegment(original string, start, end int, spans []redactedSpan) string {
+	var sb strings.Builder
+
+	pos := start
+	for _, span := range spans {
+		if span.end <= pos || span.start >= end {
+			continue
+		}
+
+		if span.start > pos {
+			sb.WriteString(original[pos:span.start])
+		}
+
+		if span.start >= start {
+			sb.WriteString(redactionMask)
+		}
+
+		pos = min(span.end, end)
+	}
+
+	if pos < end {
+		sb.WriteString(original[pos:end])
+	}
+
+	return sb.String()
+}
+
+// responsePolicyWriter holds successful responses until the policy has been
+// evaluated against them. Non streaming responses are held entirely while
+// streaming responses are evaluated every streamPolicyWindow new events,
+// together with the events carried over from the previous window.
+type responsePolicyWriter struct {
+	gin.ResponseWriter
+	streaming bool
+	anthropic bool
+	held      bool
+	status    int
+	body      *bytes.Buffer
+	pending   *bytes.Buffer
+	events    [][]byte
+	carried   int
+	blocked   bool
+	action    string
+	filter    func(contents []string) ([]string, error)
+	log       *zap.Logger
+	prod      bool
+}
+
+func newResponsePolicyWriter(c *gin.Context, p *policy.Policy, scanner Scanner, cd CustomPolicyDetector, log *zap.Logger, prod bool) *responsePolicyWriter {
+	return &responsePolicyWriter{
+		ResponseWriter: c.Writer,
+		streaming:      c.GetBool("stream"),
+		anthropic:      strings.HasPrefix(c.FullPath(), "/api/providers/anthropic/"),
+		body:           bytes.NewBufferString(""),
+		pending:        bytes.NewBufferString(""),
+		filter: func(contents []string) ([]string, error) {
+			return p.FilterResponseContents(contents, scanner, cd, log)
+		},
+		log:  log,
+		prod: prod,
+	}
+}
+
+func (w *responsePolicyWriter) setAction(action string) {
+	if actionPriorities[action] > actionPriorities[w.action] {
+		w.action = action
+	}
+}
+
+// apply evaluates the policy against the text contents of the payloads and
+// rewrites redacted contents in place. Contents of the same group are joined
+// across payloads, so that entities split over several stream events are
+// detected. Unless final is set, payloads holding the last streamPolicyOverlap
+// bytes of any text are left out of the returned count of payloads that are
+// ready to be sent. It also reports whether the response should be blocked.
+func (w *responsePolicyWriter) apply(payloads [][]byte, final bool) ([][]byte, int, bool) {
+	groups := []string{}
+	texts := map[string]*contentText{}
+
+	for i, data := range payloads {
+		for _, content := range getResponseContents(data) {
+			ct, ok := texts[content.group]
+			if !ok {
+				ct = &contentText{}
+				texts[content.group] = ct
+				groups = append(groups, content.group)
+			}
+
+			start := ct.text.Len()
+			ct.text.WriteString(gjson.GetBytes(data, content.path).Str)
+			ct.segments = append(ct.segments, contentSegment{payload: i, path: content.path, start: start, end: ct.text.Len()})
+		}
+	}
+
+	if len(groups) == 0 {
+		return payloads, len(payloads), false
+	}
+
+	contents := make([]string, 0, len(groups))
+	for _, group := range groups {
+		contents = append(contents, texts[group].text.String())
+	}
+
+	spans := make([][]redactedSpan, len(groups))
+
+	updated, err := w.filter(contents)
+	if err == nil {
+		w.setAction("allowed")
+	} else if _, ok := err.(blockedError); ok {
+		telemetry.Incr("bricksllm.proxy.response_policy_writer.apply.response_blocked", nil, 1)
+		w.setAction("blocked")
+		return payloads, 0, true
+	} else if _, ok := err.(warnedError); ok {
+		w.setAction("warned")
+	} else if _, ok := err.(redactedError); ok {
+		w.setAction("redacted")
+
+		for i := range groups {
+			if i < len(updated) && updated[i] != contents[i] {
+				spans[i] = getRedactedSpans(contents[i], updated[i])
+			}
+		}
+	} else {
+		telemetry.Incr("bricksllm.proxy.response_policy_writer.apply.filter_error", nil, 1)
+		logError(w.log, "error when filtering a response", w.prod, err)
+	}
+
+	ready := len(payloads)
+	if !final {
+		for i, group := range groups {
+			cutoff := len(contents[i]) - streamPolicyOverlap
+			for _, span := range spans[i] {
+				if span.start < cutoff && cutoff < span.end {
+					cutoff = span.start
+				}
+			}
+
+			for _, segment := range texts[group].segments {
+				if segment.end > cutoff {
+					ready = min(ready, segment.payload)
+					break
+				}
+			}
+		}
+	}
+
+	for i, group := range groups {
+		if len(spans[i]) == 0 {
+			continue
+		}
+
+		for _, segment := range texts[group].segments {
+			if segment.payload >= ready {
+				break
+			}
+
+			replaced, err := sjson.SetBytes(payloads[segment.payload], segment.path, redactSegment(contents[i], segment.start, segment.end, spans[i]))
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.response_policy_writer.apply.set_redacted_content_error", nil, 1)
+				logError(w.log, "error when setting redacted response content", w.prod, err)
+				continue
+			}
+
+			payloads[segment.payload] = replaced
+		}
+	}
+
+	return payloads, ready, false
+}
+
+func (w *responsePolicyWriter) WriteHeader(code int) {
+	if !w.streaming && code == http.StatusOK && !w.ResponseWriter.Written() {
+		w.held = true
+		w.status = code
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responsePolicyWriter) WriteHeaderNow() {
+	if w.held {
+		return
+	}
+
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *responsePolicyWriter) Write(b []byte) (int, error) {
+	if w.held {
+		return w.body.Write(b)
+	}
+
+	if w.streaming && w.ResponseWriter.Status() == http.StatusOK {
+		return w.writeStream(b)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responsePolicyWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *responsePolicyWriter) Flush() {
+	if w.held || len(w.events) != 0 || w.pending.Len() != 0 {
+		return
+	}
+
+	w.ResponseWriter.Flush()
+}
+
+func (w *responsePolicyWriter) Status() int {
+	if w.held {
+		return w.status
+	}
+
+	return w.ResponseWriter.Status()
+}
+
+func (w *responsePolicyWriter) Written() bool {
+	return w.held || w.ResponseWriter.Written()
+}
+
+func (w *responsePolicyWriter) writeStream(b []byte) (int, error) {
+	if w.blocked {
+		return len(b), nil
+	}
+
+	w.pending.Write(b)
+
+	flushNow := false
+	for {
+		idx := bytes.Index(w.pending.Bytes(), []byte("\n\n"))
+		if idx < 0 {
+			break
+		}
+
+		ev := make([]byte, idx+2)
+		copy(ev, w.pending.Next(idx+2))
+		w.events = append(w.events, ev)
+
+		if bytes.Contains(ev, []byte("[DONE]")) || bytes.Contains(ev, []byte("message_stop")) {
+			flushNow = true
+		}
+	}
+
+	if flushNow || len(w.events)-w.carried >= streamPolicyWindow {
+		w.evaluateStream(flushNow)
+	}
+
+	return len(b), nil
+}
+
+func getEventData(ev []byte) (int, int) {
+	for _, line := range []string{"\ndata:", "data:"} {
+		idx := bytes.Index(ev, []byte(line))
+		if idx < 0 || (line == "data:" && idx != 0) {
+			continue
+		}
+
+		start := idx + len(line)
+		end := bytes.IndexByte(ev[start:], '\n')
+		if end < 0 {
+			end = len(ev) - start
+		}
+
+		return start, start + end
+	}
+
+	return -1, -1
+}
+
+// evaluateStream evaluates the policy against the held events and sends the
+// ones that are ready. Unless final is set, the events holding the tail of the
+// streamed text are kept to be evaluated again with the next window.
+func (w *responsePolicyWriter) evaluateStream(final bool) {
+	if len(w.events) == 0 {
+		return
+	}
+
+	payloads := make([][]byte, len(w.events))
+	for i, ev := range w.events {
+		start, end := getEventData(ev)
+		if start < 0 {
+			continue
+		}
+
+		payloads[i] = bytes.TrimSpace(ev[start:end])
+	}
+
+	original := make([][]byte, len(payloads))
+	copy(original, payloads)
+
+	updated, ready, blocked := w.apply(payloads, final)
+	if blocked {
+		w.blocked = true
+		w.events = nil
+		w.carried = 0
+		w.pending.Reset()
+		w.writeStreamBlockedEvent()
+		w.ResponseWriter.Flush()
+		return
+	}
+
+	for i, ev := range w.events[:ready] {
+		if original[i] != nil && !bytes.Equal(original[i], updated[i]) {
+			start, end := getEventData(ev)
+			rewritten := append([]byte{}, ev[:start]...)
+			rewritten = append(rewritten, ' ')
+			rewritten = append(rewritten, updated[i]...)
+			rewritten = append(rewritten, ev[end:]...)
+			ev = rewritten
+		}
+
+		w.ResponseWriter.Write(ev)
+	}
+
+	w.events = w.events[ready:]
+	w.carried = len(w.events)
+	if ready != 0 {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *responsePolicyWriter) writeStreamBlockedEvent() {
+	if w.anthropic {
+		w.ResponseWriter.Write([]byte(`event: error` + "\n" + `data: {"type":"error","error":{"type":"bricksllm_error","message":"[BricksLLM] response blocked"}}` + "\n\n"))
+		return
+	}
+
+	data, _ := json.Marshal(&goopenai.ErrorResponse{
+		Error: &goopenai.APIError{
+			Type:    "bricksllm_error",
+			Message: "[BricksLLM] response blocked",
+			Code:    strconv.Itoa(http.StatusForbidden),
+		},
+	})
+
+	w.ResponseWriter.Write([]byte("data: " + string(data) + "\n\n"))
+	w.ResponseWriter.Write([]byte("data: [DONE]\n\n"))
+}
+
+// finish evaluates held responses and sends the result to the client.
+func (w *responsePolicyWriter) finish() {
+	if w.streaming {
+		if w.pending.Len() != 0 && !w.blocked {
+			w.events = append(w.events, w.pending.Bytes())
+			w.pending = bytes.NewBufferString("")
+		}
+
+		w.evaluateStream(true)
+		return
+	}
+
+	if !w.held {
+		return
+	}
+
+	w.held = false
+	payloads, _, blocked := w.apply([][]byte{w.body.Bytes()}, true)
+	if blocked {
+		data, _ := json.Marshal(&goopenai.ErrorResponse{
+			Error: &goopenai.APIError{
+				Message: "[BricksLLM] response blocked",
+				Code:    strconv.Itoa(http.StatusForbidden),
+			},
+		})
+
+		w.ResponseWriter.Header().Del("Content-Length")
+		w.ResponseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.ResponseWriter.WriteHeader(http.StatusForbidden)
+		w.ResponseWriter.Write(data)
+		return
+	}
+
+	if !bytes.Equal(payloads[0], w.body.Bytes()) {
+		w.ResponseWriter.Header().Del("Content-Length")
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(payloads[0])
+}
@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/pii"
+	"github.com/bricks-cloud/bricksllm/internal/pii/local"
+	"github.com/bricks-cloud/bricksllm/internal/policy"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"go.uber.org/zap"
+)
+
+func newStreamingPolicyWriter(t *testing.T, action policy.Action) (*responsePolicyWriter, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/providers/openai/v1/chat/completions", nil)
+	c.Set("stream", true)
+
+	p := &policy.Policy{
+		ApplyToResponses: true,
+		Config: &policy.Config{
+			Rules: map[policy.Rule]policy.Action{
+				policy.Email: action,
+			},
+		},
+	}
+
+	return newResponsePolicyWriter(c, p, pii.NewScanner(local.NewDetector()), nil, zap.NewNop(), true), rec
+}
+
+func writeDeltas(w *responsePolicyWriter, deltas []string) {
+	w.WriteHeader(http.StatusOK)
+	for _, delta := range deltas {
+		w.Write([]byte(fmt.Sprintf(`data: {"choices":[{"index":0,"delta":{"content":%q}}]}`, delta) + "\n\n"))
+	}
+
+	w.Write([]byte("data: [DONE]\n\n"))
+	w.finish()
+}
+
+func getStreamedContent(t *testing.T, body string) string {
+	t.Helper()
+
+	var sb strings.Builder
+	for _, ev := range strings.Split(body, "\n\n") {
+		data := strings.TrimSpace(strings.TrimPrefix(ev, "data:"))
+		if len(data) == 0 || data == "[DONE]" {
+			continue
+		}
+
+		require.True(t, gjson.Valid(data), data)
+		sb.WriteString(gjson.Get(data, "choices.0.delta.content").Str)
+	}
+
+	return sb.String()
+}
+
+func TestResponsePolicyWriter_RedactsEntitySplitAcrossDeltas(t *testing.T) {
+	tests := []struct {
+		name     string
+		deltas   []string
+		expected string
+	}{
+		{
+			name:     "within a window",
+			deltas:   []string{"Contact ", "john", "@", "example", ".com", " for help."},
+			expected: "Contact *** for help.",
+		},
+		{
+			// the third window ends after "jane.doe"
+			name: "across windows",
+			deltas: append(
+				strings.Split(strings.Repeat("filler,", 27), ",")[:27:27],
+				"reach ", "jane.", "doe", "@", "exam", "ple.", "org", " today", ".",
+			),
+			expected: strings.Repeat("filler", 27) + "reach *** today.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, rec := newStreamingPolicyWriter(t, policy.AllowButRedact)
+			writeDeltas(w, tt.deltas)
+
+			assert.Equal(t, "redacted", w.action)
+			assert.Equal(t, tt.expected, getStreamedContent(t, rec.Body.String()))
+			assert.True(t, strings.HasSuffix(rec.Body.String(), "data: [DONE]\n\n"))
+		})
+	}
+}
+
+func TestResponsePolicyWriter_BlocksEntitySplitAcrossDeltas(t *testing.T) {
+	w, rec := newStreamingPolicyWriter(t, policy.Block)
+	writeDeltas(w, []string{"Contact ", "john", "@", "example", ".com", " for help."})
+
+	assert.Equal(t, "blocked", w.action)
+	assert.NotContains(t, rec.Body.String(), "john")
+	assert.Contains(t, rec.Body.String(), "response blocked")
+}
+
+func TestGetRedactedSpans(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		redacted string
+		expected []redactedSpan
+	}{
+		{
+			name:     "no redaction",
+			original: "hello world",
+			redacted: "hello world",
+			expected: []redactedSpan{},
+		},
+		{
+			name:     "middle",
+			original: "mail a@b.com now",
+			redacted: "mail *** now",
+			expected: []redactedSpan{{start: 5, end: 12}},
+		},
+		{
+			name:     "start and end",
+			original: "a@b.com and c@d.org",
+			redacted: "*** and ***",
+			expected: []redactedSpan{{start: 0, end: 7}, {start: 12, end: 19}},
+		},
+		{
+			name:     "adjacent",
+			original: "ab",
+			redacted: "******",
+			expected: []redactedSpan{{start: 0, end: 1}, {start: 1, end: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, getRedactedSpans(tt.original, tt.redacted))
+		})
+	}
+}
+
+func TestRedactSegment(t *testing.T) {
+	original := "mail a@b.com now"
+	spans := []redactedSpan{{start: 5, end: 12}}
+
+	segments := [][2]int{{0, 6}, {6, 8}, {8, 13}, {13, 16}}
+	redacted := []string{}
+	for _, segment := range segments {
+		redacted = append(redacted, redactSegment(original, segment[0], segment[1], spans))
+	}
+
+	assert.Equal(t, []string{"mail ***", "", " ", "now"}, redacted)
+	assert.Equal(t, "mail *** now", strings.Join(redacted, ""))
+}
@@ -0,0 +1,219 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var retryablePaths = map[string]struct{}{
+	"/api/providers/openai/v1/chat/completions": {},
+	"/api/providers/openai/v1/completions":      {},
+	"/api/providers/openai/v1/embeddings":       {},
+	"/api/providers/anthropic/v1/complete":      {},
+	"/api/providers/anthropic/v1/messages":      {},
+}
+
+type retryAttempt struct {
+	SettingId   string `json:"settingId"`
+	Status      int    `json:"status"`
+	LatencyInMs int    `json:"latencyInMs"`
+}
+
+type retryMetadata struct {
+	Metadata string         `json:"metadata,omitempty"`
+	Attempts []retryAttempt `json:"attempts"`
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// retryResponseWriter holds back responses with retryable status codes so
+// that the request can be replayed before anything is sent to the client.
+type retryResponseWriter struct {
+	gin.ResponseWriter
+	canHold bool
+	held    bool
+	status  int
+	body    *bytes.Buffer
+}
+
+func (w *retryResponseWriter) reset(canHold bool) {
+	w.canHold = canHold
+	w.held = false
+	w.status = 0
+	w.body.Reset()
+}
+
+// release sends a held response to the client.
+func (w *retryResponseWriter) release() {
+	if !w.held {
+		return
+	}
+
+	w.held = false
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+}
+
+func (w *retryResponseWriter) WriteHeader(code int) {
+	if w.canHold && !w.ResponseWriter.Written() && isRetryableStatus(code) {
+		w.held = true
+		w.status = code
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *retryResponseWriter) WriteHeaderNow() {
+	if w.held {
+		return
+	}
+
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *retryResponseWriter) Write(b []byte) (int, error) {
+	if w.held {
+		return w.body.Write(b)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *retryResponseWriter) WriteString(s string) (int, error) {
+	if w.held {
+		return w.body.WriteString(s)
+	}
+
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *retryResponseWriter) Flush() {
+	if w.held {
+		return
+	}
+
+	w.ResponseWriter.Flush()
+}
+
+func (w *retryResponseWriter) Status() int {
+	if w.held {
+		return w.status
+	}
+
+	return w.ResponseWriter.Status()
+}
+
+func (w *retryResponseWriter) Written() bool {
+	return w.held || w.ResponseWriter.Written()
+}
+
+func getRetryCandidates(c *gin.Context, kc *key.ResponseKey, settings []*provider.Setting) []*provider.Setting {
+	if kc.RetryConfig == nil || !kc.RetryConfig.Enabled || len(settings) < 2 {
+		return nil
+	}
+
+	if c.Request.Method != http.MethodPost {
+		return nil
+	}
+
+	if _, ok := retryablePaths[c.FullPath()]; !ok {
+		return nil
+	}
+
+	model := c.GetString("model")
+	candidates := []*provider.Setting{settings[0]}
+	for _, s := range settings[1:] {
+		if s.Provider == settings[0].Provider && isModelAllowed(model, []*provider.Setting{s}) {
+			candidates = append(candidates, s)
+		}
+	}
+
+	if kc.RetryConfig.MaxAttempts > 0 && len(candidates) > kc.RetryConfig.MaxAttempts {
+		candidates = candidates[:kc.RetryConfig.MaxAttempts]
+	}
+
+	if len(candidates) < 2 {
+		return nil
+	}
+
+	return candidates
+}
+
+// runWithRetry runs the handler against each candidate setting until the
+// upstream responds with a status that is not retryable. The response of the
+// last attempt is always sent to the client.
+func runWithRetry(c *gin.Context, a authenticator, hr healthRecorder, log *zap.Logger, prod bool, candidates []*provider.Setting, body []byte) []retryAttempt {
+	rw := &retryResponseWriter{ResponseWriter: c.Writer, body: bytes.NewBufferString("")}
+	c.Writer = rw
+	defer func() {
+		rw.release()
+		c.Writer = rw.ResponseWriter
+	}()
+
+	base := rw.Header().Clone()
+	attempts := []retryAttempt{}
+
+	for i, s := range candidates {
+		if i != 0 {
+			if err := a.RewriteAuthHeader(c.Request, s); err != nil {
+				telemetry.Incr("bricksllm.proxy.run_with_retry.rewrite_auth_header_error", nil, 1)
+				logError(log, "error when rewriting auth header for retry", prod, err)
+				continue
+			}
+
+			header := rw.Header()
+			for k := range header {
+				delete(header, k)
+			}
+
+			for k, v := range base {
+				header[k] = v
+			}
+
+			costMap := s.CostMap
+			if costMap == nil {
+				costMap = &provider.CostMap{}
+			}
+
+			c.Set("cost_map", costMap)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+			telemetry.Incr("bricksllm.proxy.run_with_retry.retry", nil, 1)
+		}
+
+		rw.reset(i != len(candidates)-1)
+
+		start := time.Now()
+		if i == 0 {
+			c.Next()
+		} else {
+			c.Handler()(c)
+		}
+
+		status := rw.Status()
+		hr.RecordStatus(s.Id, status)
+
+		attempts = append(attempts, retryAttempt{
+			SettingId:   s.Id,
+			Status:      status,
+			LatencyInMs: int(time.Since(start).Milliseconds()),
+		})
+
+		if !rw.held {
+			break
+		}
+	}
+
+	return attempts
+}
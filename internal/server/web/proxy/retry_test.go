@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type settingAuthenticator struct{}
+
+func (a *settingAuthenticator) AuthenticateHttpRequest(req *http.Request, xCustomProviderId string) (*key.ResponseKey, []*provider.Setting, error) {
+	return nil, nil, nil
+}
+
+func (a *settingAuthenticator) AuthenticateUnifiedRequest(req *http.Request, model string) (*key.ResponseKey, *provider.Setting, error) {
+	return nil, nil, nil
+}
+
+func (a *settingAuthenticator) AuthenticateKey(req *http.Request) (*key.ResponseKey, error) {
+	return nil, nil
+}
+
+func (a *settingAuthenticator) RewriteAuthHeader(req *http.Request, used *provider.Setting) error {
+	req.Header.Set("Authorization", "Bearer "+used.Id)
+	return nil
+}
+
+type statusRecorder struct {
+	statuses map[string]int
+}
+
+func (r *statusRecorder) RecordStatus(settingId string, status int) {
+	r.statuses[settingId] = status
+}
+
+func TestRunWithRetry(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         map[string]int
+		expectedStatus   int
+		expectedBody     string
+		expectedAttempts []string
+	}{
+		{
+			name:             "first setting succeeds",
+			statuses:         map[string]int{"a": http.StatusOK, "b": http.StatusOK, "c": http.StatusOK},
+			expectedStatus:   http.StatusOK,
+			expectedBody:     "a:body",
+			expectedAttempts: []string{"a"},
+		},
+		{
+			name:             "rate limited setting is retried",
+			statuses:         map[string]int{"a": http.StatusTooManyRequests, "b": http.StatusOK, "c": http.StatusOK},
+			expectedStatus:   http.StatusOK,
+			expectedBody:     "b:body",
+			expectedAttempts: []string{"a", "b"},
+		},
+		{
+			name:             "client errors are not retried",
+			statuses:         map[string]int{"a": http.StatusBadRequest, "b": http.StatusOK, "c": http.StatusOK},
+			expectedStatus:   http.StatusBadRequest,
+			expectedBody:     "a:body",
+			expectedAttempts: []string{"a"},
+		},
+		{
+			name:             "response of the last attempt is sent",
+			statuses:         map[string]int{"a": http.StatusInternalServerError, "b": http.StatusBadGateway, "c": http.StatusServiceUnavailable},
+			expectedStatus:   http.StatusServiceUnavailable,
+			expectedBody:     "c:body",
+			expectedAttempts: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			hr := &statusRecorder{statuses: map[string]int{}}
+			candidates := []*provider.Setting{{Id: "a"}, {Id: "b"}, {Id: "c"}}
+
+			var attempts []retryAttempt
+			r := gin.New()
+			r.POST("/retry", func(c *gin.Context) {
+				attempts = runWithRetry(c, &settingAuthenticator{}, hr, zap.NewNop(), true, candidates, []byte("body"))
+			}, func(c *gin.Context) {
+				id := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+				body, _ := io.ReadAll(c.Request.Body)
+
+				c.Header("X-Setting-Id", id)
+				c.Data(tt.statuses[id], "text/plain", []byte(id+":"+string(body)))
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/retry", strings.NewReader("body"))
+			req.Header.Set("Authorization", "Bearer a")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedBody, rec.Body.String())
+			assert.Equal(t, []string{tt.expectedAttempts[len(tt.expectedAttempts)-1]}, rec.Header().Values("X-Setting-Id"))
+
+			ids := []string{}
+			for _, attempt := range attempts {
+				ids = append(ids, attempt.SettingId)
+				assert.Equal(t, tt.statuses[attempt.SettingId], attempt.Status)
+				assert.Equal(t, attempt.Status, hr.statuses[attempt.SettingId])
+			}
+
+			assert.Equal(t, tt.expectedAttempts, ids)
+		})
+	}
+}
@@ -1,10 +1,15 @@
 package proxy
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/bricks-cloud/bricksllm/internal/key"
@@ -25,7 +30,12 @@ type cache interface {
 	GetBytes(key string) ([]byte, error)
 }
 
-func getRouteHandler(prod bool, ca cache, aoe azureEstimator, e estimator, client http.Client, rec recorder) gin.HandlerFunc {
+type semanticCache interface {
+	Search(namespace, model, input string, threshold float64) (string, []float32, int, error)
+	Add(namespace, model, key string, vector []float32, ttl time.Duration)
+}
+
+func getRouteHandler(prod bool, ca cache, sc semanticCache, aoe azureEstimator, e estimator, client http.Client, rec recorder) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log := util.GetLogFromCtx(c)
 		trueStart := time.Now()
@@ -67,11 +77,54 @@ func getRouteHandler(prod bool, ca cache, aoe azureEstimator, e estimator, clien
 				telemetry.Timing("bricksllm.proxy.get_route_handeler.success_latency", time.Since(trueStart), nil, 1)
 
 				c.Set("provider", "cached")
+				c.Set("cache_hit", true)
 				c.Data(http.StatusOK, "application/json", bytes)
 				return
 			}
 		}
 
+		var semanticVector []float32
+		namespace := c.GetString("semantic_cache_namespace")
+		if shouldCache && rc.CacheConfig.IsSemantic() {
+			matched, vector, tks, err := sc.Search(namespace, rc.CacheConfig.EmbeddingModel, c.GetString("semantic_cache_input"), rc.CacheConfig.SimilarityThreshold)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_route_handeler.semantic_cache_search_error", tags, 1)
+				logError(log, "error when searching semantic cache", prod, err)
+			}
+
+			semanticVector = vector
+
+			// the embedding is created with the gateway's own OpenAI key, so its
+			// cost is added to whatever the request costs the key.
+			if tks != 0 {
+				ecost, err := e.EstimateEmbeddingsInputCost(rc.CacheConfig.EmbeddingModel, tks)
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_route_handeler.estimate_semantic_cache_embedding_cost_error", tags, 1)
+					logError(log, "error when estimating semantic cache embedding cost", prod, err)
+				}
+
+				if ecost != 0 {
+					defer func() {
+						c.Set("costInUsd", c.GetFloat64("costInUsd")+ecost)
+					}()
+				}
+			}
+
+			if len(matched) != 0 {
+				bytes, err := ca.GetBytes(matched)
+
+				if err == nil && len(bytes) != 0 {
+					telemetry.Incr("bricksllm.proxy.get_route_handeler.semantic_cache_hit", tags, 1)
+					telemetry.Timing("bricksllm.proxy.get_route_handeler.success_latency", time.Since(trueStart), nil, 1)
+
+					c.Set("provider", "cached")
+					c.Set("cache_hit", true)
+					c.Data(http.StatusOK, "application/json", bytes)
+					return
+				}
+			}
+		}
+
 		raw, exists = c.Get("settings")
 		settings, ok := raw.([]*provider.Setting)
 		if !exists || !ok {
@@ -107,6 +160,26 @@ func getRouteHandler(prod bool, ca cache, aoe azureEstimator, e estimator, clien
 			rreq.Request = bs
 		}
 
+		isStreaming := c.GetBool("stream")
+		ccr := &goopenai.ChatCompletionRequest{}
+		if isStreaming {
+			rreq.Stream = true
+
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				logError(log, "error when reading route request body", prod, err)
+				JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read route request body")
+				return
+			}
+
+			err = json.Unmarshal(body, ccr)
+			if err != nil {
+				logError(log, "error when unmarshalling route chat completion request", prod, err)
+			}
+
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
 		runRes, err := rc.RunStepsV2(rreq, rec, log, kc)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_route_handeler.run_steps_error", tags, 1)
@@ -127,6 +200,18 @@ func getRouteHandler(prod bool, ca cache, aoe azureEstimator, e estimator, clien
 		dur := time.Since(start)
 		telemetry.Timing("bricksllm.proxy.get_route_handeler.latency", dur, nil, 1)
 
+		if isStreaming && res.StatusCode == http.StatusOK {
+			for name, values := range res.Header {
+				for _, value := range values {
+					c.Header(name, value)
+				}
+			}
+
+			streamRouteResponse(c, prod, res, ccr, e, aoe, runRes.Model, runRes.Provider)
+			telemetry.Timing("bricksllm.proxy.get_route_handeler.streaming_latency", time.Since(start), nil, 1)
+			return
+		}
+
 		bytes := runRes.Data
 
 		if res.StatusCode == http.StatusOK {
@@ -151,6 +236,10 @@ func getRouteHandler(prod bool, ca cache, aoe azureEstimator, e estimator, clien
 					if err != nil {
 						logError(log, "error when storing cached response", prod, err)
 					}
+
+					if err == nil && rc.CacheConfig.IsSemantic() {
+						sc.Add(namespace, rc.CacheConfig.EmbeddingModel, cacheKey, semanticVector, parsed)
+					}
 				}
 
 			}
@@ -282,3 +371,146 @@ func parseResult(c *gin.Context, runEmbeddings bool, bytes []byte, e estimator,
 
 	return nil
 }
+
+func streamRouteResponse(c *gin.Context, prod bool, res *http.Response, ccr *goopenai.ChatCompletionRequest, e estimator, aoe azureEstimator, model, provider string) {
+	log := util.GetLogFromCtx(c)
+	telemetry.Incr("bricksllm.proxy.get_route_handeler.streaming_requests", nil, 1)
+
+	buffer := bufio.NewReader(res.Body)
+	content := ""
+	streamingResponse := [][]byte{}
+
+	defer func() {
+		c.Set("content", content)
+		c.Set("streaming_response", bytes.Join(streamingResponse, []byte{'\n'}))
+
+		err := parseStreamResult(c, ccr, content, e, aoe, model, provider)
+		if err != nil {
+			logError(log, "error when parsing run steps stream result", prod, err)
+		}
+	}()
+
+	c.Stream(func(w io.Writer) bool {
+		raw, err := buffer.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF {
+				return false
+			}
+
+			if errors.Is(err, context.DeadlineExceeded) {
+				telemetry.Incr("bricksllm.proxy.get_route_handeler.context_deadline_exceeded_error", nil, 1)
+				logError(log, "context deadline exceeded when reading bytes from route streaming response", prod, err)
+
+				return false
+			}
+
+			telemetry.Incr("bricksllm.proxy.get_route_handeler.read_bytes_error", nil, 1)
+			logError(log, "error when reading bytes from route streaming response", prod, err)
+
+			apiErr := &goopenai.ErrorResponse{
+				Error: &goopenai.APIError{
+					Type:    "bricksllm_error",
+					Message: err.Error(),
+				},
+			}
+
+			bytes, err := json.Marshal(apiErr)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_route_handeler.json_marshal_error", nil, 1)
+				logError(log, "error when marshalling bytes for route streaming error response", prod, err)
+				return false
+			}
+
+			c.SSEvent("", string(bytes))
+			c.SSEvent("", " [DONE]")
+			return false
+		}
+
+		streamingResponse = append(streamingResponse, raw)
+
+		noSpaceLine := bytes.TrimSpace(raw)
+		if !bytes.HasPrefix(noSpaceLine, headerData) {
+			return true
+		}
+
+		noPrefixLine := bytes.TrimPrefix(noSpaceLine, headerData)
+		c.SSEvent("", " "+string(noPrefixLine))
+
+		if string(noPrefixLine) == "[DONE]" {
+			return false
+		}
+
+		chatCompletionStreamResp := &goopenai.ChatCompletionStreamResponse{}
+		err = json.Unmarshal(noPrefixLine, chatCompletionStreamResp)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_route_handeler.completion_response_unmarshall_error", nil, 1)
+			logError(log, "error when unmarshalling route chat completion stream response", prod, err)
+		}
+
+		if err == nil {
+			if len(chatCompletionStreamResp.Choices) > 0 && len(chatCompletionStreamResp.Choices[0].Delta.Content) != 0 {
+				content += chatCompletionStreamResp.Choices[0].Delta.Content
+			}
+		}
+
+		return true
+	})
+}
+
+func parseStreamResult(c *gin.Context, ccr *goopenai.ChatCompletionRequest, content string, e estimator, aoe azureEstimator, model, provider string) error {
+	var cost float64 = 0
+	promptTokenCounts := 0
+	completionTokenCounts := 0
+
+	defer func() {
+		c.Set("provider", provider)
+		c.Set("costInUsd", cost)
+		c.Set("promptTokenCount", promptTokenCounts)
+		c.Set("completionTokenCount", completionTokenCounts)
+	}()
+
+	converted := *ccr
+	converted.Model = model
+
+	if provider == "azure" {
+		tokenizerModel := "gpt-3.5-turbo"
+		if strings.HasPrefix(model, "gpt-4o") {
+			tokenizerModel = "gpt-4o"
+		}
+
+		tks, err := e.EstimateChatCompletionPromptTokenCounts(tokenizerModel, &converted)
+		if err != nil {
+			return err
+		}
+
+		promptCost, err := aoe.EstimatePromptCost(model, tks)
+		if err != nil {
+			return err
+		}
+
+		completionTks, completionCost, err := aoe.EstimateChatCompletionStreamCostWithTokenCounts(model, content)
+		if err != nil {
+			return err
+		}
+
+		promptTokenCounts = tks
+		completionTokenCounts = completionTks
+		cost = promptCost + completionCost
+	} else if provider == "openai" {
+		tks, promptCost, err := e.EstimateChatCompletionPromptCostWithTokenCounts(&converted)
+		if err != nil {
+			return err
+		}
+
+		completionTks, completionCost, err := e.EstimateChatCompletionStreamCostWithTokenCounts(model, content)
+		if err != nil {
+			return err
+		}
+
+		promptTokenCounts = tks
+		completionTokenCounts = completionTks
+		cost = promptCost + completionCost
+	}
+
+	return nil
+}
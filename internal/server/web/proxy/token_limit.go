@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"github.com/bricks-cloud/bricksllm/internal/provider/custom"
+	"github.com/tidwall/gjson"
+)
+
+var promptContentPaths = []string{
+	"messages.#.content",
+	"messages.#.content.#.text",
+	"prompt",
+	"input",
+	"system",
+}
+
+// estimatePromptTokens gives a rough prompt token count of a request body so
+// that token limits can be checked before the request is forwarded. Actual
+// token usage is reconciled once the response has been processed.
+func estimatePromptTokens(body []byte) int {
+	if len(body) == 0 {
+		return 0
+	}
+
+	content := ""
+	for _, path := range promptContentPaths {
+		content += collectStrings(gjson.GetBytes(body, path))
+	}
+
+	tks, err := custom.Count(content)
+	if err != nil {
+		return 0
+	}
+
+	return tks
+}
+
+func collectStrings(result gjson.Result) string {
+	if result.Type == gjson.String {
+		return result.Str
+	}
+
+	content := ""
+	if result.IsArray() {
+		for _, val := range result.Array() {
+			content += collectStrings(val)
+		}
+	}
+
+	return content
+}
@@ -0,0 +1,403 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/gin-gonic/gin"
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"go.uber.org/zap"
+)
+
+const (
+	unifiedChatCompletionsPath = "/v1/chat/completions"
+	unifiedEmbeddingsPath      = "/v1/embeddings"
+
+	defaultAzureApiVersion = "2024-10-21"
+	anthropicApiVersion    = "2023-06-01"
+)
+
+// unifiedSettingCtxKey is used to pass the provider setting picked by the
+// unified handler to the middleware of the dispatched request.
+type unifiedSettingCtxKey struct{}
+
+func isUnifiedPath(path string) bool {
+	return path == unifiedChatCompletionsPath || path == unifiedEmbeddingsPath
+}
+
+func getUnifiedSetting(req *http.Request) *provider.Setting {
+	setting, _ := req.Context().Value(unifiedSettingCtxKey{}).(*provider.Setting)
+	return setting
+}
+
+// pinUnifiedSetting restricts the settings of a request dispatched by the
+// unified handler to the setting it picked.
+func pinUnifiedSetting(req *http.Request, a authenticator, settings []*provider.Setting) ([]*provider.Setting, error) {
+	pinned := getUnifiedSetting(req)
+	if pinned == nil {
+		return settings, nil
+	}
+
+	for index, s := range settings {
+		if s.Id != pinned.Id {
+			continue
+		}
+
+		if index != 0 {
+			err := a.RewriteAuthHeader(req, s)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return []*provider.Setting{s}, nil
+	}
+
+	return settings, nil
+}
+
+func getUnifiedApiKey(req *http.Request) string {
+	for _, header := range []string{"x-api-key", "api-key"} {
+		if v := req.Header.Get(header); len(v) != 0 {
+			return v
+		}
+	}
+
+	split := strings.Split(req.Header.Get("Authorization"), " ")
+	if len(split) >= 2 {
+		return split[1]
+	}
+
+	return ""
+}
+
+func buildUnifiedPath(c *gin.Context, setting *provider.Setting, model string, embeddings bool) (string, error) {
+	endpoint := "chat/completions"
+	if embeddings {
+		endpoint = "embeddings"
+	}
+
+	switch setting.Provider {
+	case "openai":
+		return "/api/providers/openai/v1/" + endpoint, nil
+	case "azure":
+		apiVersion := c.Query("api-version")
+		if len(apiVersion) == 0 {
+			apiVersion = defaultAzureApiVersion
+		}
+
+		return fmt.Sprintf("/api/providers/azure/openai/deployments/%s/%s?api-version=%s", model, endpoint, apiVersion), nil
+	case "deepinfra":
+		return "/api/providers/deepinfra/v1/" + endpoint, nil
+	case "vllm":
+		if !embeddings {
+			return "/api/providers/vllm/v1/chat/completions", nil
+		}
+	case "anthropic":
+		if !embeddings {
+			return "/api/providers/anthropic/v1/messages", nil
+		}
+	case "bedrock":
+		if !embeddings {
+			return "/api/providers/bedrock/anthropic/v1/messages", nil
+		}
+	}
+
+	return "", fmt.Errorf("%s is not supported by provider %s", c.FullPath(), setting.Provider)
+}
+
+func isAnthropicProvider(setting *provider.Setting) bool {
+	return setting.Provider == "anthropic" || setting.Provider == "bedrock"
+}
+
+// getUnifiedHandler serves OpenAI compatible requests for any provider. The
+// model of the request is looked up in the model maps of the key's provider
+// settings and the request is dispatched to the route of the matching
+// provider, translating requests and responses when the provider does not
+// speak the OpenAI format.
+func getUnifiedHandler(router *gin.Engine, a authenticator, prod bool, log *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		telemetry.Incr("bricksllm.proxy.get_unified_handler.requests", nil, 1)
+
+		if c == nil || c.Request == nil {
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] context is empty")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_unified_handler.read_all_error", nil, 1)
+			logError(log, "error when reading unified request body", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read request body")
+			return
+		}
+
+		model := gjson.GetBytes(body, "model").String()
+		if len(model) == 0 {
+			telemetry.Incr("bricksllm.proxy.get_unified_handler.empty_model", nil, 1)
+			JSON(c, http.StatusBadRequest, "[BricksLLM] model is required")
+			return
+		}
+
+		_, setting, err := a.AuthenticateUnifiedRequest(c.Request, model)
+		if _, ok := err.(notAuthorizedError); ok {
+			telemetry.Incr("bricksllm.proxy.get_unified_handler.authentication_error", nil, 1)
+			logError(log, "error when authenticating unified request", prod, err)
+			JSON(c, http.StatusUnauthorized, fmt.Sprintf("[BricksLLM] %v", err))
+			return
+		}
+
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_unified_handler.authenticate_unified_request_error", nil, 1)
+			logError(log, "error when authenticating unified request", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] internal authentication error")
+			return
+		}
+
+		mapped := setting.ModelMap[model]
+		path, err := buildUnifiedPath(c, setting, mapped, c.FullPath() == unifiedEmbeddingsPath)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_unified_handler.unsupported_provider", nil, 1)
+			JSON(c, http.StatusBadRequest, fmt.Sprintf("[BricksLLM] %v", err))
+			return
+		}
+
+		var converter *anthropic.StreamConverter
+		stream := false
+
+		if isAnthropicProvider(setting) {
+			ccr := &goopenai.ChatCompletionRequest{}
+			err = json.Unmarshal(body, ccr)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_unified_handler.unmarshal_chat_completion_request_error", nil, 1)
+				logError(log, "error when unmarshalling unified chat completion request", prod, err)
+				JSON(c, http.StatusBadRequest, "[BricksLLM] invalid chat completion request")
+				return
+			}
+
+			mr, err := anthropic.ConvertChatCompletionRequest(ccr, mapped)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_unified_handler.convert_chat_completion_request_error", nil, 1)
+				JSON(c, http.StatusBadRequest, fmt.Sprintf("[BricksLLM] %v", err))
+				return
+			}
+
+			body, err = json.Marshal(mr)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_unified_handler.marshal_messages_request_error", nil, 1)
+				logError(log, "error when marshalling unified messages request", prod, err)
+				JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to marshal messages request")
+				return
+			}
+
+			stream = ccr.Stream
+			if stream {
+				converter = anthropic.NewStreamConverter(model, ccr.StreamOptions != nil && ccr.StreamOptions.IncludeUsage)
+			}
+		} else {
+			body, err = sjson.SetBytes(body, "model", mapped)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_unified_handler.set_model_error", nil, 1)
+				logError(log, "error when setting model of unified request", prod, err)
+				JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to set model")
+				return
+			}
+		}
+
+		ctx := context.WithValue(c.Request.Context(), unifiedSettingCtxKey{}, setting)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(body))
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_unified_handler.new_request_error", nil, 1)
+			logError(log, "error when creating dispatched request", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to create request")
+			return
+		}
+
+		raw := getUnifiedApiKey(c.Request)
+		req.Header = c.Request.Header.Clone()
+		for _, header := range []string{"Authorization", "X-Api-Key", "Api-Key", "X-Goog-Api-Key", "Content-Length"} {
+			req.Header.Del(header)
+		}
+
+		switch setting.Provider {
+		case "anthropic", "bedrock":
+			req.Header.Set("x-api-key", raw)
+			if len(req.Header.Get("anthropic-version")) == 0 {
+				req.Header.Set("anthropic-version", anthropicApiVersion)
+			}
+		case "azure":
+			req.Header.Set("api-key", raw)
+		default:
+			req.Header.Set("Authorization", "Bearer "+raw)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = c.Request.RemoteAddr
+
+		if !isAnthropicProvider(setting) {
+			router.ServeHTTP(c.Writer, req)
+			return
+		}
+
+		uw := &unifiedResponseWriter{
+			ResponseWriter: c.Writer,
+			model:          model,
+			stream:         stream,
+			converter:      converter,
+			body:           bytes.NewBufferString(""),
+		}
+
+		router.ServeHTTP(uw, req)
+		uw.finish(log, prod)
+	}
+}
+
+// unifiedResponseWriter translates successful messages responses into chat
+// completion responses. Other responses are written as they are.
+type unifiedResponseWriter struct {
+	gin.ResponseWriter
+	model     string
+	stream    bool
+	converter *anthropic.StreamConverter
+	status    int
+	body      *bytes.Buffer
+	done      bool
+}
+
+func (w *unifiedResponseWriter) translating() bool {
+	return w.status == http.StatusOK
+}
+
+func (w *unifiedResponseWriter) WriteHeader(code int) {
+	w.status = code
+	if w.translating() {
+		w.Header().Del("Content-Length")
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *unifiedResponseWriter) Write(b []byte) (int, error) {
+	if !w.translating() {
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.body.Write(b)
+	if w.stream {
+		w.writeChunks()
+	}
+
+	return len(b), nil
+}
+
+func (w *unifiedResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// writeChunks converts the complete lines of the buffered stream.
+func (w *unifiedResponseWriter) writeChunks() {
+	for {
+		line, err := w.body.ReadBytes('\n')
+		if err != nil {
+			// keeps the incomplete line for the next write.
+			rest := append([]byte{}, line...)
+			w.body.Reset()
+			w.body.Write(rest)
+			return
+		}
+
+		w.writeChunk(line)
+	}
+}
+
+func (w *unifiedResponseWriter) writeChunk(line []byte) {
+	if w.done {
+		return
+	}
+
+	trimmed := bytes.TrimSpace(line)
+	if !bytes.HasPrefix(trimmed, []byte("data:")) {
+		return
+	}
+
+	data := bytes.TrimSpace(bytes.TrimPrefix(trimmed, []byte("data:")))
+	if len(data) == 0 {
+		return
+	}
+
+	chunks, done, err := w.converter.Convert(data)
+	if err != nil {
+		telemetry.Incr("bricksllm.proxy.unified_response_writer.convert_error", nil, 1)
+		return
+	}
+
+	for _, chunk := range chunks {
+		bs, err := json.Marshal(chunk)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.unified_response_writer.marshal_chunk_error", nil, 1)
+			continue
+		}
+
+		w.ResponseWriter.Write([]byte("data: "))
+		w.ResponseWriter.Write(bs)
+		w.ResponseWriter.Write([]byte("\n\n"))
+	}
+
+	if done {
+		w.writeDone()
+	}
+
+	w.ResponseWriter.Flush()
+}
+
+func (w *unifiedResponseWriter) writeDone() {
+	w.done = true
+	w.ResponseWriter.Write([]byte("data: [DONE]\n\n"))
+}
+
+func (w *unifiedResponseWriter) finish(log *zap.Logger, prod bool) {
+	if !w.translating() {
+		return
+	}
+
+	if w.stream {
+		if w.body.Len() != 0 {
+			w.writeChunk(w.body.Bytes())
+		}
+
+		if !w.done {
+			w.writeDone()
+		}
+
+		return
+	}
+
+	mr := &anthropic.MessagesResponse{}
+	err := json.Unmarshal(w.body.Bytes(), mr)
+	if err != nil {
+		telemetry.Incr("bricksllm.proxy.unified_response_writer.unmarshal_messages_response_error", nil, 1)
+		logError(log, "error when unmarshalling messages response", prod, err)
+		w.ResponseWriter.Write(w.body.Bytes())
+		return
+	}
+
+	bs, err := json.Marshal(anthropic.ConvertMessagesResponse(mr, w.model))
+	if err != nil {
+		telemetry.Incr("bricksllm.proxy.unified_response_writer.marshal_chat_completion_response_error", nil, 1)
+		logError(log, "error when marshalling chat completion response", prod, err)
+		w.ResponseWriter.Write(w.body.Bytes())
+		return
+	}
+
+	w.ResponseWriter.Write(bs)
+}
@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const keyUsagePath = "/api/usage"
+
+type keyUsageManager interface {
+	GetKeyUsage(k *key.ResponseKey) (*key.KeyUsage, error)
+}
+
+// getKeyUsageHandler lets key holders see the usage of their key against its
+// limits. The request is authenticated with the key itself.
+func getKeyUsageHandler(a authenticator, kum keyUsageManager, prod bool, log *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		telemetry.Incr("bricksllm.proxy.get_key_usage_handler.requests", nil, 1)
+
+		if c == nil || c.Request == nil {
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] context is empty")
+			return
+		}
+
+		kc, err := a.AuthenticateKey(c.Request)
+		if _, ok := err.(notAuthorizedError); ok {
+			telemetry.Incr("bricksllm.proxy.get_key_usage_handler.authentication_error", nil, 1)
+			logError(log, "error when authenticating key usage request", prod, err)
+			JSON(c, http.StatusUnauthorized, fmt.Sprintf("[BricksLLM] %v", err))
+			return
+		}
+
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_key_usage_handler.authenticate_key_error", nil, 1)
+			logError(log, "error when authenticating key usage request", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] internal authentication error")
+			return
+		}
+
+		usage, err := kum.GetKeyUsage(kc)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_key_usage_handler.get_key_usage_error", nil, 1)
+			logError(log, "error when getting key usage", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] error when getting key usage")
+			return
+		}
+
+		telemetry.Incr("bricksllm.proxy.get_key_usage_handler.success", nil, 1)
+		c.JSON(http.StatusOK, usage)
+	}
+}
@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -9,12 +10,61 @@ import (
 	"github.com/bricks-cloud/bricksllm/internal/telemetry"
 	"github.com/bricks-cloud/bricksllm/internal/util"
 	"github.com/gin-gonic/gin"
+	"io"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strings"
 )
 
+// xCustomUsageReader extracts token usage from an xCustom response while it
+// is being streamed to the client. Event streams are parsed event by event,
+// other responses are parsed once they have been fully read.
+type xCustomUsageReader struct {
+	io.ReadCloser
+	setting *provider.Setting
+	stream  bool
+	buf     bytes.Buffer
+	usage   *xcustom.Usage
+}
+
+func (r *xCustomUsageReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.buf.Write(p[:n])
+
+	if r.stream {
+		r.parseLines()
+	}
+
+	return n, err
+}
+
+func (r *xCustomUsageReader) parseLines() {
+	for {
+		idx := bytes.IndexByte(r.buf.Bytes(), '\n')
+		if idx < 0 {
+			return
+		}
+
+		line := bytes.TrimSpace(r.buf.Next(idx + 1))
+		if !bytes.HasPrefix(line, headerData) {
+			continue
+		}
+
+		xcustom.ExtractUsage(r.setting, bytes.TrimSpace(bytes.TrimPrefix(line, headerData)), r.usage)
+	}
+}
+
+func (r *xCustomUsageReader) finish() {
+	if r.stream {
+		r.buf.WriteByte('\n')
+		r.parseLines()
+		return
+	}
+
+	xcustom.ExtractUsage(r.setting, r.buf.Bytes(), r.usage)
+}
+
 func getXCustomHandler(prod bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log := util.GetLogFromCtx(c)
@@ -69,6 +119,57 @@ func getXCustomHandler(prod bool) gin.HandlerFunc {
 				r.Out.WithContext(ctx)
 			},
 		}
+
+		if !xcustom.TracksUsage(providerSetting) {
+			proxy.ServeHTTP(c.Writer, c.Request)
+			return
+		}
+
+		usage := &xcustom.Usage{}
+		if c.Request.Body != nil && c.Request.Method != http.MethodGet {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				logError(log, "error when reading x custom request body", prod, err)
+				JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read request body")
+				return
+			}
+
+			xcustom.ExtractUsage(providerSetting, body, usage)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		var ur *xCustomUsageReader
+		proxy.ModifyResponse = func(res *http.Response) error {
+			if res.StatusCode != http.StatusOK {
+				return nil
+			}
+
+			ur = &xCustomUsageReader{
+				ReadCloser: res.Body,
+				setting:    providerSetting,
+				stream:     strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream"),
+				usage:      usage,
+			}
+
+			res.Body = ur
+			return nil
+		}
+
 		proxy.ServeHTTP(c.Writer, c.Request)
+
+		if ur == nil {
+			return
+		}
+
+		ur.finish()
+
+		if len(usage.Model) != 0 {
+			c.Set("model", usage.Model)
+		}
+
+		c.Set("promptTokenCount", usage.PromptTokens)
+		c.Set("completionTokenCount", usage.CompletionTokens)
+
+		telemetry.Incr("bricksllm.proxy.get_x_custom_handler.usage_extracted", nil, 1)
 	}
 }
@@ -0,0 +1,144 @@
+package memdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/budget"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+type BudgetsStorage interface {
+	GetAllBudgets() ([]*budget.Budget, error)
+	GetUpdatedBudgets(updatedAt int64) ([]*budget.Budget, error)
+}
+
+type BudgetsMemDb struct {
+	external    BudgetsStorage
+	lastUpdated int64
+	idToBudget  map[string]*budget.Budget
+	lock        sync.RWMutex
+	done        chan bool
+	interval    time.Duration
+	log         *zap.Logger
+}
+
+func NewBudgetsMemDb(ex BudgetsStorage, log *zap.Logger, interval time.Duration) (*BudgetsMemDb, error) {
+	budgets, err := ex.GetAllBudgets()
+	if err != nil {
+		return nil, err
+	}
+
+	idToBudget := map[string]*budget.Budget{}
+	var latest int64 = -1
+	for _, b := range budgets {
+		idToBudget[b.Id] = b
+		if b.UpdatedAt > latest {
+			latest = b.UpdatedAt
+		}
+	}
+
+	if len(budgets) != 0 {
+		log.Sugar().Infof("budgets memdb updated at %d with %d budgets", latest, len(budgets))
+	}
+
+	return &BudgetsMemDb{
+		external:    ex,
+		lastUpdated: latest,
+		idToBudget:  idToBudget,
+		done:        make(chan bool),
+		interval:    interval,
+		log:         log,
+	}, nil
+}
+
+// GetKeyBudgets returns the budgets that cover the key through its key ring
+// or one of its tags.
+func (mdb *BudgetsMemDb) GetKeyBudgets(k *key.ResponseKey) []*budget.Budget {
+	mdb.lock.RLock()
+	defer mdb.lock.RUnlock()
+
+	budgets := []*budget.Budget{}
+	for _, b := range mdb.idToBudget {
+		if b.Matches(k) {
+			budgets = append(budgets, b)
+		}
+	}
+
+	return budgets
+}
+
+func (mdb *BudgetsMemDb) GetBudget(id string) *budget.Budget {
+	mdb.lock.RLock()
+	defer mdb.lock.RUnlock()
+
+	b, ok := mdb.idToBudget[id]
+	if ok {
+		return b
+	}
+
+	return nil
+}
+
+func (mdb *BudgetsMemDb) SetBudget(b *budget.Budget) {
+	mdb.lock.Lock()
+	defer mdb.lock.Unlock()
+
+	if b.Archived {
+		delete(mdb.idToBudget, b.Id)
+		return
+	}
+
+	mdb.idToBudget[b.Id] = b
+}
+
+func (mdb *BudgetsMemDb) Listen() {
+	ticker := time.NewTicker(mdb.interval)
+	mdb.log.Info("budgets memdb started listening for budget updates")
+
+	go func() {
+		lastUpdated := mdb.lastUpdated
+
+		for {
+			select {
+			case <-mdb.done:
+				mdb.log.Info("budgets memdb stopped")
+				return
+			case <-ticker.C:
+				budgets, err := mdb.external.GetUpdatedBudgets(lastUpdated)
+				if err != nil {
+					telemetry.Incr("bricksllm.memdb.budgets_memdb.listen.get_updated_budgets_error", nil, 1)
+
+					mdb.log.Sugar().Debugf("memdb failed to get budgets: %v", err)
+					continue
+				}
+
+				numberOfUpdated := 0
+				for _, b := range budgets {
+					if b.UpdatedAt > lastUpdated {
+						lastUpdated = b.UpdatedAt
+					}
+
+					existing := mdb.GetBudget(b.Id)
+					if (existing == nil && !b.Archived) || (existing != nil && b.UpdatedAt > existing.UpdatedAt) {
+						mdb.log.Sugar().Infof("budgets memdb updated a budget: %s", b.Id)
+						numberOfUpdated++
+						mdb.SetBudget(b)
+					}
+				}
+
+				if numberOfUpdated != 0 {
+					mdb.log.Sugar().Infof("budgets memdb updated at %d with %d budgets", lastUpdated, numberOfUpdated)
+				}
+			}
+		}
+	}()
+}
+
+func (mdb *BudgetsMemDb) Stop() {
+	mdb.log.Info("shutting down budgets memdb...")
+
+	mdb.done <- true
+}
@@ -0,0 +1,204 @@
+package memdb
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/pricing"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+type PricesStorage interface {
+	GetAllPrices() ([]*pricing.Price, error)
+	GetUpdatedPrices(updatedAt int64) ([]*pricing.Price, error)
+}
+
+type cachedCostMap struct {
+	costMap    map[string]map[string]float64
+	validUntil int64
+}
+
+// PricingMemDb keeps the pricing catalog in memory and merges it with the
+// built-in cost maps of the providers. Merged cost maps are cached per
+// provider until the catalog changes or a scheduled price takes effect.
+type PricingMemDb struct {
+	external    PricesStorage
+	lastUpdated int64
+	idToPrice   map[string]*pricing.Price
+	cached      map[string]*cachedCostMap
+	lock        sync.RWMutex
+	done        chan bool
+	interval    time.Duration
+	log         *zap.Logger
+}
+
+func NewPricingMemDb(ex PricesStorage, log *zap.Logger, interval time.Duration) (*PricingMemDb, error) {
+	prices, err := ex.GetAllPrices()
+	if err != nil {
+		return nil, err
+	}
+
+	idToPrice := map[string]*pricing.Price{}
+	var latest int64 = -1
+	for _, p := range prices {
+		idToPrice[p.Id] = p
+		if p.UpdatedAt > latest {
+			latest = p.UpdatedAt
+		}
+	}
+
+	if len(prices) != 0 {
+		log.Sugar().Infof("pricing memdb updated at %d with %d prices", latest, len(prices))
+	}
+
+	return &PricingMemDb{
+		external:    ex,
+		lastUpdated: latest,
+		idToPrice:   idToPrice,
+		cached:      map[string]*cachedCostMap{},
+		done:        make(chan bool),
+		interval:    interval,
+		log:         log,
+	}, nil
+}
+
+func (mdb *PricingMemDb) getPricesByProvider(provider string) []*pricing.Price {
+	prices := []*pricing.Price{}
+	for _, p := range mdb.idToPrice {
+		if p.Provider == provider {
+			prices = append(prices, p)
+		}
+	}
+
+	return prices
+}
+
+// GetPrices returns the prices of the provider that are in the catalog.
+func (mdb *PricingMemDb) GetPrices(provider string) []*pricing.Price {
+	mdb.lock.RLock()
+	defer mdb.lock.RUnlock()
+
+	return mdb.getPricesByProvider(provider)
+}
+
+// GetCostMap returns the built-in cost map of the provider with the catalog
+// prices that are currently in effect applied on top of it.
+func (mdb *PricingMemDb) GetCostMap(provider string, base map[string]map[string]float64) map[string]map[string]float64 {
+	now := time.Now().Unix()
+
+	mdb.lock.RLock()
+	cached, ok := mdb.cached[provider]
+	mdb.lock.RUnlock()
+
+	if ok && now < cached.validUntil {
+		return cached.costMap
+	}
+
+	mdb.lock.Lock()
+	defer mdb.lock.Unlock()
+
+	prices := mdb.getPricesByProvider(provider)
+	costMap := pricing.Apply(base, prices, now)
+	mdb.cached[provider] = &cachedCostMap{
+		costMap:    costMap,
+		validUntil: pricing.NextChange(prices, now),
+	}
+
+	return costMap
+}
+
+// HasModel reports whether the catalog has a price in effect for the model.
+func (mdb *PricingMemDb) HasModel(provider, model string) bool {
+	now := time.Now().Unix()
+
+	mdb.lock.RLock()
+	defer mdb.lock.RUnlock()
+
+	for _, p := range mdb.idToPrice {
+		if p.Provider == provider && p.EffectiveFrom <= now && strings.ToLower(p.Model) == model {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (mdb *PricingMemDb) GetPrice(id string) *pricing.Price {
+	mdb.lock.RLock()
+	defer mdb.lock.RUnlock()
+
+	p, ok := mdb.idToPrice[id]
+	if ok {
+		return p
+	}
+
+	return nil
+}
+
+func (mdb *PricingMemDb) SetPrice(p *pricing.Price) {
+	mdb.lock.Lock()
+	defer mdb.lock.Unlock()
+
+	if existing, ok := mdb.idToPrice[p.Id]; ok {
+		delete(mdb.cached, existing.Provider)
+	}
+
+	if p.Archived {
+		delete(mdb.idToPrice, p.Id)
+	} else {
+		mdb.idToPrice[p.Id] = p
+	}
+
+	delete(mdb.cached, p.Provider)
+}
+
+func (mdb *PricingMemDb) Listen() {
+	ticker := time.NewTicker(mdb.interval)
+	mdb.log.Info("pricing memdb started listening for price updates")
+
+	go func() {
+		lastUpdated := mdb.lastUpdated
+
+		for {
+			select {
+			case <-mdb.done:
+				mdb.log.Info("pricing memdb stopped")
+				return
+			case <-ticker.C:
+				prices, err := mdb.external.GetUpdatedPrices(lastUpdated)
+				if err != nil {
+					telemetry.Incr("bricksllm.memdb.pricing_memdb.listen.get_updated_prices_error", nil, 1)
+
+					mdb.log.Sugar().Debugf("memdb failed to get prices: %v", err)
+					continue
+				}
+
+				numberOfUpdated := 0
+				for _, p := range prices {
+					if p.UpdatedAt > lastUpdated {
+						lastUpdated = p.UpdatedAt
+					}
+
+					existing := mdb.GetPrice(p.Id)
+					if (existing == nil && !p.Archived) || (existing != nil && p.UpdatedAt > existing.UpdatedAt) {
+						mdb.log.Sugar().Infof("pricing memdb updated a price: %s", p.Id)
+						numberOfUpdated++
+						mdb.SetPrice(p)
+					}
+				}
+
+				if numberOfUpdated != 0 {
+					mdb.log.Sugar().Infof("pricing memdb updated at %d with %d prices", lastUpdated, numberOfUpdated)
+				}
+			}
+		}
+	}()
+}
+
+func (mdb *PricingMemDb) Stop() {
+	mdb.log.Info("shutting down pricing memdb...")
+
+	mdb.done <- true
+}
@@ -0,0 +1,109 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/bricks-cloud/bricksllm/internal/batch"
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+)
+
+func (s *Store) CreateBatchesTable() error {
+	createTableQuery := `
+	CREATE TABLE IF NOT EXISTS batches (
+		id VARCHAR(255) PRIMARY KEY,
+		key_id VARCHAR(255) NOT NULL,
+		user_id VARCHAR(255) NOT NULL DEFAULT '',
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		endpoint VARCHAR(255) NOT NULL,
+		status VARCHAR(255) NOT NULL,
+		cost_in_usd FLOAT8 NOT NULL DEFAULT 0,
+		recorded_at BIGINT NOT NULL DEFAULT 0
+	)`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, createTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) CreateBatch(b *batch.Batch) error {
+	query := `
+	INSERT INTO batches (id, key_id, user_id, created_at, updated_at, endpoint, status, cost_in_usd, recorded_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (id) DO NOTHING
+`
+
+	values := []any{
+		b.Id,
+		b.KeyId,
+		b.UserId,
+		b.CreatedAt,
+		b.UpdatedAt,
+		b.Endpoint,
+		b.Status,
+		b.CostInUsd,
+		b.RecordedAt,
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctxTimeout, query, values...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) GetBatch(id string) (*batch.Batch, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	b := &batch.Batch{}
+	if err := s.db.QueryRowContext(ctxTimeout, "SELECT * FROM batches WHERE id = $1", id).Scan(
+		&b.Id,
+		&b.KeyId,
+		&b.UserId,
+		&b.CreatedAt,
+		&b.UpdatedAt,
+		&b.Endpoint,
+		&b.Status,
+		&b.CostInUsd,
+		&b.RecordedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("batch is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// MarkBatchRecorded stores the cost of a completed batch. It reports false
+// when the batch has already been recorded, so that concurrent retrievals of
+// the same batch record its spend only once.
+func (s *Store) MarkBatchRecorded(id, status string, costInUsd float64, recordedAt int64) (bool, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctxTimeout, "UPDATE batches SET status = $2, cost_in_usd = $3, recorded_at = $4, updated_at = $4 WHERE id = $1 AND recorded_at = 0", id, status, costInUsd, recordedAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
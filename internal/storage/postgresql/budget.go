@@ -0,0 +1,254 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/bricks-cloud/bricksllm/internal/budget"
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+)
+
+func (s *Store) CreateBudgetsTable() error {
+	createTableQuery := `
+	CREATE TABLE IF NOT EXISTS budgets (
+		id VARCHAR(255) PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		key_ring VARCHAR(255) NOT NULL DEFAULT '',
+		tag VARCHAR(255) NOT NULL DEFAULT '',
+		cost_limit_in_usd_over_time FLOAT8 NOT NULL DEFAULT 0,
+		cost_limit_in_usd_unit VARCHAR(255) NOT NULL DEFAULT '',
+		rate_limit_over_time INT NOT NULL DEFAULT 0,
+		rate_limit_unit VARCHAR(255) NOT NULL DEFAULT '',
+		archived BOOLEAN NOT NULL DEFAULT FALSE
+	)`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, createTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) CreateBudget(b *budget.Budget) (*budget.Budget, error) {
+	query := `
+	INSERT INTO budgets (id, name, created_at, updated_at, key_ring, tag, cost_limit_in_usd_over_time, cost_limit_in_usd_unit, rate_limit_over_time, rate_limit_unit, archived)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	RETURNING id, name, created_at, updated_at, key_ring, tag, cost_limit_in_usd_over_time, cost_limit_in_usd_unit, rate_limit_over_time, rate_limit_unit, archived
+`
+
+	values := []any{
+		b.Id,
+		b.Name,
+		b.CreatedAt,
+		b.UpdatedAt,
+		b.KeyRing,
+		b.Tag,
+		b.CostLimitInUsdOverTime,
+		b.CostLimitInUsdUnit,
+		b.RateLimitOverTime,
+		b.RateLimitUnit,
+		b.Archived,
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	created := &budget.Budget{}
+	if err := s.db.QueryRowContext(ctxTimeout, query, values...).Scan(
+		&created.Id,
+		&created.Name,
+		&created.CreatedAt,
+		&created.UpdatedAt,
+		&created.KeyRing,
+		&created.Tag,
+		&created.CostLimitInUsdOverTime,
+		&created.CostLimitInUsdUnit,
+		&created.RateLimitOverTime,
+		&created.RateLimitUnit,
+		&created.Archived,
+	); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *Store) UpdateBudget(id string, ub *budget.UpdateBudget) (*budget.Budget, error) {
+	values := []any{
+		id,
+		ub.UpdatedAt,
+	}
+
+	fields := []string{"updated_at = $2"}
+
+	if len(ub.Name) != 0 {
+		values = append(values, ub.Name)
+		fields = append(fields, fmt.Sprintf("name = $%d", len(values)))
+	}
+
+	if ub.CostLimitInUsdOverTime != nil {
+		values = append(values, *ub.CostLimitInUsdOverTime)
+		fields = append(fields, fmt.Sprintf("cost_limit_in_usd_over_time = $%d", len(values)))
+	}
+
+	if ub.CostLimitInUsdUnit != nil {
+		values = append(values, *ub.CostLimitInUsdUnit)
+		fields = append(fields, fmt.Sprintf("cost_limit_in_usd_unit = $%d", len(values)))
+	}
+
+	if ub.RateLimitOverTime != nil {
+		values = append(values, *ub.RateLimitOverTime)
+		fields = append(fields, fmt.Sprintf("rate_limit_over_time = $%d", len(values)))
+	}
+
+	if ub.RateLimitUnit != nil {
+		values = append(values, *ub.RateLimitUnit)
+		fields = append(fields, fmt.Sprintf("rate_limit_unit = $%d", len(values)))
+	}
+
+	query := fmt.Sprintf("UPDATE budgets SET %s WHERE id = $1 AND archived = FALSE RETURNING *", strings.Join(fields, ","))
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	updated := &budget.Budget{}
+	if err := s.db.QueryRowContext(ctxTimeout, query, values...).Scan(
+		&updated.Id,
+		&updated.Name,
+		&updated.CreatedAt,
+		&updated.UpdatedAt,
+		&updated.KeyRing,
+		&updated.Tag,
+		&updated.CostLimitInUsdOverTime,
+		&updated.CostLimitInUsdUnit,
+		&updated.RateLimitOverTime,
+		&updated.RateLimitUnit,
+		&updated.Archived,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("budget is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (s *Store) ArchiveBudget(id string, updatedAt int64) error {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctxTimeout, "UPDATE budgets SET archived = TRUE, updated_at = $2 WHERE id = $1 AND archived = FALSE", id, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return internal_errors.NewNotFoundError("budget is not found for id: " + id)
+	}
+
+	return nil
+}
+
+func (s *Store) GetBudget(id string) (*budget.Budget, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	b := &budget.Budget{}
+	if err := s.db.QueryRowContext(ctxTimeout, "SELECT * FROM budgets WHERE id = $1 AND archived = FALSE", id).Scan(
+		&b.Id,
+		&b.Name,
+		&b.CreatedAt,
+		&b.UpdatedAt,
+		&b.KeyRing,
+		&b.Tag,
+		&b.CostLimitInUsdOverTime,
+		&b.CostLimitInUsdUnit,
+		&b.RateLimitOverTime,
+		&b.RateLimitUnit,
+		&b.Archived,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("budget is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (s *Store) GetBudgets(keyRing, tag string) ([]*budget.Budget, error) {
+	conditions := []string{"archived = FALSE"}
+	values := []any{}
+
+	if len(keyRing) != 0 {
+		values = append(values, keyRing)
+		conditions = append(conditions, fmt.Sprintf("key_ring = $%d", len(values)))
+	}
+
+	if len(tag) != 0 {
+		values = append(values, tag)
+		conditions = append(conditions, fmt.Sprintf("tag = $%d", len(values)))
+	}
+
+	query := fmt.Sprintf("SELECT * FROM budgets WHERE %s ORDER BY created_at DESC", strings.Join(conditions, " AND "))
+
+	return s.queryBudgets(query, values...)
+}
+
+func (s *Store) GetAllBudgets() ([]*budget.Budget, error) {
+	return s.queryBudgets("SELECT * FROM budgets WHERE archived = FALSE")
+}
+
+func (s *Store) GetUpdatedBudgets(updatedAt int64) ([]*budget.Budget, error) {
+	return s.queryBudgets("SELECT * FROM budgets WHERE updated_at >= $1", updatedAt)
+}
+
+func (s *Store) queryBudgets(query string, args ...any) ([]*budget.Budget, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctxTimeout, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bs := []*budget.Budget{}
+	for rows.Next() {
+		b := &budget.Budget{}
+		if err := rows.Scan(
+			&b.Id,
+			&b.Name,
+			&b.CreatedAt,
+			&b.UpdatedAt,
+			&b.KeyRing,
+			&b.Tag,
+			&b.CostLimitInUsdOverTime,
+			&b.CostLimitInUsdUnit,
+			&b.RateLimitOverTime,
+			&b.RateLimitUnit,
+			&b.Archived,
+		); err != nil {
+			return nil, err
+		}
+
+		bs = append(bs, b)
+	}
+
+	return bs, nil
+}
@@ -0,0 +1,163 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/bricks-cloud/bricksllm/internal/credit"
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+)
+
+func (s *Store) CreateCreditTransactionsTable() error {
+	createTableQuery := `
+	CREATE TABLE IF NOT EXISTS credit_transactions (
+		id VARCHAR(255) PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		type VARCHAR(255) NOT NULL,
+		amount_in_usd FLOAT8 NOT NULL,
+		balance_in_usd FLOAT8 NOT NULL,
+		event_id VARCHAR(255) NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		created_at BIGINT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS credit_transactions_user_id_created_at_idx ON credit_transactions(user_id, created_at);
+	CREATE UNIQUE INDEX IF NOT EXISTS credit_transactions_event_id_idx ON credit_transactions(event_id) WHERE event_id <> '';`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, createTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateCreditTransaction applies a top-up or an adjustment to the credit
+// balance of a user and records it in the ledger within a single transaction.
+func (s *Store) CreateCreditTransaction(t *credit.Transaction) (*credit.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, "UPDATE users SET credit_balance_in_usd = COALESCE(credit_balance_in_usd, 0) + $2 WHERE id = $1 RETURNING credit_balance_in_usd", t.UserId, t.AmountInUsd).Scan(&t.BalanceInUsd)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("user is not found for id: " + t.UserId)
+		}
+
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO credit_transactions (id, user_id, type, amount_in_usd, balance_in_usd, event_id, description, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", t.Id, t.UserId, t.Type, t.AmountInUsd, t.BalanceInUsd, t.EventId, t.Description, t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// DebitCredits subtracts the cost of an event from the credit balance of a
+// user. Users without a credit balance are not debited and an event is only
+// debited once.
+func (s *Store) DebitCredits(t *credit.Transaction) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, "UPDATE users SET credit_balance_in_usd = credit_balance_in_usd + $2 WHERE id = $1 AND credit_balance_in_usd IS NOT NULL RETURNING credit_balance_in_usd", t.UserId, t.AmountInUsd).Scan(&t.BalanceInUsd)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO credit_transactions (id, user_id, type, amount_in_usd, balance_in_usd, event_id, description, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (event_id) WHERE event_id <> '' DO NOTHING", t.Id, t.UserId, t.Type, t.AmountInUsd, t.BalanceInUsd, t.EventId, t.Description, t.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// the event has already been debited, so the balance update is rolled back.
+	if affected == 0 {
+		return nil
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) GetCreditBalance(userId string) (float64, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	var balance float64
+	err := s.db.QueryRowContext(ctxTimeout, "SELECT COALESCE(credit_balance_in_usd, 0) FROM users WHERE id = $1", userId).Scan(&balance)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, internal_errors.NewNotFoundError("user is not found for id: " + userId)
+		}
+
+		return 0, err
+	}
+
+	return balance, nil
+}
+
+func (s *Store) GetCreditTransactions(userId string, offset, limit int) ([]*credit.Transaction, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	query := "SELECT * FROM credit_transactions WHERE user_id = $1 ORDER BY created_at DESC"
+	if limit != 0 {
+		query += fmt.Sprintf(" OFFSET %d LIMIT %d", offset, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctxTimeout, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []*credit.Transaction{}
+	for rows.Next() {
+		t := &credit.Transaction{}
+		if err := rows.Scan(
+			&t.Id,
+			&t.UserId,
+			&t.Type,
+			&t.AmountInUsd,
+			&t.BalanceInUsd,
+			&t.EventId,
+			&t.Description,
+			&t.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
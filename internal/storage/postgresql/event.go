@@ -44,7 +44,7 @@ func (s *Store) CreateEventsByDayTable() error {
 
 func (s *Store) AlterEventsTable() error {
 	alterTableQuery := `
-		ALTER TABLE events ADD COLUMN IF NOT EXISTS path VARCHAR(255), ADD COLUMN IF NOT EXISTS method VARCHAR(255), ADD COLUMN IF NOT EXISTS custom_id VARCHAR(255), ADD COLUMN IF NOT EXISTS request JSONB, ADD COLUMN IF NOT EXISTS response JSONB, ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS action VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS policy_id VARCHAR(255) NOT NULL DEFAULT '',  ADD COLUMN IF NOT EXISTS route_id VARCHAR(255) NOT NULL DEFAULT '',  ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS metadata JSONB;
+		ALTER TABLE events ADD COLUMN IF NOT EXISTS path VARCHAR(255), ADD COLUMN IF NOT EXISTS method VARCHAR(255), ADD COLUMN IF NOT EXISTS custom_id VARCHAR(255), ADD COLUMN IF NOT EXISTS request JSONB, ADD COLUMN IF NOT EXISTS response JSONB, ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS action VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS policy_id VARCHAR(255) NOT NULL DEFAULT '',  ADD COLUMN IF NOT EXISTS route_id VARCHAR(255) NOT NULL DEFAULT '',  ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS metadata JSONB, ADD COLUMN IF NOT EXISTS cache_hit BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS response_action VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS cached_prompt_token_count INT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS cache_creation_token_count INT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS priced_with_cost_map BOOLEAN NOT NULL DEFAULT FALSE;
 	`
 
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -225,6 +225,11 @@ func (s *Store) GetEvents(userId string, customId string, keyIds []string, start
 			&e.RouteId,
 			&e.CorrelationId,
 			&e.Metadata,
+			&e.CacheHit,
+			&e.ResponseAction,
+			&e.CachedPromptTokenCount,
+			&e.CacheCreationTokenCount,
+			&e.PricedWithCostMap,
 		); err != nil {
 			return nil, err
 		}
@@ -710,7 +715,7 @@ func (s *Store) GetAggregatedEventByDayDataPoints(start, end int64, keyIds []str
 
 func (s *Store) GetEventDataPoints(start, end, increment int64, tags, keyIds, customIds, userIds []string, filters []string) ([]*event.DataPoint, error) {
 	groupByQuery := "GROUP BY time_series_table.series"
-	selectQuery := "SELECT series AS time_stamp, COALESCE(COUNT(events_table.event_id),0) AS num_of_requests, COALESCE(SUM(events_table.cost_in_usd),0) AS cost_in_usd, COALESCE(SUM(events_table.latency_in_ms),0) AS latency_in_ms, COALESCE(SUM(events_table.prompt_token_count),0) AS prompt_token_count, COALESCE(SUM(events_table.completion_token_count),0) AS completion_token_count, COALESCE(SUM(CASE WHEN status_code = 200 THEN 1 END),0) AS success_count"
+	selectQuery := "SELECT series AS time_stamp, COALESCE(COUNT(events_table.event_id),0) AS num_of_requests, COALESCE(SUM(events_table.cost_in_usd),0) AS cost_in_usd, COALESCE(SUM(events_table.latency_in_ms),0) AS latency_in_ms, COALESCE(SUM(events_table.prompt_token_count),0) AS prompt_token_count, COALESCE(SUM(events_table.completion_token_count),0) AS completion_token_count, COALESCE(SUM(events_table.cached_prompt_token_count),0) AS cached_prompt_token_count, COALESCE(SUM(CASE WHEN status_code = 200 THEN 1 END),0) AS success_count"
 
 	if len(filters) != 0 {
 		for _, filter := range filters {
@@ -805,6 +810,7 @@ func (s *Store) GetEventDataPoints(start, end, increment int64, tags, keyIds, cu
 			&e.LatencyInMs,
 			&e.PromptTokenCount,
 			&e.CompletionTokenCount,
+			&e.CachedPromptTokenCount,
 			&e.SuccessCount,
 		}
 
@@ -959,6 +965,11 @@ func (s *Store) GetEventsV2(req *event.EventRequest) (*event.EventResponse, erro
 			&e.RouteId,
 			&e.CorrelationId,
 			&e.Metadata,
+			&e.CacheHit,
+			&e.ResponseAction,
+			&e.CachedPromptTokenCount,
+			&e.CacheCreationTokenCount,
+			&e.PricedWithCostMap,
 		); err != nil {
 			return nil, err
 		}
@@ -988,8 +999,9 @@ func (s *Store) InsertEvent(e *event.Event) error {
 	}
 
 	query := `
-		INSERT INTO events (event_id, created_at, tags, key_id, cost_in_usd, provider, model, status_code, prompt_token_count, completion_token_count, latency_in_ms, path, method, custom_id, request, response, user_id, action, policy_id, route_id, correlation_id, metadata)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
+		INSERT INTO events (event_id, created_at, tags, key_id, cost_in_usd, provider, model, status_code, prompt_token_count, completion_token_count, latency_in_ms, path, method, custom_id, request, response, user_id, action, policy_id, route_id, correlation_id, metadata, cache_hit, response_action, cached_prompt_token_count, cache_creation_token_count, priced_with_cost_map)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27)
+		ON CONFLICT (event_id) DO NOTHING
 	`
 
 	values := []any{
@@ -1015,6 +1027,11 @@ func (s *Store) InsertEvent(e *event.Event) error {
 		e.RouteId,
 		e.CorrelationId,
 		e.Metadata,
+		e.CacheHit,
+		e.ResponseAction,
+		e.CachedPromptTokenCount,
+		e.CacheCreationTokenCount,
+		e.PricedWithCostMap,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -1025,3 +1042,130 @@ func (s *Store) InsertEvent(e *event.Event) error {
 
 	return nil
 }
+
+func repricingConditions(start, end int64, provider, model string) (string, []any) {
+	conditions := []string{"created_at >= $1", "created_at < $2"}
+	values := []any{start, end}
+
+	if len(provider) != 0 {
+		values = append(values, provider)
+		conditions = append(conditions, fmt.Sprintf("provider = $%d", len(values)))
+	}
+
+	if len(model) != 0 {
+		values = append(values, model)
+		conditions = append(conditions, fmt.Sprintf("model = $%d", len(values)))
+	}
+
+	return strings.Join(conditions, " AND "), values
+}
+
+func (s *Store) CountEventsForRepricing(start, end int64, provider, model string) (int, error) {
+	conditions, values := repricingConditions(start, end, provider, model)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	count := 0
+	if err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM events WHERE %s", conditions), values...).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetEventsForRepricing returns the events created after the given cursor in
+// ascending order of creation time. Only the fields needed to price an event
+// are populated.
+func (s *Store) GetEventsForRepricing(start, end int64, provider, model string, afterCreatedAt int64, afterId string, limit int) ([]*event.Event, error) {
+	conditions, values := repricingConditions(start, end, provider, model)
+
+	values = append(values, afterCreatedAt, afterId, limit)
+	query := fmt.Sprintf(`
+		SELECT event_id, created_at, key_id, cost_in_usd, provider, model, status_code, prompt_token_count, completion_token_count, cached_prompt_token_count, cache_creation_token_count, path, cache_hit, priced_with_cost_map
+		FROM events
+		WHERE %s AND (created_at, event_id) > ($%d, $%d)
+		ORDER BY created_at, event_id
+		LIMIT $%d
+	`, conditions, len(values)-2, len(values)-1, len(values))
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*event.Event{}
+	for rows.Next() {
+		var path sql.NullString
+
+		e := &event.Event{}
+		if err := rows.Scan(
+			&e.Id,
+			&e.CreatedAt,
+			&e.KeyId,
+			&e.CostInUsd,
+			&e.Provider,
+			&e.Model,
+			&e.Status,
+			&e.PromptTokenCount,
+			&e.CompletionTokenCount,
+			&e.CachedPromptTokenCount,
+			&e.CacheCreationTokenCount,
+			&path,
+			&e.CacheHit,
+			&e.PricedWithCostMap,
+		); err != nil {
+			return nil, err
+		}
+
+		e.Path = path.String
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// UpdateEventCosts updates the cost of events and the aggregated cost of the
+// days they were created in within a single transaction.
+func (s *Store) UpdateEventCosts(updates []*event.CostUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	type dayKey struct {
+		day   int64
+		keyId string
+	}
+
+	deltas := map[dayKey]float64{}
+	for _, u := range updates {
+		if _, err := tx.ExecContext(ctx, "UPDATE events SET cost_in_usd = $2 WHERE event_id = $1", u.EventId, u.CostInUsd); err != nil {
+			return err
+		}
+
+		day := u.CreatedAt - u.CreatedAt%86400
+		deltas[dayKey{day: day, keyId: u.KeyId}] += u.CostInUsd - u.PreviousCostInUsd
+	}
+
+	for k, delta := range deltas {
+		if _, err := tx.ExecContext(ctx, "UPDATE event_agg_by_day SET cost_in_usd = cost_in_usd + $1 WHERE key_id = $2 AND time_stamp >= $3 AND time_stamp < $4", delta, k.keyId, k.day, k.day+86400); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -57,7 +57,7 @@ func (s *Store) AlterKeysTable() error {
 			END IF;
 		END
 		$$;
-		ALTER TABLE keys ADD COLUMN IF NOT EXISTS setting_id VARCHAR(255), ADD COLUMN IF NOT EXISTS allowed_paths JSONB, ADD COLUMN IF NOT EXISTS setting_ids VARCHAR(255)[] NOT NULL DEFAULT ARRAY[]::VARCHAR(255)[], ADD COLUMN IF NOT EXISTS should_log_request BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS should_log_response BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS rotation_enabled BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS policy_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS is_key_not_hashed BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS requests_limit INT NOT NULL DEFAULT 0;
+		ALTER TABLE keys ADD COLUMN IF NOT EXISTS setting_id VARCHAR(255), ADD COLUMN IF NOT EXISTS allowed_paths JSONB, ADD COLUMN IF NOT EXISTS setting_ids VARCHAR(255)[] NOT NULL DEFAULT ARRAY[]::VARCHAR(255)[], ADD COLUMN IF NOT EXISTS should_log_request BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS should_log_response BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS rotation_enabled BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS policy_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS is_key_not_hashed BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS requests_limit INT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS cache_config JSONB, ADD COLUMN IF NOT EXISTS retry_config JSONB, ADD COLUMN IF NOT EXISTS token_limit_over_time INT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS token_limit_unit VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS rate_limit_algorithm VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS budget_alert_config JSONB, ADD COLUMN IF NOT EXISTS max_concurrent_requests INT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS model_limits JSONB;
 	`
 
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -166,6 +166,10 @@ func (s *Store) GetKeys(tags, keyIds []string, provider string) ([]*key.Response
 		var k key.ResponseKey
 		var settingId sql.NullString
 		var data []byte
+		var cacheConfigData []byte
+		var retryConfigData []byte
+		var budgetAlertConfigData []byte
+		var modelLimitsData []byte
 		if err := rows.Scan(
 			&k.Name,
 			&k.CreatedAt,
@@ -191,6 +195,14 @@ func (s *Store) GetKeys(tags, keyIds []string, provider string) ([]*key.Response
 			&k.PolicyId,
 			&k.IsKeyNotHashed,
 			&k.RequestsLimit,
+			&cacheConfigData,
+			&retryConfigData,
+			&k.TokenLimitOverTime,
+			&k.TokenLimitUnit,
+			&k.RateLimitAlgorithm,
+			&budgetAlertConfigData,
+			&k.MaxConcurrentRequests,
+			&modelLimitsData,
 		); err != nil {
 			return nil, err
 		}
@@ -207,6 +219,42 @@ func (s *Store) GetKeys(tags, keyIds []string, provider string) ([]*key.Response
 			pk.AllowedPaths = pathConfigs
 		}
 
+		if len(cacheConfigData) != 0 {
+			cc := &key.CacheConfig{}
+			if err := json.Unmarshal(cacheConfigData, cc); err != nil {
+				return nil, err
+			}
+
+			pk.CacheConfig = cc
+		}
+
+		if len(retryConfigData) != 0 {
+			rc := &key.RetryConfig{}
+			if err := json.Unmarshal(retryConfigData, rc); err != nil {
+				return nil, err
+			}
+
+			pk.RetryConfig = rc
+		}
+
+		if len(budgetAlertConfigData) != 0 {
+			bac := &key.BudgetAlertConfig{}
+			if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+				return nil, err
+			}
+
+			pk.BudgetAlertConfig = bac
+		}
+
+		if len(modelLimitsData) != 0 {
+			mls := map[string]*key.ModelLimit{}
+			if err := json.Unmarshal(modelLimitsData, &mls); err != nil {
+				return nil, err
+			}
+
+			pk.ModelLimits = mls
+		}
+
 		keys = append(keys, pk)
 	}
 
@@ -292,6 +340,10 @@ func (s *Store) GetKeysV2(tags, keyIds []string, revoked *bool, limit, offset in
 		var k key.ResponseKey
 		var settingId sql.NullString
 		var data []byte
+		var cacheConfigData []byte
+		var retryConfigData []byte
+		var budgetAlertConfigData []byte
+		var modelLimitsData []byte
 		if err := rows.Scan(
 			&k.Name,
 			&k.CreatedAt,
@@ -317,6 +369,14 @@ func (s *Store) GetKeysV2(tags, keyIds []string, revoked *bool, limit, offset in
 			&k.PolicyId,
 			&k.IsKeyNotHashed,
 			&k.RequestsLimit,
+			&cacheConfigData,
+			&retryConfigData,
+			&k.TokenLimitOverTime,
+			&k.TokenLimitUnit,
+			&k.RateLimitAlgorithm,
+			&budgetAlertConfigData,
+			&k.MaxConcurrentRequests,
+			&modelLimitsData,
 		); err != nil {
 			return nil, err
 		}
@@ -333,6 +393,42 @@ func (s *Store) GetKeysV2(tags, keyIds []string, revoked *bool, limit, offset in
 			pk.AllowedPaths = pathConfigs
 		}
 
+		if len(cacheConfigData) != 0 {
+			cc := &key.CacheConfig{}
+			if err := json.Unmarshal(cacheConfigData, cc); err != nil {
+				return nil, err
+			}
+
+			pk.CacheConfig = cc
+		}
+
+		if len(retryConfigData) != 0 {
+			rc := &key.RetryConfig{}
+			if err := json.Unmarshal(retryConfigData, rc); err != nil {
+				return nil, err
+			}
+
+			pk.RetryConfig = rc
+		}
+
+		if len(budgetAlertConfigData) != 0 {
+			bac := &key.BudgetAlertConfig{}
+			if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+				return nil, err
+			}
+
+			pk.BudgetAlertConfig = bac
+		}
+
+		if len(modelLimitsData) != 0 {
+			mls := map[string]*key.ModelLimit{}
+			if err := json.Unmarshal(modelLimitsData, &mls); err != nil {
+				return nil, err
+			}
+
+			pk.ModelLimits = mls
+		}
+
 		keys = append(keys, pk)
 	}
 
@@ -371,6 +467,10 @@ func (s *Store) GetKeyByHash(hash string) (*key.ResponseKey, error) {
 	var k key.ResponseKey
 	var settingId sql.NullString
 	var data []byte
+	var cacheConfigData []byte
+	var retryConfigData []byte
+	var budgetAlertConfigData []byte
+	var modelLimitsData []byte
 
 	err := s.db.QueryRowContext(ctxTimeout, "SELECT * FROM keys WHERE key = $1", hash).Scan(
 		&k.Name,
@@ -397,6 +497,14 @@ func (s *Store) GetKeyByHash(hash string) (*key.ResponseKey, error) {
 		&k.PolicyId,
 		&k.IsKeyNotHashed,
 		&k.RequestsLimit,
+		&cacheConfigData,
+		&retryConfigData,
+		&k.TokenLimitOverTime,
+		&k.TokenLimitUnit,
+		&k.RateLimitAlgorithm,
+		&budgetAlertConfigData,
+		&k.MaxConcurrentRequests,
+		&modelLimitsData,
 	)
 
 	if err != nil {
@@ -418,6 +526,42 @@ func (s *Store) GetKeyByHash(hash string) (*key.ResponseKey, error) {
 		k.AllowedPaths = pathConfigs
 	}
 
+	if len(cacheConfigData) != 0 {
+		cc := &key.CacheConfig{}
+		if err := json.Unmarshal(cacheConfigData, cc); err != nil {
+			return nil, err
+		}
+
+		k.CacheConfig = cc
+	}
+
+	if len(retryConfigData) != 0 {
+		rc := &key.RetryConfig{}
+		if err := json.Unmarshal(retryConfigData, rc); err != nil {
+			return nil, err
+		}
+
+		k.RetryConfig = rc
+	}
+
+	if len(budgetAlertConfigData) != 0 {
+		bac := &key.BudgetAlertConfig{}
+		if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+			return nil, err
+		}
+
+		k.BudgetAlertConfig = bac
+	}
+
+	if len(modelLimitsData) != 0 {
+		mls := map[string]*key.ModelLimit{}
+		if err := json.Unmarshal(modelLimitsData, &mls); err != nil {
+			return nil, err
+		}
+
+		k.ModelLimits = mls
+	}
+
 	return &k, nil
 }
 
@@ -436,6 +580,10 @@ func (s *Store) GetKey(keyId string) (*key.ResponseKey, error) {
 		var k key.ResponseKey
 		var settingId sql.NullString
 		var data []byte
+		var cacheConfigData []byte
+		var retryConfigData []byte
+		var budgetAlertConfigData []byte
+		var modelLimitsData []byte
 
 		if err := rows.Scan(
 			&k.Name,
@@ -462,6 +610,14 @@ func (s *Store) GetKey(keyId string) (*key.ResponseKey, error) {
 			&k.PolicyId,
 			&k.IsKeyNotHashed,
 			&k.RequestsLimit,
+			&cacheConfigData,
+			&retryConfigData,
+			&k.TokenLimitOverTime,
+			&k.TokenLimitUnit,
+			&k.RateLimitAlgorithm,
+			&budgetAlertConfigData,
+			&k.MaxConcurrentRequests,
+			&modelLimitsData,
 		); err != nil {
 			return nil, err
 		}
@@ -478,6 +634,42 @@ func (s *Store) GetKey(keyId string) (*key.ResponseKey, error) {
 			pk.AllowedPaths = pathConfigs
 		}
 
+		if len(cacheConfigData) != 0 {
+			cc := &key.CacheConfig{}
+			if err := json.Unmarshal(cacheConfigData, cc); err != nil {
+				return nil, err
+			}
+
+			pk.CacheConfig = cc
+		}
+
+		if len(retryConfigData) != 0 {
+			rc := &key.RetryConfig{}
+			if err := json.Unmarshal(retryConfigData, rc); err != nil {
+				return nil, err
+			}
+
+			pk.RetryConfig = rc
+		}
+
+		if len(budgetAlertConfigData) != 0 {
+			bac := &key.BudgetAlertConfig{}
+			if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+				return nil, err
+			}
+
+			pk.BudgetAlertConfig = bac
+		}
+
+		if len(modelLimitsData) != 0 {
+			mls := map[string]*key.ModelLimit{}
+			if err := json.Unmarshal(modelLimitsData, &mls); err != nil {
+				return nil, err
+			}
+
+			pk.ModelLimits = mls
+		}
+
 		keys = append(keys, pk)
 	}
 
@@ -534,6 +726,10 @@ func (s *Store) GetSpentKeys(tags []string, order string, limit, offset int, val
 		var k key.ResponseKey
 		var settingId sql.NullString
 		var data []byte
+		var cacheConfigData []byte
+		var retryConfigData []byte
+		var budgetAlertConfigData []byte
+		var modelLimitsData []byte
 		if err := rows.Scan(
 			&k.Name,
 			&k.CreatedAt,
@@ -559,6 +755,14 @@ func (s *Store) GetSpentKeys(tags []string, order string, limit, offset int, val
 			&k.PolicyId,
 			&k.IsKeyNotHashed,
 			&k.RequestsLimit,
+			&cacheConfigData,
+			&retryConfigData,
+			&k.TokenLimitOverTime,
+			&k.TokenLimitUnit,
+			&k.RateLimitAlgorithm,
+			&budgetAlertConfigData,
+			&k.MaxConcurrentRequests,
+			&modelLimitsData,
 		); err != nil {
 			return nil, err
 		}
@@ -574,6 +778,42 @@ func (s *Store) GetSpentKeys(tags []string, order string, limit, offset int, val
 			pk.AllowedPaths = pathConfigs
 		}
 
+		if len(cacheConfigData) != 0 {
+			cc := &key.CacheConfig{}
+			if err := json.Unmarshal(cacheConfigData, cc); err != nil {
+				return nil, err
+			}
+
+			pk.CacheConfig = cc
+		}
+
+		if len(retryConfigData) != 0 {
+			rc := &key.RetryConfig{}
+			if err := json.Unmarshal(retryConfigData, rc); err != nil {
+				return nil, err
+			}
+
+			pk.RetryConfig = rc
+		}
+
+		if len(budgetAlertConfigData) != 0 {
+			bac := &key.BudgetAlertConfig{}
+			if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+				return nil, err
+			}
+
+			pk.BudgetAlertConfig = bac
+		}
+
+		if len(modelLimitsData) != 0 {
+			mls := map[string]*key.ModelLimit{}
+			if err := json.Unmarshal(modelLimitsData, &mls); err != nil {
+				return nil, err
+			}
+
+			pk.ModelLimits = mls
+		}
+
 		if !validator(pk) {
 			invalidKeyRings = append(invalidKeyRings, event.SpentKey{
 				KeyRing:     pk.KeyRing,
@@ -600,6 +840,10 @@ func (s *Store) GetAllKeys() ([]*key.ResponseKey, error) {
 		var k key.ResponseKey
 		var settingId sql.NullString
 		var data []byte
+		var cacheConfigData []byte
+		var retryConfigData []byte
+		var budgetAlertConfigData []byte
+		var modelLimitsData []byte
 		if err := rows.Scan(
 			&k.Name,
 			&k.CreatedAt,
@@ -625,6 +869,14 @@ func (s *Store) GetAllKeys() ([]*key.ResponseKey, error) {
 			&k.PolicyId,
 			&k.IsKeyNotHashed,
 			&k.RequestsLimit,
+			&cacheConfigData,
+			&retryConfigData,
+			&k.TokenLimitOverTime,
+			&k.TokenLimitUnit,
+			&k.RateLimitAlgorithm,
+			&budgetAlertConfigData,
+			&k.MaxConcurrentRequests,
+			&modelLimitsData,
 		); err != nil {
 			return nil, err
 		}
@@ -640,6 +892,42 @@ func (s *Store) GetAllKeys() ([]*key.ResponseKey, error) {
 			pk.AllowedPaths = pathConfigs
 		}
 
+		if len(cacheConfigData) != 0 {
+			cc := &key.CacheConfig{}
+			if err := json.Unmarshal(cacheConfigData, cc); err != nil {
+				return nil, err
+			}
+
+			pk.CacheConfig = cc
+		}
+
+		if len(retryConfigData) != 0 {
+			rc := &key.RetryConfig{}
+			if err := json.Unmarshal(retryConfigData, rc); err != nil {
+				return nil, err
+			}
+
+			pk.RetryConfig = rc
+		}
+
+		if len(budgetAlertConfigData) != 0 {
+			bac := &key.BudgetAlertConfig{}
+			if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+				return nil, err
+			}
+
+			pk.BudgetAlertConfig = bac
+		}
+
+		if len(modelLimitsData) != 0 {
+			mls := map[string]*key.ModelLimit{}
+			if err := json.Unmarshal(modelLimitsData, &mls); err != nil {
+				return nil, err
+			}
+
+			pk.ModelLimits = mls
+		}
+
 		keys = append(keys, pk)
 	}
 
@@ -661,6 +949,10 @@ func (s *Store) GetUpdatedKeys(updatedAt int64) ([]*key.ResponseKey, error) {
 		var k key.ResponseKey
 		var settingId sql.NullString
 		var data []byte
+		var cacheConfigData []byte
+		var retryConfigData []byte
+		var budgetAlertConfigData []byte
+		var modelLimitsData []byte
 		if err := rows.Scan(
 			&k.Name,
 			&k.CreatedAt,
@@ -686,6 +978,14 @@ func (s *Store) GetUpdatedKeys(updatedAt int64) ([]*key.ResponseKey, error) {
 			&k.PolicyId,
 			&k.IsKeyNotHashed,
 			&k.RequestsLimit,
+			&cacheConfigData,
+			&retryConfigData,
+			&k.TokenLimitOverTime,
+			&k.TokenLimitUnit,
+			&k.RateLimitAlgorithm,
+			&budgetAlertConfigData,
+			&k.MaxConcurrentRequests,
+			&modelLimitsData,
 		); err != nil {
 			return nil, err
 		}
@@ -701,6 +1001,42 @@ func (s *Store) GetUpdatedKeys(updatedAt int64) ([]*key.ResponseKey, error) {
 			pk.AllowedPaths = pathConfigs
 		}
 
+		if len(cacheConfigData) != 0 {
+			cc := &key.CacheConfig{}
+			if err := json.Unmarshal(cacheConfigData, cc); err != nil {
+				return nil, err
+			}
+
+			pk.CacheConfig = cc
+		}
+
+		if len(retryConfigData) != 0 {
+			rc := &key.RetryConfig{}
+			if err := json.Unmarshal(retryConfigData, rc); err != nil {
+				return nil, err
+			}
+
+			pk.RetryConfig = rc
+		}
+
+		if len(budgetAlertConfigData) != 0 {
+			bac := &key.BudgetAlertConfig{}
+			if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+				return nil, err
+			}
+
+			pk.BudgetAlertConfig = bac
+		}
+
+		if len(modelLimitsData) != 0 {
+			mls := map[string]*key.ModelLimit{}
+			if err := json.Unmarshal(modelLimitsData, &mls); err != nil {
+				return nil, err
+			}
+
+			pk.ModelLimits = mls
+		}
+
 		keys = append(keys, pk)
 	}
 
@@ -818,6 +1154,75 @@ func (s *Store) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, error
 
 		values = append(values, data)
 		fields = append(fields, fmt.Sprintf("allowed_paths = $%d", counter))
+		counter++
+	}
+
+	if uk.CacheConfig != nil {
+		data, err := json.Marshal(uk.CacheConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("cache_config = $%d", counter))
+		counter++
+	}
+
+	if uk.RetryConfig != nil {
+		data, err := json.Marshal(uk.RetryConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("retry_config = $%d", counter))
+		counter++
+	}
+
+	if uk.BudgetAlertConfig != nil {
+		data, err := json.Marshal(uk.BudgetAlertConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("budget_alert_config = $%d", counter))
+		counter++
+	}
+
+	if uk.TokenLimitOverTime != nil {
+		values = append(values, *uk.TokenLimitOverTime)
+		fields = append(fields, fmt.Sprintf("token_limit_over_time = $%d", counter))
+		counter++
+	}
+
+	if uk.TokenLimitUnit != nil {
+		values = append(values, *uk.TokenLimitUnit)
+		fields = append(fields, fmt.Sprintf("token_limit_unit = $%d", counter))
+		counter++
+	}
+
+	if uk.RateLimitAlgorithm != nil {
+		values = append(values, *uk.RateLimitAlgorithm)
+		fields = append(fields, fmt.Sprintf("rate_limit_algorithm = $%d", counter))
+		counter++
+	}
+
+	if uk.MaxConcurrentRequests != nil {
+		values = append(values, *uk.MaxConcurrentRequests)
+		fields = append(fields, fmt.Sprintf("max_concurrent_requests = $%d", counter))
+		counter++
+	}
+
+	if uk.ModelLimits != nil {
+		data, err := json.Marshal(uk.ModelLimits)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("model_limits = $%d", counter))
+		counter++
 	}
 
 	if uk.PolicyId != nil {
@@ -840,6 +1245,10 @@ func (s *Store) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, error
 	var k key.ResponseKey
 	var settingId sql.NullString
 	var data []byte
+	var cacheConfigData []byte
+	var retryConfigData []byte
+	var budgetAlertConfigData []byte
+	var modelLimitsData []byte
 	if err := s.db.QueryRowContext(ctxTimeout, query, values...).Scan(
 		&k.Name,
 		&k.CreatedAt,
@@ -865,6 +1274,14 @@ func (s *Store) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, error
 		&k.PolicyId,
 		&k.IsKeyNotHashed,
 		&k.RequestsLimit,
+		&cacheConfigData,
+		&retryConfigData,
+		&k.TokenLimitOverTime,
+		&k.TokenLimitUnit,
+		&k.RateLimitAlgorithm,
+		&budgetAlertConfigData,
+		&k.MaxConcurrentRequests,
+		&modelLimitsData,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, internal_errors.NewNotFoundError(fmt.Sprintf("key not found for id: %s", id))
@@ -884,13 +1301,49 @@ func (s *Store) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, error
 		pk.AllowedPaths = pathConfigs
 	}
 
+	if len(cacheConfigData) != 0 {
+		cc := &key.CacheConfig{}
+		if err := json.Unmarshal(cacheConfigData, cc); err != nil {
+			return nil, err
+		}
+
+		pk.CacheConfig = cc
+	}
+
+	if len(retryConfigData) != 0 {
+		rc := &key.RetryConfig{}
+		if err := json.Unmarshal(retryConfigData, rc); err != nil {
+			return nil, err
+		}
+
+		pk.RetryConfig = rc
+	}
+
+	if len(budgetAlertConfigData) != 0 {
+		bac := &key.BudgetAlertConfig{}
+		if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+			return nil, err
+		}
+
+		pk.BudgetAlertConfig = bac
+	}
+
+	if len(modelLimitsData) != 0 {
+		mls := map[string]*key.ModelLimit{}
+		if err := json.Unmarshal(modelLimitsData, &mls); err != nil {
+			return nil, err
+		}
+
+		pk.ModelLimits = mls
+	}
+
 	return pk, nil
 }
 
 func (s *Store) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 	query := `
-		INSERT INTO keys (name, created_at, updated_at, tags, revoked, key_id, key, revoked_reason, cost_limit_in_usd, cost_limit_in_usd_over_time, cost_limit_in_usd_unit, rate_limit_over_time, rate_limit_unit, ttl, key_ring, setting_id, allowed_paths, setting_ids, should_log_request, should_log_response, rotation_enabled, policy_id, is_key_not_hashed, requests_limit)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
+		INSERT INTO keys (name, created_at, updated_at, tags, revoked, key_id, key, revoked_reason, cost_limit_in_usd, cost_limit_in_usd_over_time, cost_limit_in_usd_unit, rate_limit_over_time, rate_limit_unit, ttl, key_ring, setting_id, allowed_paths, setting_ids, should_log_request, should_log_response, rotation_enabled, policy_id, is_key_not_hashed, requests_limit, cache_config, retry_config, token_limit_over_time, token_limit_unit, rate_limit_algorithm, budget_alert_config, max_concurrent_requests, model_limits)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32)
 		RETURNING *;
 	`
 
@@ -899,6 +1352,38 @@ func (s *Store) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 		return nil, err
 	}
 
+	var cdata []byte
+	if rk.CacheConfig != nil {
+		cdata, err = json.Marshal(rk.CacheConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var rcdata []byte
+	if rk.RetryConfig != nil {
+		rcdata, err = json.Marshal(rk.RetryConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var bacdata []byte
+	if rk.BudgetAlertConfig != nil {
+		bacdata, err = json.Marshal(rk.BudgetAlertConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var mldata []byte
+	if rk.ModelLimits != nil {
+		mldata, err = json.Marshal(rk.ModelLimits)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	values := []any{
 		rk.Name,
 		rk.CreatedAt,
@@ -924,6 +1409,14 @@ func (s *Store) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 		rk.PolicyId,
 		rk.IsKeyNotHashed,
 		rk.RequestsLimit,
+		cdata,
+		rcdata,
+		rk.TokenLimitOverTime,
+		rk.TokenLimitUnit,
+		rk.RateLimitAlgorithm,
+		bacdata,
+		rk.MaxConcurrentRequests,
+		mldata,
 	}
 
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -933,6 +1426,10 @@ func (s *Store) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 
 	var settingId sql.NullString
 	var data []byte
+	var cacheConfigData []byte
+	var retryConfigData []byte
+	var budgetAlertConfigData []byte
+	var modelLimitsData []byte
 	if err := s.db.QueryRowContext(ctxTimeout, query, values...).Scan(
 		&k.Name,
 		&k.CreatedAt,
@@ -958,6 +1455,14 @@ func (s *Store) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 		&k.PolicyId,
 		&k.IsKeyNotHashed,
 		&k.RequestsLimit,
+		&cacheConfigData,
+		&retryConfigData,
+		&k.TokenLimitOverTime,
+		&k.TokenLimitUnit,
+		&k.RateLimitAlgorithm,
+		&budgetAlertConfigData,
+		&k.MaxConcurrentRequests,
+		&modelLimitsData,
 	); err != nil {
 		return nil, err
 	}
@@ -974,6 +1479,42 @@ func (s *Store) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 		pk.AllowedPaths = pathConfigs
 	}
 
+	if len(cacheConfigData) != 0 {
+		cc := &key.CacheConfig{}
+		if err := json.Unmarshal(cacheConfigData, cc); err != nil {
+			return nil, err
+		}
+
+		pk.CacheConfig = cc
+	}
+
+	if len(retryConfigData) != 0 {
+		rc := &key.RetryConfig{}
+		if err := json.Unmarshal(retryConfigData, rc); err != nil {
+			return nil, err
+		}
+
+		pk.RetryConfig = rc
+	}
+
+	if len(budgetAlertConfigData) != 0 {
+		bac := &key.BudgetAlertConfig{}
+		if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+			return nil, err
+		}
+
+		pk.BudgetAlertConfig = bac
+	}
+
+	if len(modelLimitsData) != 0 {
+		mls := map[string]*key.ModelLimit{}
+		if err := json.Unmarshal(modelLimitsData, &mls); err != nil {
+			return nil, err
+		}
+
+		pk.ModelLimits = mls
+	}
+
 	return pk, nil
 }
 
@@ -35,6 +35,21 @@ func (s *Store) CreatePolicyTable() error {
 	return nil
 }
 
+func (s *Store) AlterPolicyTable() error {
+	alterTableQuery := `
+		ALTER TABLE policies ADD COLUMN IF NOT EXISTS apply_to_responses BOOLEAN NOT NULL DEFAULT FALSE
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, alterTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (s *Store) CreatePolicy(p *policy.Policy) (*policy.Policy, error) {
 	fields := []string{
 		"id",
@@ -42,6 +57,7 @@ func (s *Store) CreatePolicy(p *policy.Policy) (*policy.Policy, error) {
 		"updated_at",
 		"tags",
 		"name",
+		"apply_to_responses",
 	}
 
 	values := []any{
@@ -50,12 +66,13 @@ func (s *Store) CreatePolicy(p *policy.Policy) (*policy.Policy, error) {
 		p.UpdatedAt,
 		pq.Array(p.Tags),
 		p.Name,
+		p.ApplyToResponses,
 	}
 
 	vidxs := []string{
-		"$1", "$2", "$3", "$4", "$5",
+		"$1", "$2", "$3", "$4", "$5", "$6",
 	}
-	idx := 6
+	idx := 7
 
 	if p.Config != nil {
 		cd, err := json.Marshal(p.Config)
@@ -116,6 +133,7 @@ func (s *Store) CreatePolicy(p *policy.Policy) (*policy.Policy, error) {
 		&createdcd,
 		&createdregexd,
 		&createdcusd,
+		&created.ApplyToResponses,
 	); err != nil {
 
 		return nil, err
@@ -194,6 +212,12 @@ func (s *Store) UpdatePolicy(id string, p *policy.UpdatePolicy) (*policy.Policy,
 
 		values = append(values, data)
 		fields = append(fields, fmt.Sprintf("custom_config = $%d", d))
+		d++
+	}
+
+	if p.ApplyToResponses != nil {
+		values = append(values, *p.ApplyToResponses)
+		fields = append(fields, fmt.Sprintf("apply_to_responses = $%d", d))
 	}
 
 	query := fmt.Sprintf("UPDATE policies SET %s WHERE id = $1 RETURNING *", strings.Join(fields, ","))
@@ -214,6 +238,7 @@ func (s *Store) UpdatePolicy(id string, p *policy.UpdatePolicy) (*policy.Policy,
 		&cd,
 		&regexd,
 		&cusd,
+		&updated.ApplyToResponses,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, internal_errors.NewNotFoundError("policy is not found for id: " + id)
@@ -269,6 +294,7 @@ func (s *Store) GetAllPolicies() ([]*policy.Policy, error) {
 			&cd,
 			&regexd,
 			&cusd,
+			&p.ApplyToResponses,
 		); err != nil {
 			return nil, err
 		}
@@ -317,6 +343,7 @@ func (s *Store) GetPolicyById(id string) (*policy.Policy, error) {
 		&cd,
 		&regexd,
 		&cusd,
+		&p.ApplyToResponses,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, internal_errors.NewNotFoundError("policy is not found for id: " + id)
@@ -374,6 +401,7 @@ func (s *Store) GetPoliciesByTags(tags []string) ([]*policy.Policy, error) {
 			&cd,
 			&regexd,
 			&cusd,
+			&p.ApplyToResponses,
 		); err != nil {
 			return nil, err
 		}
@@ -429,6 +457,7 @@ func (s *Store) GetUpdatedPolicies(updatedAt int64) ([]*policy.Policy, error) {
 			&cd,
 			&regexd,
 			&cusd,
+			&p.ApplyToResponses,
 		); err != nil {
 			return nil, err
 		}
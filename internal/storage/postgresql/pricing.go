@@ -0,0 +1,230 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/pricing"
+)
+
+func (s *Store) CreatePricesTable() error {
+	createTableQuery := `
+	CREATE TABLE IF NOT EXISTS prices (
+		id VARCHAR(255) PRIMARY KEY,
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		provider VARCHAR(255) NOT NULL,
+		model VARCHAR(255) NOT NULL,
+		category VARCHAR(255) NOT NULL,
+		cost FLOAT8 NOT NULL,
+		effective_from BIGINT NOT NULL,
+		archived BOOLEAN NOT NULL DEFAULT FALSE
+	)`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, createTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) CreatePrice(p *pricing.Price) (*pricing.Price, error) {
+	query := `
+	INSERT INTO prices (id, created_at, updated_at, provider, model, category, cost, effective_from, archived)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id, created_at, updated_at, provider, model, category, cost, effective_from, archived
+`
+
+	values := []any{
+		p.Id,
+		p.CreatedAt,
+		p.UpdatedAt,
+		p.Provider,
+		p.Model,
+		p.Category,
+		p.Cost,
+		p.EffectiveFrom,
+		p.Archived,
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	created := &pricing.Price{}
+	if err := s.db.QueryRowContext(ctxTimeout, query, values...).Scan(
+		&created.Id,
+		&created.CreatedAt,
+		&created.UpdatedAt,
+		&created.Provider,
+		&created.Model,
+		&created.Category,
+		&created.Cost,
+		&created.EffectiveFrom,
+		&created.Archived,
+	); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *Store) UpdatePrice(id string, p *pricing.UpdatePrice) (*pricing.Price, error) {
+	values := []any{
+		id,
+		p.UpdatedAt,
+	}
+
+	fields := []string{"updated_at = $2"}
+
+	d := 3
+
+	if p.Cost != nil {
+		values = append(values, *p.Cost)
+		fields = append(fields, fmt.Sprintf("cost = $%d", d))
+		d++
+	}
+
+	if p.EffectiveFrom != nil {
+		values = append(values, *p.EffectiveFrom)
+		fields = append(fields, fmt.Sprintf("effective_from = $%d", d))
+	}
+
+	query := fmt.Sprintf("UPDATE prices SET %s WHERE id = $1 AND archived = FALSE RETURNING *", strings.Join(fields, ","))
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	updated := &pricing.Price{}
+	if err := s.db.QueryRowContext(ctxTimeout, query, values...).Scan(
+		&updated.Id,
+		&updated.CreatedAt,
+		&updated.UpdatedAt,
+		&updated.Provider,
+		&updated.Model,
+		&updated.Category,
+		&updated.Cost,
+		&updated.EffectiveFrom,
+		&updated.Archived,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("price is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (s *Store) ArchivePrice(id string, updatedAt int64) error {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctxTimeout, "UPDATE prices SET archived = TRUE, updated_at = $2 WHERE id = $1 AND archived = FALSE", id, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return internal_errors.NewNotFoundError("price is not found for id: " + id)
+	}
+
+	return nil
+}
+
+func (s *Store) GetPrice(id string) (*pricing.Price, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	p := &pricing.Price{}
+	if err := s.db.QueryRowContext(ctxTimeout, "SELECT * FROM prices WHERE id = $1 AND archived = FALSE", id).Scan(
+		&p.Id,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+		&p.Provider,
+		&p.Model,
+		&p.Category,
+		&p.Cost,
+		&p.EffectiveFrom,
+		&p.Archived,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("price is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *Store) GetPrices(provider, model string) ([]*pricing.Price, error) {
+	conditions := []string{"archived = FALSE"}
+	values := []any{}
+
+	if len(provider) != 0 {
+		values = append(values, provider)
+		conditions = append(conditions, fmt.Sprintf("provider = $%d", len(values)))
+	}
+
+	if len(model) != 0 {
+		values = append(values, model)
+		conditions = append(conditions, fmt.Sprintf("model = $%d", len(values)))
+	}
+
+	query := fmt.Sprintf("SELECT * FROM prices WHERE %s ORDER BY provider, model, category, effective_from", strings.Join(conditions, " AND "))
+
+	return s.queryPrices(query, values...)
+}
+
+func (s *Store) GetAllPrices() ([]*pricing.Price, error) {
+	return s.queryPrices("SELECT * FROM prices WHERE archived = FALSE")
+}
+
+func (s *Store) GetUpdatedPrices(updatedAt int64) ([]*pricing.Price, error) {
+	return s.queryPrices("SELECT * FROM prices WHERE updated_at >= $1", updatedAt)
+}
+
+func (s *Store) queryPrices(query string, args ...any) ([]*pricing.Price, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctxTimeout, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ps := []*pricing.Price{}
+	for rows.Next() {
+		p := &pricing.Price{}
+		if err := rows.Scan(
+			&p.Id,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+			&p.Provider,
+			&p.Model,
+			&p.Category,
+			&p.Cost,
+			&p.EffectiveFrom,
+			&p.Archived,
+		); err != nil {
+			return nil, err
+		}
+
+		ps = append(ps, p)
+	}
+
+	return ps, nil
+}
@@ -36,7 +36,7 @@ func (s *Store) CreateProviderSettingsTable() error {
 
 func (s *Store) AlterProviderSettingsTable() error {
 	alterTableQuery := `
-		ALTER TABLE provider_settings ADD COLUMN IF NOT EXISTS name VARCHAR(255), ADD COLUMN IF NOT EXISTS allowed_models VARCHAR(255)[], ADD COLUMN IF NOT EXISTS cost_map JSONB NOT NULL DEFAULT '{}'::JSONB
+		ALTER TABLE provider_settings ADD COLUMN IF NOT EXISTS name VARCHAR(255), ADD COLUMN IF NOT EXISTS allowed_models VARCHAR(255)[], ADD COLUMN IF NOT EXISTS cost_map JSONB NOT NULL DEFAULT '{}'::JSONB, ADD COLUMN IF NOT EXISTS weight INT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS model_map JSONB NOT NULL DEFAULT '{}'::JSONB
 	`
 
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -56,6 +56,7 @@ func (s *Store) GetProviderSetting(id string, withSecret bool) (*provider.Settin
 	setting := &provider.Setting{}
 	var data []byte
 	var cmdata []byte
+	var mmdata []byte
 	var name sql.NullString
 	err := s.db.QueryRowContext(ctxTimeout, "SELECT * FROM provider_settings WHERE $1 = id", id).Scan(
 		&setting.Id,
@@ -66,6 +67,8 @@ func (s *Store) GetProviderSetting(id string, withSecret bool) (*provider.Settin
 		&name,
 		pq.Array(&setting.AllowedModels),
 		&cmdata,
+		&setting.Weight,
+		&mmdata,
 	)
 
 	if err != nil {
@@ -90,8 +93,14 @@ func (s *Store) GetProviderSetting(id string, withSecret bool) (*provider.Settin
 		delete(m, "apikey")
 	}
 
+	mm := map[string]string{}
+	if err := json.Unmarshal(mmdata, &mm); err != nil {
+		return nil, err
+	}
+
 	setting.Setting = m
 	setting.CostMap = cm
+	setting.ModelMap = mm
 
 	setting.Name = name.String
 
@@ -113,6 +122,7 @@ func (s *Store) GetUpdatedProviderSettings(updatedAt int64) ([]*provider.Setting
 		setting := &provider.Setting{}
 		var data []byte
 		var cmdata []byte
+		var mmdata []byte
 		var name sql.NullString
 		if err := rows.Scan(
 			&setting.Id,
@@ -123,6 +133,8 @@ func (s *Store) GetUpdatedProviderSettings(updatedAt int64) ([]*provider.Setting
 			&name,
 			pq.Array(&setting.AllowedModels),
 			&cmdata,
+			&setting.Weight,
+			&mmdata,
 		); err != nil {
 			return nil, err
 		}
@@ -137,8 +149,14 @@ func (s *Store) GetUpdatedProviderSettings(updatedAt int64) ([]*provider.Setting
 			return nil, err
 		}
 
+		mm := map[string]string{}
+		if err := json.Unmarshal(mmdata, &mm); err != nil {
+			return nil, err
+		}
+
 		setting.Setting = m
 		setting.CostMap = cm
+		setting.ModelMap = mm
 		setting.Name = name.String
 		settings = append(settings, setting)
 	}
@@ -186,15 +204,34 @@ func (s *Store) UpdateProviderSetting(id string, setting *provider.UpdateSetting
 
 		values = append(values, data)
 		fields = append(fields, fmt.Sprintf("cost_map = $%d", d))
+		d++
+	}
+
+	if setting.Weight != nil {
+		values = append(values, *setting.Weight)
+		fields = append(fields, fmt.Sprintf("weight = $%d", d))
+		d++
 	}
 
-	query := fmt.Sprintf("UPDATE provider_settings SET %s WHERE id = $1 RETURNING id, created_at, updated_at, provider, name, allowed_models, setting, cost_map;", strings.Join(fields, ","))
+	if setting.ModelMap != nil {
+		data, err := json.Marshal(setting.ModelMap)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("model_map = $%d", d))
+		d++
+	}
+
+	query := fmt.Sprintf("UPDATE provider_settings SET %s WHERE id = $1 RETURNING id, created_at, updated_at, provider, name, allowed_models, setting, cost_map, weight, model_map;", strings.Join(fields, ","))
 	updated := &provider.Setting{}
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
 	defer cancel()
 
 	var rawd []byte
 	var cmdata []byte
+	var mmdata []byte
 
 	row := s.db.QueryRowContext(ctxTimeout, query, values...)
 	if err := row.Scan(
@@ -206,6 +243,8 @@ func (s *Store) UpdateProviderSetting(id string, setting *provider.UpdateSetting
 		pq.Array(&updated.AllowedModels),
 		&rawd,
 		&cmdata,
+		&updated.Weight,
+		&mmdata,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, internal_errors.NewNotFoundError("provider setting is not found for: " + id)
@@ -226,8 +265,14 @@ func (s *Store) UpdateProviderSetting(id string, setting *provider.UpdateSetting
 
 	delete(m, "apikey")
 
+	mm := map[string]string{}
+	if err := json.Unmarshal(mmdata, &mm); err != nil {
+		return nil, err
+	}
+
 	updated.Setting = m
 	updated.CostMap = cm
+	updated.ModelMap = mm
 
 	return updated, nil
 }
@@ -255,9 +300,9 @@ func (s *Store) CreateProviderSetting(setting *provider.Setting) (*provider.Sett
 	}
 
 	query := `
-		INSERT INTO provider_settings (id, created_at, updated_at, provider, setting, name, allowed_models, cost_map)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, created_at, updated_at, provider, name, allowed_models, setting, cost_map
+		INSERT INTO provider_settings (id, created_at, updated_at, provider, setting, name, allowed_models, cost_map, weight, model_map)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at, provider, name, allowed_models, setting, cost_map, weight, model_map
 	`
 
 	data, err := json.Marshal(setting.Setting)
@@ -270,6 +315,11 @@ func (s *Store) CreateProviderSetting(setting *provider.Setting) (*provider.Sett
 		return nil, err
 	}
 
+	mmd, err := json.Marshal(setting.ModelMap)
+	if err != nil {
+		return nil, err
+	}
+
 	values := []any{
 		setting.Id,
 		setting.CreatedAt,
@@ -279,10 +329,13 @@ func (s *Store) CreateProviderSetting(setting *provider.Setting) (*provider.Sett
 		setting.Name,
 		sliceToSqlStringArray(setting.AllowedModels),
 		cmd,
+		setting.Weight,
+		mmd,
 	}
 
 	var rawd []byte
 	var rawcmd []byte
+	var mmdata []byte
 
 	created := &provider.Setting{}
 	var name sql.NullString
@@ -295,6 +348,8 @@ func (s *Store) CreateProviderSetting(setting *provider.Setting) (*provider.Sett
 		pq.Array(&created.AllowedModels),
 		&rawd,
 		&rawcmd,
+		&created.Weight,
+		&mmdata,
 	); err != nil {
 		return nil, err
 	}
@@ -311,8 +366,14 @@ func (s *Store) CreateProviderSetting(setting *provider.Setting) (*provider.Sett
 
 	delete(m, "apikey")
 
+	mm := map[string]string{}
+	if err := json.Unmarshal(mmdata, &mm); err != nil {
+		return nil, err
+	}
+
 	created.Setting = m
 	created.CostMap = cm
+	created.ModelMap = mm
 
 	created.Name = name.String
 	return created, nil
@@ -342,6 +403,7 @@ func (s *Store) GetProviderSettings(withSecret bool, ids []string) ([]*provider.
 		setting := &provider.Setting{}
 		var data []byte
 		var cmdata []byte
+		var mmdata []byte
 
 		var name sql.NullString
 		if err := rows.Scan(
@@ -353,6 +415,8 @@ func (s *Store) GetProviderSettings(withSecret bool, ids []string) ([]*provider.
 			&name,
 			pq.Array(&setting.AllowedModels),
 			&cmdata,
+			&setting.Weight,
+			&mmdata,
 		); err != nil {
 			return nil, err
 		}
@@ -371,8 +435,14 @@ func (s *Store) GetProviderSettings(withSecret bool, ids []string) ([]*provider.
 			delete(m, "apikey")
 		}
 
+		mm := map[string]string{}
+		if err := json.Unmarshal(mmdata, &mm); err != nil {
+			return nil, err
+		}
+
 		setting.Setting = m
 		setting.CostMap = cm
+		setting.ModelMap = mm
 
 		setting.Name = name.String
 		settings = append(settings, setting)
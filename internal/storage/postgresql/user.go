@@ -46,6 +46,21 @@ func (s *Store) CreateUsersTable() error {
 	return nil
 }
 
+func (s *Store) AlterUsersTable() error {
+	alterTableQuery := `
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS token_limit_over_time INT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS token_limit_unit VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS budget_alert_config JSONB, ADD COLUMN IF NOT EXISTS max_concurrent_requests INT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS credit_balance_in_usd FLOAT8;
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, alterTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (s *Store) CreateCreatedAtIndexForUsers() error {
 	createIndexQuery := `
 	CREATE INDEX IF NOT EXISTS created_at_idx ON users(created_at);
@@ -133,6 +148,7 @@ func (s *Store) GetUsers(tags, keyIds, userIds []string, offset, limit int) ([]*
 	for rows.Next() {
 		var u user.User
 		var data []byte
+		var budgetAlertConfigData []byte
 		if err := rows.Scan(
 			&u.Id,
 			&u.Name,
@@ -151,6 +167,11 @@ func (s *Store) GetUsers(tags, keyIds, userIds []string, offset, limit int) ([]*
 			&data,
 			pq.Array(&u.AllowedModels),
 			&u.UserId,
+			&u.TokenLimitOverTime,
+			&u.TokenLimitUnit,
+			&budgetAlertConfigData,
+			&u.MaxConcurrentRequests,
+			&u.CreditBalanceInUsd,
 		); err != nil {
 			return nil, err
 		}
@@ -166,6 +187,15 @@ func (s *Store) GetUsers(tags, keyIds, userIds []string, offset, limit int) ([]*
 			pu.AllowedPaths = pathConfigs
 		}
 
+		if len(budgetAlertConfigData) != 0 {
+			bac := &key.BudgetAlertConfig{}
+			if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+				return nil, err
+			}
+
+			pu.BudgetAlertConfig = bac
+		}
+
 		users = append(users, pu)
 	}
 
@@ -174,8 +204,8 @@ func (s *Store) GetUsers(tags, keyIds, userIds []string, offset, limit int) ([]*
 
 func (s *Store) CreateUser(u *user.User) (*user.User, error) {
 	query := `
-		INSERT INTO users (id, name, created_at, updated_at, tags, revoked, revoked_reason, cost_limit_in_usd, cost_limit_in_usd_over_time, cost_limit_in_usd_unit, rate_limit_over_time, rate_limit_unit, ttl, key_ids, allowed_paths, allowed_models, user_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		INSERT INTO users (id, name, created_at, updated_at, tags, revoked, revoked_reason, cost_limit_in_usd, cost_limit_in_usd_over_time, cost_limit_in_usd_unit, rate_limit_over_time, rate_limit_unit, ttl, key_ids, allowed_paths, allowed_models, user_id, token_limit_over_time, token_limit_unit, budget_alert_config, max_concurrent_requests)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 		RETURNING *;
 	`
 
@@ -184,6 +214,14 @@ func (s *Store) CreateUser(u *user.User) (*user.User, error) {
 		return nil, err
 	}
 
+	var bacdata []byte
+	if u.BudgetAlertConfig != nil {
+		bacdata, err = json.Marshal(u.BudgetAlertConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	values := []any{
 		u.Id,
 		u.Name,
@@ -202,6 +240,10 @@ func (s *Store) CreateUser(u *user.User) (*user.User, error) {
 		rdata,
 		pq.Array(u.AllowedModels),
 		u.UserId,
+		u.TokenLimitOverTime,
+		u.TokenLimitUnit,
+		bacdata,
+		u.MaxConcurrentRequests,
 	}
 
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -210,6 +252,7 @@ func (s *Store) CreateUser(u *user.User) (*user.User, error) {
 	var created user.User
 
 	var data []byte
+	var budgetAlertConfigData []byte
 	if err := s.db.QueryRowContext(ctxTimeout, query, values...).Scan(
 		&created.Id,
 		&created.Name,
@@ -228,6 +271,11 @@ func (s *Store) CreateUser(u *user.User) (*user.User, error) {
 		&data,
 		pq.Array(&created.AllowedModels),
 		&created.UserId,
+		&created.TokenLimitOverTime,
+		&created.TokenLimitUnit,
+		&budgetAlertConfigData,
+		&created.MaxConcurrentRequests,
+		&created.CreditBalanceInUsd,
 	); err != nil {
 		return nil, err
 	}
@@ -243,6 +291,15 @@ func (s *Store) CreateUser(u *user.User) (*user.User, error) {
 		pu.AllowedPaths = pathConfigs
 	}
 
+	if len(budgetAlertConfigData) != 0 {
+		bac := &key.BudgetAlertConfig{}
+		if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+			return nil, err
+		}
+
+		pu.BudgetAlertConfig = bac
+	}
+
 	return pu, nil
 }
 
@@ -319,6 +376,35 @@ func (s *Store) UpdateUser(id string, uu *user.UpdateUser) (*user.User, error) {
 		counter++
 	}
 
+	if uu.TokenLimitOverTime != nil {
+		values = append(values, *uu.TokenLimitOverTime)
+		fields = append(fields, fmt.Sprintf("token_limit_over_time = $%d", counter))
+		counter++
+	}
+
+	if uu.TokenLimitUnit != nil {
+		values = append(values, *uu.TokenLimitUnit)
+		fields = append(fields, fmt.Sprintf("token_limit_unit = $%d", counter))
+		counter++
+	}
+
+	if uu.MaxConcurrentRequests != nil {
+		values = append(values, *uu.MaxConcurrentRequests)
+		fields = append(fields, fmt.Sprintf("max_concurrent_requests = $%d", counter))
+		counter++
+	}
+
+	if uu.BudgetAlertConfig != nil {
+		data, err := json.Marshal(uu.BudgetAlertConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("budget_alert_config = $%d", counter))
+		counter++
+	}
+
 	if uu.AllowedPaths != nil {
 		data, err := json.Marshal(uu.AllowedPaths)
 		if err != nil {
@@ -350,6 +436,7 @@ func (s *Store) UpdateUser(id string, uu *user.UpdateUser) (*user.User, error) {
 	var updated user.User
 
 	var data []byte
+	var budgetAlertConfigData []byte
 	if err := s.db.QueryRowContext(ctxTimeout, query, values...).Scan(
 		&updated.Id,
 		&updated.Name,
@@ -368,6 +455,11 @@ func (s *Store) UpdateUser(id string, uu *user.UpdateUser) (*user.User, error) {
 		&data,
 		pq.Array(&updated.AllowedModels),
 		&updated.UserId,
+		&updated.TokenLimitOverTime,
+		&updated.TokenLimitUnit,
+		&budgetAlertConfigData,
+		&updated.MaxConcurrentRequests,
+		&updated.CreditBalanceInUsd,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, internal_errors.NewNotFoundError(fmt.Sprintf("key not found for id: %s", id))
@@ -386,6 +478,15 @@ func (s *Store) UpdateUser(id string, uu *user.UpdateUser) (*user.User, error) {
 		updated.AllowedPaths = pathConfigs
 	}
 
+	if len(budgetAlertConfigData) != 0 {
+		bac := &key.BudgetAlertConfig{}
+		if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+			return nil, err
+		}
+
+		updated.BudgetAlertConfig = bac
+	}
+
 	return pu, nil
 }
 
@@ -464,6 +565,35 @@ func (s *Store) UpdateUserViaTagsAndUserId(tags []string, uid string, uu *user.U
 		counter++
 	}
 
+	if uu.TokenLimitOverTime != nil {
+		values = append(values, *uu.TokenLimitOverTime)
+		fields = append(fields, fmt.Sprintf("token_limit_over_time = $%d", counter))
+		counter++
+	}
+
+	if uu.TokenLimitUnit != nil {
+		values = append(values, *uu.TokenLimitUnit)
+		fields = append(fields, fmt.Sprintf("token_limit_unit = $%d", counter))
+		counter++
+	}
+
+	if uu.MaxConcurrentRequests != nil {
+		values = append(values, *uu.MaxConcurrentRequests)
+		fields = append(fields, fmt.Sprintf("max_concurrent_requests = $%d", counter))
+		counter++
+	}
+
+	if uu.BudgetAlertConfig != nil {
+		data, err := json.Marshal(uu.BudgetAlertConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("budget_alert_config = $%d", counter))
+		counter++
+	}
+
 	if uu.AllowedPaths != nil {
 		data, err := json.Marshal(uu.AllowedPaths)
 		if err != nil {
@@ -495,6 +625,7 @@ func (s *Store) UpdateUserViaTagsAndUserId(tags []string, uid string, uu *user.U
 	var updated user.User
 
 	var data []byte
+	var budgetAlertConfigData []byte
 	if err := s.db.QueryRowContext(ctxTimeout, query, values...).Scan(
 		&updated.Id,
 		&updated.Name,
@@ -513,6 +644,11 @@ func (s *Store) UpdateUserViaTagsAndUserId(tags []string, uid string, uu *user.U
 		&data,
 		pq.Array(&updated.AllowedModels),
 		&updated.UserId,
+		&updated.TokenLimitOverTime,
+		&updated.TokenLimitUnit,
+		&budgetAlertConfigData,
+		&updated.MaxConcurrentRequests,
+		&updated.CreditBalanceInUsd,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, internal_errors.NewNotFoundError(fmt.Sprintf("key not found for user id: %s tags: [%s]", uid, strings.Join(tags, ",")))
@@ -531,5 +667,14 @@ func (s *Store) UpdateUserViaTagsAndUserId(tags []string, uid string, uu *user.U
 		updated.AllowedPaths = pathConfigs
 	}
 
+	if len(budgetAlertConfigData) != 0 {
+		bac := &key.BudgetAlertConfig{}
+		if err := json.Unmarshal(budgetAlertConfigData, bac); err != nil {
+			return nil, err
+		}
+
+		updated.BudgetAlertConfig = bac
+	}
+
 	return pu, nil
 }
@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/redis/go-redis/v9"
+)
+
+// AlertCache keeps track of the budget alerts that have been sent. Its keys
+// are prefixed so that it can share a database with the cost limit counters.
+type AlertCache struct {
+	client *redis.Client
+	wt     time.Duration
+	rt     time.Duration
+}
+
+func NewAlertCache(c *redis.Client, wt time.Duration, rt time.Duration) *AlertCache {
+	return &AlertCache{
+		client: c,
+		wt:     wt,
+		rt:     rt,
+	}
+}
+
+func getAlertKey(key string) string {
+	return fmt.Sprintf("alert:%s", key)
+}
+
+// SetIfNotExists marks an alert as sent until the end of the current time unit
+// period and reports whether it had not been sent yet. Alerts without a time
+// unit are kept until deleted.
+func (ac *AlertCache) SetIfNotExists(key string, timeUnit key.TimeUnit) (bool, error) {
+	var ttl time.Duration
+	if len(timeUnit) != 0 {
+		expireAt, err := getCounterTtl(timeUnit)
+		if err != nil {
+			return false, err
+		}
+
+		ttl = time.Until(expireAt)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ac.wt)
+	defer cancel()
+
+	return ac.client.SetNX(ctx, getAlertKey(key), true, ttl).Result()
+}
+
+func (ac *AlertCache) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), ac.wt)
+	defer cancel()
+	err := ac.client.Del(ctx, getAlertKey(key)).Err()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
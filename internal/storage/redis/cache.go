@@ -7,11 +7,13 @@ import (
 	"time"
 
 	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/util"
 	"github.com/redis/go-redis/v9"
 )
 
 type Cache struct {
 	client *redis.Client
+	prefix string
 	wt     time.Duration
 	rt     time.Duration
 }
@@ -24,10 +26,25 @@ func NewCache(c *redis.Client, wt time.Duration, rt time.Duration) *Cache {
 	}
 }
 
+// NewPrefixedCache returns a cache whose keys are prefixed, so that it can
+// share a Redis database with another cache keyed by the same ids.
+func NewPrefixedCache(c *redis.Client, prefix string, wt time.Duration, rt time.Duration) *Cache {
+	return &Cache{
+		client: c,
+		prefix: prefix,
+		wt:     wt,
+		rt:     rt,
+	}
+}
+
+func (c *Cache) key(id string) string {
+	return c.prefix + id
+}
+
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), c.wt)
 	defer cancel()
-	err := c.client.Set(ctx, key, value, ttl).Err()
+	err := c.client.Set(ctx, c.key(key), value, ttl).Err()
 	if err != nil {
 		return err
 	}
@@ -38,7 +55,7 @@ func (c *Cache) Set(key string, value interface{}, ttl time.Duration) error {
 func (c *Cache) Delete(key string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), c.wt)
 	defer cancel()
-	err := c.client.Del(ctx, key).Err()
+	err := c.client.Del(ctx, c.key(key)).Err()
 	if err != nil {
 		return err
 	}
@@ -50,7 +67,7 @@ func (c *Cache) GetBytes(key string) ([]byte, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), c.rt)
 	defer cancel()
 
-	result := c.client.Get(ctx, key)
+	result := c.client.Get(ctx, c.key(key))
 	err := result.Err()
 	if err != nil {
 		return nil, err
@@ -68,14 +85,14 @@ func (c *Cache) IncrementCounter(keyId string, timeUnit key.TimeUnit, incr int64
 		return err
 	}
 
-	err = c.client.HIncrBy(ctxTimeout, keyId, strconv.FormatInt(ts, 10), incr).Err()
+	err = c.client.HIncrBy(ctxTimeout, c.key(keyId), strconv.FormatInt(ts, 10), incr).Err()
 	if err != nil {
 		return err
 	}
 
 	ctxTimeout, cancel = context.WithTimeout(context.Background(), c.rt)
 	defer cancel()
-	dur := c.client.TTL(ctxTimeout, keyId)
+	dur := c.client.TTL(ctxTimeout, c.key(keyId))
 	err = dur.Err()
 	if err != nil {
 		return err
@@ -90,7 +107,7 @@ func (c *Cache) IncrementCounter(keyId string, timeUnit key.TimeUnit, incr int64
 
 		ctxTimeout, cancel = context.WithTimeout(context.Background(), c.wt)
 		defer cancel()
-		err = c.client.ExpireAt(ctxTimeout, keyId, ttl).Err()
+		err = c.client.ExpireAt(ctxTimeout, c.key(keyId), ttl).Err()
 		if err != nil {
 			return err
 		}
@@ -141,7 +158,7 @@ func (c *Cache) GetCounter(keyId string, rateLimitUnit key.TimeUnit) (int64, err
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), c.rt)
 	defer cancel()
 
-	strSlices := c.client.HVals(ctxTimeout, keyId)
+	strSlices := c.client.HVals(ctxTimeout, c.key(keyId))
 	err := strSlices.Err()
 
 	if err != nil && err != redis.Nil {
@@ -168,3 +185,73 @@ func (c *Cache) GetCounter(keyId string, rateLimitUnit key.TimeUnit) (int64, err
 	return counter, nil
 
 }
+
+func getSlidingWindowDuration(timeUnit key.TimeUnit) (time.Duration, error) {
+	switch timeUnit {
+	case key.SecondTimeUnit:
+		return time.Second, nil
+	case key.MinuteTimeUnit:
+		return time.Minute, nil
+	case key.HourTimeUnit:
+		return time.Hour, nil
+	case key.DayTimeUnit:
+		return 24 * time.Hour, nil
+	}
+
+	return 0, fmt.Errorf("cannot recognize sliding window time unit %v", timeUnit)
+}
+
+func getSlidingWindowKey(keyId string, timeUnit key.TimeUnit) string {
+	return fmt.Sprintf("sliding:%s:%s", timeUnit, keyId)
+}
+
+// slidingWindowIncrementScript trims entries that fell out of the window and
+// records a request atomically. Every member counts as one request.
+var slidingWindowIncrementScript = redis.NewScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1] - ARGV[2])
+redis.call("ZADD", KEYS[1], ARGV[1], ARGV[3])
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1
+`)
+
+var slidingWindowCounterScript = redis.NewScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1] - ARGV[2])
+return redis.call("ZCARD", KEYS[1])
+`)
+
+func (c *Cache) IncrementSlidingWindowCounter(keyId string, timeUnit key.TimeUnit) error {
+	window, err := getSlidingWindowDuration(timeUnit)
+	if err != nil {
+		return err
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), c.wt)
+	defer cancel()
+
+	now := time.Now().UTC()
+	member := fmt.Sprintf("%d-%s", now.UnixNano(), util.NewUuid())
+
+	err = slidingWindowIncrementScript.Run(ctxTimeout, c.client, []string{getSlidingWindowKey(c.key(keyId), timeUnit)}, now.UnixMilli(), window.Milliseconds(), member).Err()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Cache) GetSlidingWindowCounter(keyId string, timeUnit key.TimeUnit) (int64, error) {
+	window, err := getSlidingWindowDuration(timeUnit)
+	if err != nil {
+		return 0, err
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), c.rt)
+	defer cancel()
+
+	counter, err := slidingWindowCounterScript.Run(ctxTimeout, c.client, []string{getSlidingWindowKey(c.key(keyId), timeUnit)}, time.Now().UTC().UnixMilli(), window.Milliseconds()).Int64()
+	if err != nil && err != redis.Nil {
+		return 0, err
+	}
+
+	return counter, nil
+}
@@ -0,0 +1,110 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSlidingWindowDuration(t *testing.T) {
+	tests := []struct {
+		unit     key.TimeUnit
+		expected time.Duration
+		err      bool
+	}{
+		{unit: key.SecondTimeUnit, expected: time.Second},
+		{unit: key.MinuteTimeUnit, expected: time.Minute},
+		{unit: key.HourTimeUnit, expected: time.Hour},
+		{unit: key.DayTimeUnit, expected: 24 * time.Hour},
+		{unit: key.MonthTimeUnit, err: true},
+		{unit: "", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.unit), func(t *testing.T) {
+			window, err := getSlidingWindowDuration(tt.unit)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, window)
+		})
+	}
+}
+
+func TestGetSlidingWindowKey(t *testing.T) {
+	assert.Equal(t, "sliding:m:key-id", getSlidingWindowKey("key-id", key.MinuteTimeUnit))
+	assert.NotEqual(t, getSlidingWindowKey("key-id", key.MinuteTimeUnit), getSlidingWindowKey("key-id", key.HourTimeUnit))
+}
+
+// getTestClient returns a client of a local Redis server. Tests that run the
+// Lua scripts are skipped when no server is reachable.
+func getTestClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379", DB: 15})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		t.Skip("redis is not reachable on localhost:6379")
+	}
+
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestCache_SlidingWindowCounter(t *testing.T) {
+	client := getTestClient(t)
+
+	tests := []struct {
+		name       string
+		increments int
+		expired    int
+		expected   int64
+	}{
+		{name: "empty", expected: 0},
+		{name: "increments", increments: 3, expected: 3},
+		{name: "expired entries are trimmed", increments: 2, expired: 4, expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewPrefixedCache(client, util.NewUuid()+":", time.Second, time.Second)
+			id := "key-id"
+
+			for i := 0; i < tt.expired; i++ {
+				past := time.Now().Add(-2 * time.Minute)
+				err := client.ZAdd(context.Background(), getSlidingWindowKey(c.key(id), key.MinuteTimeUnit), redis.Z{
+					Score:  float64(past.UnixMilli()),
+					Member: util.NewUuid(),
+				}).Err()
+				require.NoError(t, err)
+			}
+
+			for i := 0; i < tt.increments; i++ {
+				require.NoError(t, c.IncrementSlidingWindowCounter(id, key.MinuteTimeUnit))
+			}
+
+			counter, err := c.GetSlidingWindowCounter(id, key.MinuteTimeUnit)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, counter)
+
+			counter, err = c.GetSlidingWindowCounter(id, key.HourTimeUnit)
+			require.NoError(t, err)
+			assert.Equal(t, int64(0), counter, "windows of other units are kept apart")
+
+			client.Del(context.Background(), getSlidingWindowKey(c.key(id), key.MinuteTimeUnit))
+		})
+	}
+}
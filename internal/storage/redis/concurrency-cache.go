@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ConcurrencyCache keeps the in-flight requests of a key or a user as leases
+// in a sorted set scored by their expiration. Leases that are not extended,
+// for example because the instance holding them crashed, stop counting once
+// they expire.
+type ConcurrencyCache struct {
+	client *redis.Client
+	wt     time.Duration
+	rt     time.Duration
+}
+
+func NewConcurrencyCache(c *redis.Client, wt time.Duration, rt time.Duration) *ConcurrencyCache {
+	return &ConcurrencyCache{
+		client: c,
+		wt:     wt,
+		rt:     rt,
+	}
+}
+
+func getConcurrencyKey(id string) string {
+	return fmt.Sprintf("concurrency:%s", id)
+}
+
+var acquireLeaseScript = redis.NewScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+if redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], ARGV[1] + ARGV[2], ARGV[4])
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1
+`)
+
+var extendLeaseScript = redis.NewScript(`
+if redis.call("ZSCORE", KEYS[1], ARGV[3]) == false then
+	return 0
+end
+redis.call("ZADD", KEYS[1], ARGV[1] + ARGV[2], ARGV[3])
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1
+`)
+
+// AcquireLease adds the lease if fewer than limit unexpired leases are held
+// for the id. It reports whether the lease was acquired.
+func (cc *ConcurrencyCache) AcquireLease(id, leaseId string, limit int, ttl time.Duration) (bool, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), cc.wt)
+	defer cancel()
+
+	acquired, err := acquireLeaseScript.Run(ctxTimeout, cc.client, []string{getConcurrencyKey(id)}, time.Now().UnixMilli(), ttl.Milliseconds(), limit, leaseId).Int64()
+	if err != nil {
+		return false, err
+	}
+
+	return acquired == 1, nil
+}
+
+// ExtendLease pushes back the expiration of a held lease.
+func (cc *ConcurrencyCache) ExtendLease(id, leaseId string, ttl time.Duration) error {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), cc.wt)
+	defer cancel()
+
+	return extendLeaseScript.Run(ctxTimeout, cc.client, []string{getConcurrencyKey(id)}, time.Now().UnixMilli(), ttl.Milliseconds(), leaseId).Err()
+}
+
+func (cc *ConcurrencyCache) ReleaseLease(id, leaseId string) error {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), cc.wt)
+	defer cancel()
+
+	return cc.client.ZRem(ctxTimeout, getConcurrencyKey(id), leaseId).Err()
+}
@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IdempotencyCache claims the operations recorded for events. Its keys are
+// prefixed so that it can share a database with other storages.
+type IdempotencyCache struct {
+	client *redis.Client
+	ttl    time.Duration
+	wt     time.Duration
+	rt     time.Duration
+}
+
+func NewIdempotencyCache(c *redis.Client, ttl time.Duration, wt time.Duration, rt time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{
+		client: c,
+		ttl:    ttl,
+		wt:     wt,
+		rt:     rt,
+	}
+}
+
+func getIdempotencyKey(key string) string {
+	return fmt.Sprintf("idempotency:%s", key)
+}
+
+// Claim sets the key unless it already exists and reports whether it was set.
+func (ic *IdempotencyCache) Claim(key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ic.wt)
+	defer cancel()
+
+	return ic.client.SetNX(ctx, getIdempotencyKey(key), true, ic.ttl).Result()
+}
+
+func (ic *IdempotencyCache) Release(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), ic.wt)
+	defer cancel()
+
+	return ic.client.Del(ctx, getIdempotencyKey(key)).Err()
+}
@@ -10,23 +10,30 @@ import (
 )
 
 type User struct {
-	Id                     string           `json:"id"`
-	Name                   string           `json:"name"`
-	CreatedAt              int64            `json:"createdAt"`
-	UpdatedAt              int64            `json:"updatedAt"`
-	Tags                   []string         `json:"tags"`
-	KeyIds                 []string         `json:"keyIds"`
-	Revoked                bool             `json:"revoked"`
-	RevokedReason          string           `json:"revokedReason"`
-	CostLimitInUsd         float64          `json:"costLimitInUsd"`
-	CostLimitInUsdOverTime float64          `json:"costLimitInUsdOverTime"`
-	CostLimitInUsdUnit     key.TimeUnit     `json:"costLimitInUsdUnit"`
-	RateLimitOverTime      int              `json:"rateLimitOverTime"`
-	RateLimitUnit          key.TimeUnit     `json:"rateLimitUnit"`
-	Ttl                    string           `json:"ttl"`
-	AllowedPaths           []key.PathConfig `json:"allowedPaths"`
-	AllowedModels          []string         `json:"allowedModels"`
-	UserId                 string           `json:"userId"`
+	Id                     string                 `json:"id"`
+	Name                   string                 `json:"name"`
+	CreatedAt              int64                  `json:"createdAt"`
+	UpdatedAt              int64                  `json:"updatedAt"`
+	Tags                   []string               `json:"tags"`
+	KeyIds                 []string               `json:"keyIds"`
+	Revoked                bool                   `json:"revoked"`
+	RevokedReason          string                 `json:"revokedReason"`
+	CostLimitInUsd         float64                `json:"costLimitInUsd"`
+	CostLimitInUsdOverTime float64                `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     key.TimeUnit           `json:"costLimitInUsdUnit"`
+	RateLimitOverTime      int                    `json:"rateLimitOverTime"`
+	RateLimitUnit          key.TimeUnit           `json:"rateLimitUnit"`
+	Ttl                    string                 `json:"ttl"`
+	AllowedPaths           []key.PathConfig       `json:"allowedPaths"`
+	AllowedModels          []string               `json:"allowedModels"`
+	UserId                 string                 `json:"userId"`
+	TokenLimitOverTime     int                    `json:"tokenLimitOverTime"`
+	TokenLimitUnit         key.TimeUnit           `json:"tokenLimitUnit"`
+	BudgetAlertConfig      *key.BudgetAlertConfig `json:"budgetAlertConfig"`
+	MaxConcurrentRequests  int                    `json:"maxConcurrentRequests"`
+	// CreditBalanceInUsd is nil until the first credit transaction of the user
+	// and only changes through the credit ledger.
+	CreditBalanceInUsd *float64 `json:"creditBalanceInUsd"`
 }
 
 func (u *User) Validate() error {
@@ -70,6 +77,14 @@ func (u *User) Validate() error {
 		invalid = append(invalid, "rateLimitOverTime")
 	}
 
+	if u.TokenLimitOverTime < 0 {
+		invalid = append(invalid, "tokenLimitOverTime")
+	}
+
+	if u.MaxConcurrentRequests < 0 {
+		invalid = append(invalid, "maxConcurrentRequests")
+	}
+
 	if len(u.Ttl) != 0 {
 		_, err := time.ParseDuration(u.Ttl)
 		if err != nil {
@@ -77,6 +92,10 @@ func (u *User) Validate() error {
 		}
 	}
 
+	if u.BudgetAlertConfig != nil && !u.BudgetAlertConfig.IsValid() {
+		invalid = append(invalid, "budgetAlertConfig")
+	}
+
 	for index, p := range u.AllowedPaths {
 		if len(p.Path) == 0 {
 			invalid = append(invalid, fmt.Sprintf("allowedPaths.%d.path", index))
@@ -118,6 +137,20 @@ func (u *User) Validate() error {
 		}
 	}
 
+	if len(u.TokenLimitUnit) != 0 && u.TokenLimitOverTime == 0 {
+		return internal_errors.NewValidationError("token limit over time can not be empty if token limit unit is specified")
+	}
+
+	if u.TokenLimitOverTime != 0 {
+		if len(u.TokenLimitUnit) == 0 {
+			return internal_errors.NewValidationError("token limit unit can not be empty if token limit over time is specified")
+		}
+
+		if u.TokenLimitUnit != key.HourTimeUnit && u.TokenLimitUnit != key.MinuteTimeUnit && u.TokenLimitUnit != key.SecondTimeUnit && u.TokenLimitUnit != key.DayTimeUnit {
+			return internal_errors.NewValidationError("token limit unit can not be identified")
+		}
+	}
+
 	if u.CostLimitInUsdOverTime != 0 {
 		if len(u.CostLimitInUsdUnit) == 0 {
 			return internal_errors.NewValidationError("cost limit unit can not be empty if cost limit over time is specified")
@@ -132,19 +165,23 @@ func (u *User) Validate() error {
 }
 
 type UpdateUser struct {
-	Name                   string           `json:"name"`
-	UpdatedAt              int64            `json:"updatedAt"`
-	KeyIds                 []string         `json:"keyIds"`
-	Revoked                *bool            `json:"revoked"`
-	RevokedReason          string           `json:"revokedReason"`
-	CostLimitInUsd         *float64         `json:"costLimitInUsd"`
-	CostLimitInUsdOverTime *float64         `json:"costLimitInUsdOverTime"`
-	CostLimitInUsdUnit     *key.TimeUnit    `json:"costLimitInUsdUnit"`
-	RateLimitOverTime      *int             `json:"rateLimitOverTime"`
-	RateLimitUnit          *key.TimeUnit    `json:"rateLimitUnit"`
-	AllowedPaths           []key.PathConfig `json:"allowedPaths"`
-	AllowedModels          []string         `json:"allowedModels"`
-	Ttl                    *string          `json:"ttl"`
+	Name                   string                 `json:"name"`
+	UpdatedAt              int64                  `json:"updatedAt"`
+	KeyIds                 []string               `json:"keyIds"`
+	Revoked                *bool                  `json:"revoked"`
+	RevokedReason          string                 `json:"revokedReason"`
+	CostLimitInUsd         *float64               `json:"costLimitInUsd"`
+	CostLimitInUsdOverTime *float64               `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     *key.TimeUnit          `json:"costLimitInUsdUnit"`
+	RateLimitOverTime      *int                   `json:"rateLimitOverTime"`
+	RateLimitUnit          *key.TimeUnit          `json:"rateLimitUnit"`
+	AllowedPaths           []key.PathConfig       `json:"allowedPaths"`
+	AllowedModels          []string               `json:"allowedModels"`
+	Ttl                    *string                `json:"ttl"`
+	TokenLimitOverTime     *int                   `json:"tokenLimitOverTime"`
+	TokenLimitUnit         *key.TimeUnit          `json:"tokenLimitUnit"`
+	BudgetAlertConfig      *key.BudgetAlertConfig `json:"budgetAlertConfig"`
+	MaxConcurrentRequests  *int                   `json:"maxConcurrentRequests"`
 }
 
 func (uu *UpdateUser) Validate() error {
@@ -161,6 +198,10 @@ func (uu *UpdateUser) Validate() error {
 		invalid = append(invalid, "costLimitInUsd")
 	}
 
+	if uu.MaxConcurrentRequests != nil && *uu.MaxConcurrentRequests < 0 {
+		invalid = append(invalid, "maxConcurrentRequests")
+	}
+
 	if uu.Ttl != nil && len(*uu.Ttl) != 0 {
 		_, err := time.ParseDuration(*uu.Ttl)
 		if err != nil {
@@ -172,6 +213,10 @@ func (uu *UpdateUser) Validate() error {
 		invalid = append(invalid, "updatedAt")
 	}
 
+	if uu.BudgetAlertConfig != nil && !uu.BudgetAlertConfig.IsValid() {
+		invalid = append(invalid, "budgetAlertConfig")
+	}
+
 	for index, p := range uu.AllowedPaths {
 		if len(p.Path) == 0 {
 			invalid = append(invalid, fmt.Sprintf("allowedPaths.%d.path", index))
@@ -233,6 +278,34 @@ func (uu *UpdateUser) Validate() error {
 		}
 	}
 
+	if uu.TokenLimitUnit != nil {
+		if uu.TokenLimitOverTime == nil {
+			return internal_errors.NewValidationError("token limit over time can not be empty if token limit unit is specified")
+		}
+
+		if len(*uu.TokenLimitUnit) == 0 && *uu.TokenLimitOverTime != 0 {
+			return internal_errors.NewValidationError("token limit over time must be 0 if token limit unit is empty")
+		}
+	}
+
+	if uu.TokenLimitOverTime != nil {
+		if *uu.TokenLimitOverTime < 0 {
+			return internal_errors.NewValidationError("token limit over time can not be negative")
+		}
+
+		if uu.TokenLimitUnit == nil {
+			return internal_errors.NewValidationError("token limit unit can not be empty if token limit over time is specified")
+		}
+
+		if *uu.TokenLimitOverTime == 0 && len(*uu.TokenLimitUnit) != 0 {
+			return internal_errors.NewValidationError("token limit unit has to be empty if token limit over time is 0")
+		}
+
+		if *uu.TokenLimitOverTime != 0 && *uu.TokenLimitUnit != key.HourTimeUnit && *uu.TokenLimitUnit != key.MinuteTimeUnit && *uu.TokenLimitUnit != key.SecondTimeUnit && *uu.TokenLimitUnit != key.DayTimeUnit {
+			return internal_errors.NewValidationError("token limit unit can not be identified")
+		}
+	}
+
 	if uu.CostLimitInUsdOverTime != nil {
 		if uu.CostLimitInUsdUnit == nil {
 			return internal_errors.NewValidationError("cost limit unit can not be empty if cost limit over time is specified")
@@ -14,17 +14,20 @@ type UserValidator struct {
 	clc costLimitCache
 	rlc rateLimitCache
 	cls costLimitStorage
+	tlc tokenLimitCache
 }
 
 func NewUserValidator(
 	clc costLimitCache,
 	rlc rateLimitCache,
 	cls costLimitStorage,
+	tlc tokenLimitCache,
 ) *UserValidator {
 	return &UserValidator{
 		clc: clc,
 		rlc: rlc,
 		cls: cls,
+		tlc: tlc,
 	}
 }
 
@@ -47,6 +50,11 @@ func (v *UserValidator) Validate(u *user.User, promptCost float64) error {
 		return err
 	}
 
+	err = v.validateTokenLimitOverTime(u.Id, u.TokenLimitOverTime, u.TokenLimitUnit, 0)
+	if err != nil {
+		return err
+	}
+
 	err = v.validateCostLimitOverTime(u.Id, u.CostLimitInUsdOverTime, u.CostLimitInUsdUnit)
 	if err != nil {
 		return err
@@ -88,6 +96,33 @@ func (v *UserValidator) validateRateLimitOverTime(userId string, rateLimitOverTi
 	return nil
 }
 
+// ValidateTokenLimit checks whether the estimated prompt tokens of an incoming
+// request would exceed the token limit for the current time period.
+func (v *UserValidator) ValidateTokenLimit(u *user.User, promptTks int) error {
+	if u == nil {
+		return nil
+	}
+
+	return v.validateTokenLimitOverTime(u.Id, u.TokenLimitOverTime, u.TokenLimitUnit, promptTks)
+}
+
+func (v *UserValidator) validateTokenLimitOverTime(userId string, tokenLimitOverTime int, tokenLimitUnit key.TimeUnit, promptTks int) error {
+	if tokenLimitOverTime == 0 {
+		return nil
+	}
+
+	c, err := v.tlc.GetCounter(userId, tokenLimitUnit)
+	if err != nil {
+		return errors.New("failed to get token limit counter")
+	}
+
+	if c >= int64(tokenLimitOverTime) || (promptTks > 0 && c+int64(promptTks) > int64(tokenLimitOverTime)) {
+		return internal_errors.NewTokenLimitError(fmt.Sprintf("user exceeded token limit %d tokens per %s", tokenLimitOverTime, tokenLimitUnit))
+	}
+
+	return nil
+}
+
 func (v *UserValidator) validateCostLimitOverTime(userId string, costLimitOverTime float64, costLimitUnit key.TimeUnit) error {
 	if costLimitOverTime == 0 {
 		return nil
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/bricks-cloud/bricksllm/internal/budget"
 	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
 	"github.com/bricks-cloud/bricksllm/internal/key"
 )
@@ -15,6 +16,11 @@ type costLimitCache interface {
 
 type rateLimitCache interface {
 	GetCounter(keyId string, rateLimitUnit key.TimeUnit) (int64, error)
+	GetSlidingWindowCounter(keyId string, rateLimitUnit key.TimeUnit) (int64, error)
+}
+
+type tokenLimitCache interface {
+	GetCounter(keyId string, tokenLimitUnit key.TimeUnit) (int64, error)
 }
 
 type costLimitStorage interface {
@@ -25,11 +31,17 @@ type requestsLimitStorage interface {
 	GetCounter(keyId string) (int64, error)
 }
 
+type budgetsStorage interface {
+	GetKeyBudgets(k *key.ResponseKey) []*budget.Budget
+}
+
 type Validator struct {
 	clc  costLimitCache
 	rlc  rateLimitCache
 	cls  costLimitStorage
 	rqls requestsLimitStorage
+	tlc  tokenLimitCache
+	bs   budgetsStorage
 }
 
 func NewValidator(
@@ -37,12 +49,16 @@ func NewValidator(
 	rlc rateLimitCache,
 	cls costLimitStorage,
 	rqls requestsLimitStorage,
+	tlc tokenLimitCache,
+	bs budgetsStorage,
 ) *Validator {
 	return &Validator{
 		clc:  clc,
 		rlc:  rlc,
 		cls:  cls,
 		rqls: rqls,
+		tlc:  tlc,
+		bs:   bs,
 	}
 }
 
@@ -65,7 +81,16 @@ func (v *Validator) Validate(k *key.ResponseKey, promptCost float64) error {
 		return err
 	}
 
-	err = v.validateRateLimitOverTime(k.KeyId, k.RateLimitOverTime, k.RateLimitUnit)
+	// sliding window limits are checked by the proxy on every request, so they
+	// must not keep the remaining limits of the key from being evaluated.
+	if k.RateLimitAlgorithm != key.SlidingWindowRateLimitAlgorithm {
+		err = v.validateRateLimitOverTime(k.KeyId, k.RateLimitOverTime, k.RateLimitUnit, k.RateLimitAlgorithm)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = v.validateTokenLimitOverTime(k.KeyId, k.TokenLimitOverTime, k.TokenLimitUnit, 0)
 	if err != nil {
 		return err
 	}
@@ -80,6 +105,86 @@ func (v *Validator) Validate(k *key.ResponseKey, promptCost float64) error {
 		return err
 	}
 
+	err = v.ValidateBudgets(k)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidateBudgets checks the collective spend and request rate of the budgets
+// that cover the key. Budget counters are kept under the budget id in the same
+// caches as the key counters.
+func (v *Validator) ValidateBudgets(k *key.ResponseKey) error {
+	if k == nil {
+		return nil
+	}
+
+	for _, b := range v.bs.GetKeyBudgets(k) {
+		if b.RateLimitOverTime != 0 {
+			c, err := v.rlc.GetCounter(b.Id, b.RateLimitUnit)
+			if err != nil {
+				return errors.New("failed to get budget rate limit counter")
+			}
+
+			if c >= int64(b.RateLimitOverTime) {
+				return internal_errors.NewBudgetLimitError(fmt.Sprintf("budget %s exceeded rate limit %d requests per %s", b.Name, b.RateLimitOverTime, b.RateLimitUnit))
+			}
+		}
+
+		if b.CostLimitInUsdOverTime != 0 {
+			cachedCost, err := v.clc.GetCounter(b.Id, b.CostLimitInUsdUnit)
+			if err != nil {
+				return errors.New("failed to get budget cached token cost")
+			}
+
+			if cachedCost >= convertDollarToMicroDollars(b.CostLimitInUsdOverTime) {
+				return internal_errors.NewBudgetLimitError(fmt.Sprintf("budget %s cost limit: %f has been reached for the current time period: %s", b.Name, b.CostLimitInUsdOverTime, b.CostLimitInUsdUnit))
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateModelLimits checks the spend and the request rate of the key for
+// the requested model. Per model counters are kept next to the key counters
+// in the cost limit and rate limit caches.
+func (v *Validator) ValidateModelLimits(k *key.ResponseKey, model string) error {
+	if k == nil || len(model) == 0 {
+		return nil
+	}
+
+	limited, ml := k.GetModelLimit(model)
+	if ml == nil {
+		return nil
+	}
+
+	id := key.GetModelLimitCounterId(k.KeyId, limited)
+
+	if ml.RateLimitOverTime != 0 {
+		c, err := v.rlc.GetCounter(id, ml.RateLimitUnit)
+		if err != nil {
+			return errors.New("failed to get model rate limit counter")
+		}
+
+		if c >= int64(ml.RateLimitOverTime) {
+			return internal_errors.NewModelLimitError(fmt.Sprintf("key exceeded rate limit %d requests per %s for model %s", ml.RateLimitOverTime, ml.RateLimitUnit, limited))
+		}
+	}
+
+	if ml.CostLimitInUsdOverTime != 0 {
+		cachedCost, err := v.clc.GetCounter(id, ml.CostLimitInUsdUnit)
+		if err != nil {
+			return errors.New("failed to get model cached token cost")
+		}
+
+		if cachedCost >= convertDollarToMicroDollars(ml.CostLimitInUsdOverTime) {
+			return internal_errors.NewModelLimitError(fmt.Sprintf("cost limit: %f has been reached for model %s for the current time period: %s", ml.CostLimitInUsdOverTime, limited, ml.CostLimitInUsdUnit))
+		}
+	}
+
 	return nil
 }
 
@@ -94,12 +199,27 @@ func (v *Validator) validateTtl(createdAt int64, ttl time.Duration) bool {
 	return current < createdAt+ttlInSecs
 }
 
-func (v *Validator) validateRateLimitOverTime(keyId string, rateLimitOverTime int, rateLimitUnit key.TimeUnit) error {
+// ValidateRateLimit checks the request rate of a key against its counter
+// without evaluating other limits.
+func (v *Validator) ValidateRateLimit(k *key.ResponseKey) error {
+	if k == nil {
+		return nil
+	}
+
+	return v.validateRateLimitOverTime(k.KeyId, k.RateLimitOverTime, k.RateLimitUnit, k.RateLimitAlgorithm)
+}
+
+func (v *Validator) validateRateLimitOverTime(keyId string, rateLimitOverTime int, rateLimitUnit key.TimeUnit, algorithm key.RateLimitAlgorithm) error {
 	if rateLimitOverTime == 0 {
 		return nil
 	}
 
-	c, err := v.rlc.GetCounter(keyId, rateLimitUnit)
+	getCounter := v.rlc.GetCounter
+	if algorithm == key.SlidingWindowRateLimitAlgorithm {
+		getCounter = v.rlc.GetSlidingWindowCounter
+	}
+
+	c, err := getCounter(keyId, rateLimitUnit)
 	if err != nil {
 		return errors.New("failed to get rate limit counter")
 	}
@@ -111,6 +231,33 @@ func (v *Validator) validateRateLimitOverTime(keyId string, rateLimitOverTime in
 	return nil
 }
 
+// ValidateTokenLimit checks whether the estimated prompt tokens of an incoming
+// request would exceed the token limit for the current time period.
+func (v *Validator) ValidateTokenLimit(k *key.ResponseKey, promptTks int) error {
+	if k == nil {
+		return nil
+	}
+
+	return v.validateTokenLimitOverTime(k.KeyId, k.TokenLimitOverTime, k.TokenLimitUnit, promptTks)
+}
+
+func (v *Validator) validateTokenLimitOverTime(keyId string, tokenLimitOverTime int, tokenLimitUnit key.TimeUnit, promptTks int) error {
+	if tokenLimitOverTime == 0 {
+		return nil
+	}
+
+	c, err := v.tlc.GetCounter(keyId, tokenLimitUnit)
+	if err != nil {
+		return errors.New("failed to get token limit counter")
+	}
+
+	if c >= int64(tokenLimitOverTime) || (promptTks > 0 && c+int64(promptTks) > int64(tokenLimitOverTime)) {
+		return internal_errors.NewTokenLimitError(fmt.Sprintf("key exceeded token limit %d tokens per %s", tokenLimitOverTime, tokenLimitUnit))
+	}
+
+	return nil
+}
+
 func (v *Validator) validateCostLimitOverTime(keyId string, costLimitOverTime float64, costLimitUnit key.TimeUnit) error {
 	if costLimitOverTime == 0 {
 		return nil
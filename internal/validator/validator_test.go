@@ -0,0 +1,107 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/budget"
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedCounters struct {
+	counter        int64
+	slidingCounter int64
+}
+
+func (c *fixedCounters) GetCounter(keyId string, unit key.TimeUnit) (int64, error) {
+	return c.counter, nil
+}
+
+func (c *fixedCounters) GetSlidingWindowCounter(keyId string, unit key.TimeUnit) (int64, error) {
+	return c.slidingCounter, nil
+}
+
+type fixedStorage struct {
+	counter int64
+}
+
+func (s *fixedStorage) GetCounter(keyId string) (int64, error) {
+	return s.counter, nil
+}
+
+type noBudgets struct{}
+
+func (b *noBudgets) GetKeyBudgets(k *key.ResponseKey) []*budget.Budget {
+	return nil
+}
+
+func TestValidator_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         *key.ResponseKey
+		costMicros  int64
+		rateCounter int64
+		expected    error
+	}{
+		{
+			name: "fixed window rate limit",
+			key: &key.ResponseKey{
+				RateLimitOverTime: 10,
+				RateLimitUnit:     key.MinuteTimeUnit,
+			},
+			rateCounter: 10,
+			expected:    &internal_errors.RateLimitError{},
+		},
+		{
+			name: "sliding window rate limit is left to the proxy",
+			key: &key.ResponseKey{
+				RateLimitOverTime:  10,
+				RateLimitUnit:      key.MinuteTimeUnit,
+				RateLimitAlgorithm: key.SlidingWindowRateLimitAlgorithm,
+			},
+			rateCounter: 10,
+		},
+		{
+			name: "cost limit over time of a sliding window key at its rate limit",
+			key: &key.ResponseKey{
+				RateLimitOverTime:      10,
+				RateLimitUnit:          key.MinuteTimeUnit,
+				RateLimitAlgorithm:     key.SlidingWindowRateLimitAlgorithm,
+				CostLimitInUsdOverTime: 1,
+				CostLimitInUsdUnit:     key.DayTimeUnit,
+			},
+			costMicros:  1000000,
+			rateCounter: 10,
+			expected:    &internal_errors.CostLimitError{},
+		},
+		{
+			name: "total cost limit of a sliding window key at its rate limit",
+			key: &key.ResponseKey{
+				RateLimitOverTime:  10,
+				RateLimitUnit:      key.MinuteTimeUnit,
+				RateLimitAlgorithm: key.SlidingWindowRateLimitAlgorithm,
+				CostLimitInUsd:     1,
+			},
+			costMicros:  1000000,
+			rateCounter: 10,
+			expected:    &internal_errors.ExpirationError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			costs := &fixedCounters{counter: tt.costMicros}
+			rates := &fixedCounters{counter: tt.rateCounter, slidingCounter: tt.rateCounter}
+			v := NewValidator(costs, rates, &fixedStorage{counter: tt.costMicros}, &fixedStorage{}, &fixedCounters{}, &noBudgets{})
+
+			err := v.Validate(tt.key, 0)
+			if tt.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.IsType(t, tt.expected, err)
+		})
+	}
+}